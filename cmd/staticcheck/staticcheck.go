@@ -10,19 +10,73 @@
 package main
 
 import (
+	"flag"
+	"fmt"
 	"github.com/Tengfei1010/GCBDetector/lint/lintutil"
 	"github.com/Tengfei1010/GCBDetector/staticcheck"
 	"os"
+	"strings"
 )
 
 func main() {
 	//path := []string { "/home/kevin/go/src/github.com/Tengfei1010/GCBDetector/testdata/CheckDeferLock.go"}
 	fs := lintutil.FlagSet("staticcheck")
 	gen := fs.Bool("generated", false, "Check generated code")
+	generatedFiles := fs.String("generated-files", "", "Comma-separated list of patterns of names of generated files, e.g. *.pb.go")
+	primitives := fs.Bool("primitives", false, "Print the synchronization primitives used by the checked code")
+	lockTypes := fs.String("lock-types", "", "Comma-separated list of additional lock methods, e.g. (*example.com/spin.SpinLock).Lock")
+	unlockHelpers := fs.String("unlock-helpers", "", "Comma-separated list of functions that release the lock passed as their first argument")
+	lockHeuristic := fs.Bool("lock-heuristic", true, "Treat other methods whose name contains \"lock\" as lock operations")
+	perFuncTimeout := fs.Duration("per-func-timeout", 0, "Skip the double lock analysis of functions taking longer than `duration`, e.g. 30s; 0 means no limit")
+	experimental := fs.Bool("experimental", false, "Run experimental checks, such as SA2048")
+	deferClosersOnly := fs.Bool("defer-in-loop-closers-only", false, "Limit SA2051 to deferred calls of Close, Unlock, Release and the like")
+	checkTests := fs.Bool("check-tests", false, "Check the functions in _test.go files too")
 	fs.Parse(os.Args[1:])
 	//fs.Parse(path)
 	c := staticcheck.NewChecker()
-	c.CheckGenerated = *gen
+	// settings from the configuration file, which flags given on the
+	// command line override or add to
+	conf, err := staticcheck.LoadConfig(".")
+	if err == nil && conf != nil {
+		err = conf.Apply(c)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(lintutil.ExitError)
+	}
+	set := map[string]bool{}
+	fs.Visit(func(f *flag.Flag) { set[f.Name] = true })
+	if set["generated"] {
+		c.CheckGenerated = *gen
+	}
+	if set["per-func-timeout"] {
+		c.PerFuncTimeout = *perFuncTimeout
+	}
+	if set["experimental"] {
+		c.Experimental = *experimental
+	}
+	if set["defer-in-loop-closers-only"] {
+		c.DeferInLoopClosersOnly = *deferClosersOnly
+	}
+	c.Verbose = fs.Lookup("v").Value.(flag.Getter).Get().(bool)
+	if set["check-tests"] {
+		c.IncludeTests = *checkTests
+	}
+	if *generatedFiles != "" {
+		c.GeneratedFiles = append(c.GeneratedFiles, strings.Split(*generatedFiles, ",")...)
+	}
+	if *lockTypes != "" {
+		c.LockTypes = append(c.LockTypes, strings.Split(*lockTypes, ",")...)
+	}
+	if *unlockHelpers != "" {
+		c.UnlockHelpers = append(c.UnlockHelpers, strings.Split(*unlockHelpers, ",")...)
+	}
+	c.NoLockHeuristic = !*lockHeuristic
+	if *primitives {
+		c.ReportPrimitiveUsage = func(stats staticcheck.PrimitiveStats) {
+			fmt.Println(stats)
+		}
+	}
 	cfg := lintutil.CheckerConfig{
 		Checker:     c,
 		ExitNonZero: true,
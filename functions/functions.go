@@ -70,6 +70,8 @@ type Descriptions struct {
 	CallGraph *callgraph.Graph
 	mu        sync.Mutex
 	cache     map[*ssa.Function]*descriptionEntry
+	// loops caches the loops of functions, see Loops
+	loops sync.Map
 }
 
 func NewDescriptions(prog *ssa.Program) *Descriptions {
@@ -79,6 +81,19 @@ func NewDescriptions(prog *ssa.Program) *Descriptions {
 	}
 }
 
+// Loops returns the loops of fn. They are computed once per function
+// and cached independently of the rest of fn's description, which is
+// more expensive to compute.
+func (d *Descriptions) Loops(fn *ssa.Function) []Loop {
+	if loops, ok := d.loops.Load(fn); ok {
+		return loops.([]Loop)
+	}
+	loops, _ := d.loops.LoadOrStore(fn, findLoops(fn))
+	return loops.([]Loop)
+}
+
+// Get returns the description of fn. It is computed on the first call
+// for fn and cached; concurrent callers wait for the computation.
 func (d *Descriptions) Get(fn *ssa.Function) Description {
 	d.mu.Lock()
 	fd := d.cache[fn]
@@ -95,7 +110,7 @@ func (d *Descriptions) Get(fn *ssa.Function) Description {
 			fd.result.Stub = fd.result.Stub || d.IsStub(fn)
 			fd.result.Infinite = fd.result.Infinite || !terminates(fn)
 			fd.result.Ranges = vrp.BuildGraph(fn).Solve()
-			fd.result.Loops = findLoops(fn)
+			fd.result.Loops = d.Loops(fn)
 			fd.result.NilError = fd.result.NilError || IsNilError(fn)
 			fd.result.ConcreteReturnTypes = concreteReturnTypes(fn)
 		}
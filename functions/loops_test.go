@@ -0,0 +1,71 @@
+package functions
+
+import (
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"strings"
+	"testing"
+
+	"github.com/Tengfei1010/GCBDetector/ssa"
+	"github.com/Tengfei1010/GCBDetector/ssa/ssautil"
+)
+
+// manyLoops returns a function with n sequential loops, each
+// containing a branch, so that it has several blocks per loop.
+func manyLoops(tb testing.TB, n int) (*Descriptions, *ssa.Function) {
+	var buf strings.Builder
+	buf.WriteString("package p\n\nfunc f(x int) int {\n")
+	for i := 0; i < n; i++ {
+		fmt.Fprintf(&buf, "\tfor i := 0; i < x; i++ {\n\t\tif i%%2 == 0 {\n\t\t\tx += %d\n\t\t}\n\t}\n", i)
+	}
+	buf.WriteString("\treturn x\n}\n")
+
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, "p.go", buf.String(), 0)
+	if err != nil {
+		tb.Fatal(err)
+	}
+	pkg, _, err := ssautil.BuildPackage(&types.Config{}, fset, types.NewPackage("p", ""), []*ast.File{f}, 0)
+	if err != nil {
+		tb.Fatal(err)
+	}
+	return NewDescriptions(pkg.Prog), pkg.Func("f")
+}
+
+func TestLoops(t *testing.T) {
+	d, fn := manyLoops(t, 10)
+	loops := d.Loops(fn)
+	if len(loops) != 10 {
+		t.Fatalf("got %d loops, want 10", len(loops))
+	}
+	if again := d.Loops(fn); &again[0] != &loops[0] {
+		t.Error("loops were computed again")
+	}
+	if desc := d.Get(fn); len(desc.Loops) != 10 || &desc.Loops[0] != &loops[0] {
+		t.Error("Get doesn't use the cached loops")
+	}
+}
+
+// BenchmarkFindLoops measures computing the loops of a function on
+// every lookup.
+func BenchmarkFindLoops(b *testing.B) {
+	_, fn := manyLoops(b, 200)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		findLoops(fn)
+	}
+}
+
+// BenchmarkDescriptionsLoops measures cached lookups of the loops of a
+// function.
+func BenchmarkDescriptionsLoops(b *testing.B) {
+	d, fn := manyLoops(b, 200)
+	d.Loops(fn)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		d.Loops(fn)
+	}
+}
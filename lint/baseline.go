@@ -0,0 +1,155 @@
+package lint
+
+import (
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+)
+
+// BaselineEntry identifies a known problem. Line and column numbers
+// are deliberately left out, so that unrelated edits to a file don't
+// invalidate its entries.
+type BaselineEntry struct {
+	Check   string `json:"check"`
+	File    string `json:"file"`
+	Message string `json:"message"`
+}
+
+// A Baseline is a set of known problems that aren't to be reported.
+// Problems are matched by check, file and message; a baseline entry
+// recorded n times suppresses at most n matching problems.
+type Baseline struct {
+	// Root is the directory file names are made relative to.
+	Root string
+
+	entries []BaselineEntry
+	counts  map[BaselineEntry]int
+}
+
+// NewBaseline returns a baseline of problems, with file names made
+// relative to root. Ignored problems aren't part of it.
+func NewBaseline(problems []Problem, root string) *Baseline {
+	b := &Baseline{Root: root, counts: map[BaselineEntry]int{}}
+	for _, p := range problems {
+		if p.Ignored {
+			continue
+		}
+		e := b.entry(p)
+		b.entries = append(b.entries, e)
+		b.counts[e]++
+	}
+	sort.Slice(b.entries, func(i, j int) bool {
+		ei, ej := b.entries[i], b.entries[j]
+		if ei.File != ej.File {
+			return ei.File < ej.File
+		}
+		if ei.Check != ej.Check {
+			return ei.Check < ej.Check
+		}
+		return ei.Message < ej.Message
+	})
+	return b
+}
+
+// ReadBaseline reads a baseline written by WriteTo.
+func ReadBaseline(r io.Reader, root string) (*Baseline, error) {
+	var entries []BaselineEntry
+	if err := json.NewDecoder(r).Decode(&entries); err != nil {
+		return nil, err
+	}
+	b := &Baseline{Root: root, entries: entries, counts: map[BaselineEntry]int{}}
+	for _, e := range entries {
+		b.counts[e]++
+	}
+	return b, nil
+}
+
+// WriteTo writes the baseline to w as JSON.
+func (b *Baseline) WriteTo(w io.Writer) (int64, error) {
+	entries := b.entries
+	if entries == nil {
+		entries = []BaselineEntry{}
+	}
+	data, err := json.MarshalIndent(entries, "", "  ")
+	if err != nil {
+		return 0, err
+	}
+	n, err := w.Write(append(data, '\n'))
+	return int64(n), err
+}
+
+// Known reports whether p is in the baseline and, if so, uses up one
+// of its entries.
+func (b *Baseline) Known(p Problem) bool {
+	e := b.entry(p)
+	if b.counts[e] == 0 {
+		return false
+	}
+	b.counts[e]--
+	return true
+}
+
+// Filter returns the problems that aren't in the baseline.
+func (b *Baseline) Filter(problems []Problem) []Problem {
+	var out []Problem
+	for _, p := range problems {
+		if !b.Known(p) {
+			out = append(out, p)
+		}
+	}
+	return out
+}
+
+// positionRe matches the positions of Go files embedded in messages,
+// e.g. "previously acquired at a/b.go:12:3".
+var positionRe = regexp.MustCompile(`(\S+\.go)(:\d+)(:\d+)?`)
+
+func (b *Baseline) entry(p Problem) BaselineEntry {
+	msg := positionRe.ReplaceAllStringFunc(p.Text, func(pos string) string {
+		return b.rel(positionRe.FindStringSubmatch(pos)[1])
+	})
+	return BaselineEntry{
+		Check:   p.Check,
+		File:    b.rel(p.Position.Filename),
+		Message: msg,
+	}
+}
+
+// rel returns name relative to the baseline's root, with forward
+// slashes, so that baselines are portable between machines.
+func (b *Baseline) rel(name string) string {
+	if name == "" {
+		return ""
+	}
+	if b.Root != "" {
+		abs, err := filepath.Abs(name)
+		if err == nil {
+			if rel, err := filepath.Rel(b.Root, abs); err == nil {
+				name = rel
+			}
+		}
+	}
+	return filepath.ToSlash(name)
+}
+
+// ModuleRoot returns the directory containing the go.mod file of the
+// module dir is in, or dir itself if it isn't in a module.
+func ModuleRoot(dir string) string {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return dir
+	}
+	for d := dir; ; {
+		if _, err := os.Stat(filepath.Join(d, "go.mod")); err == nil {
+			return d
+		}
+		parent := filepath.Dir(d)
+		if parent == d {
+			return dir
+		}
+		d = parent
+	}
+}
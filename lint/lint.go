@@ -21,10 +21,12 @@ import (
 	"go/build"
 	"go/token"
 	"go/types"
+	"os"
 	"path/filepath"
 	"sort"
 	"strings"
 	"sync"
+	"time"
 	"unicode"
 
 	"github.com/Tengfei1010/GCBDetector/ssa"
@@ -37,6 +39,7 @@ type Job struct {
 
 	checker  string
 	check    string
+	severity Severity
 	problems []Problem
 }
 
@@ -131,6 +134,43 @@ type Program struct {
 
 type Func func(*Job)
 
+// Severity describes how serious a problem is.
+type Severity int
+
+const (
+	// SeverityInfo is used for findings that are informational, or
+	// too uncertain to be acted on without review.
+	SeverityInfo Severity = iota
+	// SeverityWarning is used for likely, but not certain, bugs.
+	SeverityWarning
+	// SeverityError is used for findings that are almost certainly
+	// bugs.
+	SeverityError
+)
+
+func (s Severity) String() string {
+	switch s {
+	case SeverityInfo:
+		return "info"
+	case SeverityWarning:
+		return "warning"
+	case SeverityError:
+		return "error"
+	}
+	return fmt.Sprintf("Severity(%d)", int(s))
+}
+
+// ParseSeverity parses the string form of a severity, as returned by
+// Severity.String.
+func ParseSeverity(s string) (Severity, error) {
+	for sev := SeverityInfo; sev <= SeverityError; sev++ {
+		if s == sev.String() {
+			return sev, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown severity %q", s)
+}
+
 // Problem represents a problem in some source code.
 type Problem struct {
 	pos      token.Pos
@@ -140,6 +180,7 @@ type Problem struct {
 	Checker  string
 	Package  *types.Package
 	Ignored  bool
+	Severity Severity
 }
 
 func (p *Problem) String() string {
@@ -156,6 +197,42 @@ type Checker interface {
 	Funcs() map[string]Func
 }
 
+// A SeverityChecker is a Checker that assigns severities to its
+// checks. Problems found by checks missing from Severities, and by
+// checkers not implementing the interface, are warnings.
+type SeverityChecker interface {
+	Checker
+	Severities() map[string]Severity
+}
+
+// CheckInfo describes a check.
+type CheckInfo struct {
+	Code string `json:"code"`
+	// Title summarizes what the check finds in a few words.
+	Title string `json:"title"`
+	// Description explains what the check finds and why it is a
+	// problem, in a few sentences.
+	Description string `json:"description"`
+	// DefaultSeverity is the severity, e.g. "warning", of the
+	// problems the check reports unless configured otherwise.
+	DefaultSeverity string `json:"defaultSeverity"`
+}
+
+// A DocumentedChecker is a Checker that describes its checks.
+type DocumentedChecker interface {
+	Checker
+	// Checks describes the checks in Funcs, sorted by code.
+	Checks() []CheckInfo
+}
+
+// A FilteringChecker is a Checker that keeps some of the problems its
+// checks find from being reported, such as those in generated code.
+type FilteringChecker interface {
+	Checker
+	// Keep reports whether p is to be reported.
+	Keep(p Problem) bool
+}
+
 // A Linter lints Go source code.
 type Linter struct {
 	Checker       Checker
@@ -163,9 +240,29 @@ type Linter struct {
 	GoVersion     int
 	ReturnIgnored bool
 
+	// MinSeverity is the lowest severity of problems that are
+	// reported; less severe problems are dropped.
+	MinSeverity Severity
+
+	// Report, if set, is called with each problem as soon as the
+	// check that found it has finished, in no particular order, and
+	// Lint doesn't collect the problems. Calls are serialized.
+	Report func(Problem)
+
+	// Verbose logs how long preparing the checker and running each
+	// check take to standard error.
+	Verbose bool
+
 	automaticIgnores []Ignore
 }
 
+// logf logs a phase that started at start, if l.Verbose is set.
+func (l *Linter) logf(start time.Time, format string, args ...interface{}) {
+	if l.Verbose {
+		fmt.Fprintf(os.Stderr, "%s: %v\n", fmt.Sprintf(format, args...), time.Since(start).Round(time.Millisecond))
+	}
+}
+
 func (l *Linter) ignore(p Problem) bool {
 	ignored := false
 	for _, ig := range l.automaticIgnores {
@@ -221,6 +318,9 @@ func (ps byPosition) Less(i int, j int) bool {
 	if pi.Column != pj.Column {
 		return pi.Column < pj.Column
 	}
+	if ps.ps[i].Check != ps.ps[j].Check {
+		return ps.ps[i].Check < ps.ps[j].Check
+	}
 
 	return ps.ps[i].Text < ps.ps[j].Text
 }
@@ -229,6 +329,38 @@ func (ps byPosition) Swap(i int, j int) {
 	ps.ps[i], ps.ps[j] = ps.ps[j], ps.ps[i]
 }
 
+// SortProblems sorts problems by file, line, column and check, so
+// that output doesn't depend on the order in which checks ran.
+func SortProblems(ps []Problem) {
+	sort.Stable(byPosition{nil, ps})
+}
+
+// CheckCount is the number of problems a check reported.
+type CheckCount struct {
+	Check string
+	Count int
+}
+
+// CountByCheck tallies ps by check, most frequent first and checks
+// reporting equally often by code.
+func CountByCheck(ps []Problem) []CheckCount {
+	counts := map[string]int{}
+	for _, p := range ps {
+		counts[p.Check]++
+	}
+	out := make([]CheckCount, 0, len(counts))
+	for check, n := range counts {
+		out = append(out, CheckCount{check, n})
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Count != out[j].Count {
+			return out[i].Count > out[j].Count
+		}
+		return out[i].Check < out[j].Check
+	})
+	return out
+}
+
 func parseDirective(s string) (cmd string, args []string) {
 	if !strings.HasPrefix(s, "//lint:") {
 		return "", nil
@@ -238,7 +370,63 @@ func parseDirective(s string) (cmd string, args []string) {
 	return fields[0], fields[1:]
 }
 
-func (l *Linter) Lint(lprog *loader.Program, conf *loader.Config) []Problem {
+const gcbdIgnore = "//gcbd:ignore"
+
+// gcbdIgnores returns the ignores for the //gcbd:ignore directives in
+// f. A directive applies to the line it is on or, if it is on a line
+// of its own, to the line below. It is followed by a comma-separated
+// list of checks to ignore; without one, all checks are ignored.
+func gcbdIgnores(prog *Program, f *ast.File) []Ignore {
+	var directives []*ast.Comment
+	for _, cg := range f.Comments {
+		for _, c := range cg.List {
+			if c.Text == gcbdIgnore || strings.HasPrefix(c.Text, gcbdIgnore+" ") {
+				directives = append(directives, c)
+			}
+		}
+	}
+	if len(directives) == 0 {
+		return nil
+	}
+
+	// code records the lines on which code ends, to tell trailing
+	// comments from comments on lines of their own. A // comment is
+	// always the last thing on its line.
+	code := map[int]bool{}
+	ast.Inspect(f, func(n ast.Node) bool {
+		switch n.(type) {
+		case nil, *ast.File, *ast.CommentGroup, *ast.Comment:
+			return true
+		}
+		code[prog.DisplayPosition(n.End()).Line] = true
+		return true
+	})
+
+	var out []Ignore
+	for _, c := range directives {
+		checks := []string{"*"}
+		if args := strings.Fields(strings.TrimPrefix(c.Text, gcbdIgnore)); len(args) > 0 {
+			checks = strings.Split(args[0], ",")
+		}
+		pos := prog.DisplayPosition(c.Pos())
+		line := pos.Line
+		if !code[line] {
+			line++
+		}
+		out = append(out, &LineIgnore{
+			File:   pos.Filename,
+			Line:   line,
+			Checks: checks,
+			pos:    c.Pos(),
+		})
+	}
+	return out
+}
+
+// NewProgram prepares the loaded program lprog for linting, building
+// its SSA form. goVersion is the minor Go version the program is
+// checked against. The result can be passed to Linter.LintProgram.
+func NewProgram(lprog *loader.Program, conf *loader.Config, goVersion int) *Program {
 	ssaprog := ssautil.CreateProgram(lprog, ssa.GlobalDebug)
 	ssaprog.Build()
 	pkgMap := map[*ssa.Package]*Pkg{}
@@ -272,7 +460,7 @@ func (l *Linter) Lint(lprog *loader.Program, conf *loader.Config) []Problem {
 		Prog:         lprog,
 		Packages:     pkgs,
 		Info:         &types.Info{},
-		GoVersion:    l.GoVersion,
+		GoVersion:    goVersion,
 		tokenFileMap: map[*token.File]*ast.File{},
 		astFileMap:   map[*ast.File]*Pkg{},
 	}
@@ -306,7 +494,69 @@ func (l *Linter) Lint(lprog *loader.Program, conf *loader.Config) []Problem {
 		}
 	}
 
+	sizes := struct {
+		types      int
+		defs       int
+		uses       int
+		implicits  int
+		selections int
+		scopes     int
+	}{}
+	for _, pkg := range pkgs {
+		sizes.types += len(pkg.Info.Info.Types)
+		sizes.defs += len(pkg.Info.Info.Defs)
+		sizes.uses += len(pkg.Info.Info.Uses)
+		sizes.implicits += len(pkg.Info.Info.Implicits)
+		sizes.selections += len(pkg.Info.Info.Selections)
+		sizes.scopes += len(pkg.Info.Info.Scopes)
+	}
+	prog.Info.Types = make(map[ast.Expr]types.TypeAndValue, sizes.types)
+	prog.Info.Defs = make(map[*ast.Ident]types.Object, sizes.defs)
+	prog.Info.Uses = make(map[*ast.Ident]types.Object, sizes.uses)
+	prog.Info.Implicits = make(map[ast.Node]types.Object, sizes.implicits)
+	prog.Info.Selections = make(map[*ast.SelectorExpr]*types.Selection, sizes.selections)
+	prog.Info.Scopes = make(map[ast.Node]*types.Scope, sizes.scopes)
+	for _, pkg := range pkgs {
+		for k, v := range pkg.Info.Info.Types {
+			prog.Info.Types[k] = v
+		}
+		for k, v := range pkg.Info.Info.Defs {
+			prog.Info.Defs[k] = v
+		}
+		for k, v := range pkg.Info.Info.Uses {
+			prog.Info.Uses[k] = v
+		}
+		for k, v := range pkg.Info.Info.Implicits {
+			prog.Info.Implicits[k] = v
+		}
+		for k, v := range pkg.Info.Info.Selections {
+			prog.Info.Selections[k] = v
+		}
+		for k, v := range pkg.Info.Info.Scopes {
+			prog.Info.Scopes[k] = v
+		}
+	}
+	return prog
+}
+
+func (l *Linter) Lint(lprog *loader.Program, conf *loader.Config) []Problem {
+	return l.LintProgram(NewProgram(lprog, conf, l.GoVersion))
+}
+
+// LintProgram runs the checks of l.Checker on prog, which must have
+// been created by NewProgram.
+func (l *Linter) LintProgram(prog *Program) []Problem {
+	lprog := prog.Prog
 	var out []Problem
+	// mu protects out, the automatic ignores and calls to l.Report
+	var mu sync.Mutex
+	emit := func(p Problem) {
+		if l.Report != nil {
+			l.Report(p)
+		} else {
+			out = append(out, p)
+		}
+	}
 	l.automaticIgnores = nil
 	for _, pkginfo := range lprog.InitialPackages() {
 		for _, f := range pkginfo.Files {
@@ -329,8 +579,13 @@ func (l *Linter) Lint(lprog *loader.Program, conf *loader.Config) []Problem {
 									Check:    "",
 									Checker:  l.Checker.Name(),
 									Package:  nil,
+									Severity: SeverityWarning,
+								}
+								if p.Severity >= l.MinSeverity {
+									mu.Lock()
+									emit(p)
+									mu.Unlock()
 								}
-								out = append(out, p)
 								continue
 							}
 						default:
@@ -358,52 +613,13 @@ func (l *Linter) Lint(lprog *loader.Program, conf *loader.Config) []Problem {
 					}
 				}
 			}
+			l.automaticIgnores = append(l.automaticIgnores, gcbdIgnores(prog, f)...)
 		}
 	}
 
-	sizes := struct {
-		types      int
-		defs       int
-		uses       int
-		implicits  int
-		selections int
-		scopes     int
-	}{}
-	for _, pkg := range pkgs {
-		sizes.types += len(pkg.Info.Info.Types)
-		sizes.defs += len(pkg.Info.Info.Defs)
-		sizes.uses += len(pkg.Info.Info.Uses)
-		sizes.implicits += len(pkg.Info.Info.Implicits)
-		sizes.selections += len(pkg.Info.Info.Selections)
-		sizes.scopes += len(pkg.Info.Info.Scopes)
-	}
-	prog.Info.Types = make(map[ast.Expr]types.TypeAndValue, sizes.types)
-	prog.Info.Defs = make(map[*ast.Ident]types.Object, sizes.defs)
-	prog.Info.Uses = make(map[*ast.Ident]types.Object, sizes.uses)
-	prog.Info.Implicits = make(map[ast.Node]types.Object, sizes.implicits)
-	prog.Info.Selections = make(map[*ast.SelectorExpr]*types.Selection, sizes.selections)
-	prog.Info.Scopes = make(map[ast.Node]*types.Scope, sizes.scopes)
-	for _, pkg := range pkgs {
-		for k, v := range pkg.Info.Info.Types {
-			prog.Info.Types[k] = v
-		}
-		for k, v := range pkg.Info.Info.Defs {
-			prog.Info.Defs[k] = v
-		}
-		for k, v := range pkg.Info.Info.Uses {
-			prog.Info.Uses[k] = v
-		}
-		for k, v := range pkg.Info.Info.Implicits {
-			prog.Info.Implicits[k] = v
-		}
-		for k, v := range pkg.Info.Info.Selections {
-			prog.Info.Selections[k] = v
-		}
-		for k, v := range pkg.Info.Info.Scopes {
-			prog.Info.Scopes[k] = v
-		}
-	}
+	start := time.Now()
 	l.Checker.Init(prog)
+	l.logf(start, "initializing %s", l.Checker.Name())
 
 	funcs := l.Checker.Funcs()
 	var keys []string
@@ -412,12 +628,27 @@ func (l *Linter) Lint(lprog *loader.Program, conf *loader.Config) []Problem {
 	}
 	sort.Strings(keys)
 
+	var severities map[string]Severity
+	if sc, ok := l.Checker.(SeverityChecker); ok {
+		severities = sc.Severities()
+	}
+
+	var keep func(Problem) bool
+	if fc, ok := l.Checker.(FilteringChecker); ok {
+		keep = fc.Keep
+	}
+
 	var jobs []*Job
 	for _, k := range keys {
+		sev, ok := severities[k]
+		if !ok {
+			sev = SeverityWarning
+		}
 		j := &Job{
-			Program: prog,
-			checker: l.Checker.Name(),
-			check:   k,
+			Program:  prog,
+			checker:  l.Checker.Name(),
+			check:    k,
+			severity: sev,
 		}
 		jobs = append(jobs, j)
 	}
@@ -430,20 +661,31 @@ func (l *Linter) Lint(lprog *loader.Program, conf *loader.Config) []Problem {
 			if fn == nil {
 				return
 			}
+			start := time.Now()
 			fn(j)
+			l.logf(start, "running %s over %d functions", j.check, len(prog.InitialFunctions))
+
+			mu.Lock()
+			defer mu.Unlock()
+			for _, p := range j.problems {
+				// match ignores even for dropped problems, so
+				// that their directives aren't reported as unused
+				p.Ignored = l.ignore(p)
+				if keep != nil && !keep(p) {
+					continue
+				}
+				if p.Severity < l.MinSeverity {
+					continue
+				}
+				if l.ReturnIgnored || !p.Ignored {
+					emit(p)
+				}
+			}
+			j.problems = nil
 		}(j)
 	}
 	wg.Wait()
 
-	for _, j := range jobs {
-		for _, p := range j.problems {
-			p.Ignored = l.ignore(p)
-			if l.ReturnIgnored || !p.Ignored {
-				out = append(out, p)
-			}
-		}
-	}
-
 	for _, ig := range l.automaticIgnores {
 		ig, ok := ig.(*LineIgnore)
 		if !ok {
@@ -471,12 +713,15 @@ func (l *Linter) Lint(lprog *loader.Program, conf *loader.Config) []Problem {
 				Check:    "",
 				Checker:  l.Checker.Name(),
 				Package:  nil,
+				Severity: SeverityWarning,
+			}
+			if p.Severity >= l.MinSeverity {
+				emit(p)
 			}
-			out = append(out, p)
 		}
 	}
 
-	sort.Sort(byPosition{lprog.Fset, out})
+	SortProblems(out)
 	return out
 }
 
@@ -540,6 +785,7 @@ func (j *Job) Errorf(n Positioner, format string, args ...interface{}) *Problem
 		Check:    j.check,
 		Checker:  j.checker,
 		Package:  pkg,
+		Severity: j.severity,
 	}
 	j.problems = append(j.problems, problem)
 	return &j.problems[len(j.problems)-1]
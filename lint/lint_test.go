@@ -7,8 +7,17 @@
 package lint_test
 
 import (
+	"bytes"
+	"encoding/json"
+	"go/parser"
+	"go/token"
+	"reflect"
+	"strings"
 	"testing"
 
+	"golang.org/x/tools/go/buildutil"
+	"golang.org/x/tools/go/loader"
+
 	. "github.com/Tengfei1010/GCBDetector/lint"
 	"github.com/Tengfei1010/GCBDetector/lint/testutil"
 )
@@ -38,3 +47,199 @@ func TestAll(t *testing.T) {
 	c := testChecker{}
 	testutil.TestAll(t, c, "")
 }
+
+func TestWriteSARIF(t *testing.T) {
+	checks := []CheckInfo{{
+		Code:            "TEST1000",
+		Title:           "Test check",
+		Description:     "Finds problems in tests.",
+		DefaultSeverity: "error",
+	}}
+	ps := []Problem{
+		{
+			Position: token.Position{Filename: "a/b.go", Line: 3, Column: 7},
+			Text:     "first",
+			Check:    "TEST1000",
+		},
+		{
+			Position: token.Position{Filename: "a/c.go", Line: 1},
+			Text:     "second",
+			Check:    "TEST1000",
+			Ignored:  true,
+		},
+	}
+	var buf bytes.Buffer
+	if err := WriteSARIF(&buf, ps, checks); err != nil {
+		t.Fatal(err)
+	}
+
+	var log struct {
+		Version string
+		Runs    []struct {
+			Tool struct {
+				Driver struct {
+					Rules []struct {
+						ID                   string
+						ShortDescription     struct{ Text string }
+						FullDescription      struct{ Text string }
+						DefaultConfiguration struct{ Level string }
+					}
+				}
+			}
+			Results []struct {
+				RuleID    string
+				Message   struct{ Text string }
+				Locations []struct {
+					PhysicalLocation struct {
+						ArtifactLocation struct{ URI string }
+						Region           struct{ StartLine, StartColumn int }
+					}
+				}
+				Suppressions []struct{ Kind string }
+			}
+		}
+	}
+	if err := json.Unmarshal(buf.Bytes(), &log); err != nil {
+		t.Fatal(err)
+	}
+	if log.Version != "2.1.0" || len(log.Runs) != 1 {
+		t.Fatalf("unexpected log: %s", buf.String())
+	}
+	run := log.Runs[0]
+	if rules := run.Tool.Driver.Rules; len(rules) != 1 || rules[0].ID != "TEST1000" || rules[0].ShortDescription.Text != "Test check" ||
+		rules[0].FullDescription.Text != "Finds problems in tests." || rules[0].DefaultConfiguration.Level != "error" {
+		t.Errorf("unexpected rules: %+v", rules)
+	}
+	if len(run.Results) != 2 {
+		t.Fatalf("got %d results, want 2", len(run.Results))
+	}
+	res := run.Results[0]
+	loc := res.Locations[0].PhysicalLocation
+	if res.RuleID != "TEST1000" || res.Message.Text != "first" ||
+		loc.ArtifactLocation.URI != "a/b.go" || loc.Region.StartLine != 3 || loc.Region.StartColumn != 7 {
+		t.Errorf("unexpected result: %+v", res)
+	}
+	if res := run.Results[1]; len(res.Suppressions) != 1 || res.Suppressions[0].Kind != "inSource" {
+		t.Errorf("ignored problem isn't suppressed: %+v", res)
+	}
+}
+
+func TestBaseline(t *testing.T) {
+	problem := func(file string, line int, text string) Problem {
+		return Problem{
+			Position: token.Position{Filename: file, Line: line},
+			Text:     text,
+			Check:    "TEST1000",
+		}
+	}
+	old := []Problem{
+		problem("/src/a/b.go", 3, "mutex re-acquired here (previously acquired at /src/a/b.go:1:2)"),
+		problem("/src/a/c.go", 5, "dup"),
+		problem("/src/a/c.go", 9, "dup"),
+	}
+	var buf bytes.Buffer
+	if _, err := NewBaseline(old, "/src").WriteTo(&buf); err != nil {
+		t.Fatal(err)
+	}
+	var entries []BaselineEntry
+	if err := json.Unmarshal(buf.Bytes(), &entries); err != nil {
+		t.Fatal(err)
+	}
+	want := BaselineEntry{Check: "TEST1000", File: "a/b.go", Message: "mutex re-acquired here (previously acquired at a/b.go)"}
+	if len(entries) != 3 || entries[0] != want {
+		t.Fatalf("unexpected baseline: %s", buf.String())
+	}
+
+	// The same code checked out elsewhere, with lines moved.
+	b, err := ReadBaseline(&buf, "/home/me/src")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ps := b.Filter([]Problem{
+		problem("/home/me/src/a/b.go", 10, "mutex re-acquired here (previously acquired at /home/me/src/a/b.go:8:2)"),
+		problem("/home/me/src/a/c.go", 5, "dup"),
+		problem("/home/me/src/a/c.go", 9, "dup"),
+		problem("/home/me/src/a/c.go", 12, "dup"),
+		problem("/home/me/src/a/d.go", 1, "new"),
+	})
+	if len(ps) != 2 || ps[0].Position.Line != 12 || ps[1].Text != "new" {
+		t.Errorf("unexpected problems after filtering: %v", ps)
+	}
+}
+
+func TestSortProblems(t *testing.T) {
+	at := func(file string, line, col int, check string) Problem {
+		return Problem{Position: token.Position{Filename: file, Line: line, Column: col}, Check: check, Text: "problem"}
+	}
+	ps := []Problem{
+		at("b.go", 1, 1, "SA2000"),
+		at("a.go", 2, 1, "SA2000"),
+		at("a.go", 1, 5, "SA2001"),
+		at("a.go", 1, 5, "SA2000"),
+		at("a.go", 1, 2, "SA2005"),
+	}
+	SortProblems(ps)
+	want := []Problem{
+		at("a.go", 1, 2, "SA2005"),
+		at("a.go", 1, 5, "SA2000"),
+		at("a.go", 1, 5, "SA2001"),
+		at("a.go", 2, 1, "SA2000"),
+		at("b.go", 1, 1, "SA2000"),
+	}
+	for i := range ps {
+		if ps[i].Position != want[i].Position || ps[i].Check != want[i].Check {
+			t.Fatalf("problem %d is %v %s, want %v %s", i, ps[i].Position, ps[i].Check, want[i].Position, want[i].Check)
+		}
+	}
+}
+
+func TestCountByCheck(t *testing.T) {
+	var ps []Problem
+	for _, check := range []string{"SA2000", "SA2005", "SA2001", "SA2005", "SA2000", "SA2005"} {
+		ps = append(ps, Problem{Check: check})
+	}
+	got := CountByCheck(ps)
+	want := []CheckCount{{"SA2005", 3}, {"SA2000", 2}, {"SA2001", 1}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("CountByCheck = %v, want %v", got, want)
+	}
+}
+
+func TestMalformedDirective(t *testing.T) {
+	ctx := buildutil.FakeContext(map[string]map[string]string{
+		"pkg": {"pkg.go": `package pkg
+
+//lint:ignore TEST1000
+func fn() {}
+`},
+	})
+	conf := &loader.Config{Build: ctx, ParserMode: parser.ParseComments}
+	conf.Import("pkg")
+	lprog, err := conf.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	prog := NewProgram(lprog, conf, 0)
+
+	var reported []Problem
+	l := &Linter{Checker: testChecker{}, Report: func(p Problem) { reported = append(reported, p) }}
+	if ps := l.LintProgram(prog); len(ps) != 0 {
+		t.Errorf("got %d returned problems with Report set, want 0", len(ps))
+	}
+	var malformed int
+	for _, p := range reported {
+		if strings.HasPrefix(p.Text, "malformed linter directive") {
+			malformed++
+		}
+	}
+	if malformed != 1 {
+		t.Errorf("malformed directive was reported %d times, want 1", malformed)
+	}
+
+	l = &Linter{Checker: testChecker{}, MinSeverity: SeverityError}
+	for _, p := range l.LintProgram(prog) {
+		if strings.HasPrefix(p.Text, "malformed linter directive") {
+			t.Errorf("malformed directive reported below the minimum severity: %v", p)
+		}
+	}
+}
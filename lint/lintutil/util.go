@@ -16,6 +16,7 @@
 package lintutil
 
 import (
+	"crypto/sha256"
 	"encoding/json"
 	"errors"
 	"flag"
@@ -25,10 +26,14 @@ import (
 	"go/token"
 	"go/types"
 	"io"
+	"io/ioutil"
 	"os"
 	"path/filepath"
+	"reflect"
+	"sort"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/Tengfei1010/GCBDetector/lint"
 	"honnef.co/go/tools/version"
@@ -69,7 +74,7 @@ func (o JSONOutput) Format(p lint.Problem) {
 	}{
 		p.Checker,
 		p.Check,
-		"", // TODO(dh): support severity
+		p.Severity.String(),
 		location{
 			p.Position.Filename,
 			p.Position.Line,
@@ -80,6 +85,31 @@ func (o JSONOutput) Format(p lint.Problem) {
 	}
 	_ = json.NewEncoder(o.w).Encode(jp)
 }
+
+// NDJSONOutput writes every problem as a flat JSON object on its own
+// line, for consumption by tools like jq.
+type NDJSONOutput struct {
+	w io.Writer
+}
+
+func (o NDJSONOutput) Format(p lint.Problem) {
+	jp := struct {
+		Check    string `json:"check"`
+		Message  string `json:"message"`
+		File     string `json:"file"`
+		Line     int    `json:"line"`
+		Col      int    `json:"col"`
+		Severity string `json:"severity"`
+	}{
+		p.Check,
+		p.Text,
+		p.Position.Filename,
+		p.Position.Line,
+		p.Position.Column,
+		p.Severity.String(),
+	}
+	_ = json.NewEncoder(o.w).Encode(jp)
+}
 func usage(name string, flags *flag.FlagSet) func() {
 	return func() {
 		fmt.Fprintf(os.Stderr, "Usage of %s:\n", name)
@@ -89,18 +119,39 @@ func usage(name string, flags *flag.FlagSet) func() {
 		fmt.Fprintf(os.Stderr, "\t%s [flags] files... # must be a single package\n", name)
 		fmt.Fprintf(os.Stderr, "Flags:\n")
 		flags.PrintDefaults()
+		fmt.Fprintf(os.Stderr, "Exit codes:\n")
+		fmt.Fprintf(os.Stderr, "\t%d\tno problems of error severity were found\n", ExitOK)
+		fmt.Fprintf(os.Stderr, "\t%d\tproblems of error severity were found\n", ExitProblems)
+		fmt.Fprintf(os.Stderr, "\t%d\tthe packages couldn't be loaded, or another error occurred\n", ExitError)
 	}
 }
 
+// The exit codes of ProcessFlagSet, which let scripts tell problems
+// found in the checked code from failures of the tool itself.
+const (
+	// ExitOK means that no problems of error severity were found;
+	// there may be less severe ones.
+	ExitOK = 0
+	// ExitProblems means that problems of error severity were found
+	// by a checker configured with ExitNonZero.
+	ExitProblems = 1
+	// ExitError means that the packages couldn't be loaded, the
+	// command line was invalid or another error occurred.
+	ExitError = 2
+)
+
 type runner struct {
 	checker       lint.Checker
 	tags          []string
 	ignores       []lint.Ignore
 	version       int
 	returnIgnored bool
+	minSeverity   lint.Severity
+	report        func(lint.Problem)
+	verbose       bool
 }
 
-func resolveRelative(importPaths []string, tags []string) (goFiles bool, err error) {
+func resolveRelative(importPaths []string, ctx *build.Context) (goFiles bool, err error) {
 	if len(importPaths) == 0 {
 		return false, nil
 	}
@@ -112,8 +163,6 @@ func resolveRelative(importPaths []string, tags []string) (goFiles bool, err err
 	if err != nil {
 		return false, err
 	}
-	ctx := build.Default
-	ctx.BuildTags = tags
 	for i, path := range importPaths {
 		bpkg, err := ctx.Import(path, wd, build.FindOnly)
 		if err != nil {
@@ -171,11 +220,19 @@ func FlagSet(name string) *flag.FlagSet {
 	flags.Usage = usage(name, flags)
 	flags.Float64("min_confidence", 0, "Deprecated; use -ignore instead")
 	flags.String("tags", "", "List of `build tags`")
+	flags.String("goos", build.Default.GOOS, "Target operating system, as in `GOOS`")
+	flags.String("goarch", build.Default.GOARCH, "Target architecture, as in `GOARCH`")
 	flags.String("ignore", "", "Space separated list of checks to ignore, in the following format: 'import/path/file.go:Check1,Check2,...' Both the import path and file name sections support globbing, e.g. 'os/exec/*_test.go'")
 	flags.Bool("tests", true, "Include tests")
 	flags.Bool("version", false, "Print version and exit")
+	flags.Bool("list", false, "Print the checks and their descriptions and exit")
 	flags.Bool("show-ignored", false, "Don't filter ignored problems")
-	flags.String("f", "text", "Output `format` (valid choices are 'text' and 'json')")
+	flags.String("min-severity", "info", "Only report problems of at least this `severity` ('info', 'warning' or 'error')")
+	flags.String("baseline", "", "Don't report problems recorded in the baseline `file`")
+	flags.String("write-baseline", "", "Record all problems in the baseline `file` instead of reporting them")
+	flags.Bool("v", false, "Log the phases of the analysis and how long they take to standard error")
+	flags.Bool("quiet", false, "Don't print the number of problems found per check to standard error")
+	flags.String("f", "text", "Output `format` (valid choices are 'text', 'json', 'ndjson' and 'sarif')")
 
 	tags := build.Default.ReleaseTags
 	v := tags[len(tags)-1][2:]
@@ -188,6 +245,59 @@ func FlagSet(name string) *flag.FlagSet {
 	return flags
 }
 
+// checkInfos returns the checks of cs. Checks of checkers that don't
+// describe them are returned with their code only.
+func checkInfos(cs []lint.Checker) []lint.CheckInfo {
+	var checks []lint.CheckInfo
+	for _, c := range cs {
+		if dc, ok := c.(lint.DocumentedChecker); ok {
+			checks = append(checks, dc.Checks()...)
+			continue
+		}
+		var codes []string
+		for code := range c.Funcs() {
+			codes = append(codes, code)
+		}
+		sort.Strings(codes)
+		for _, code := range codes {
+			checks = append(checks, lint.CheckInfo{Code: code})
+		}
+	}
+	return checks
+}
+
+// listChecks writes the checks of cs to w, as a JSON array if format
+// is "json" and as text otherwise.
+func listChecks(w io.Writer, cs []lint.Checker, format string) error {
+	checks := checkInfos(cs)
+	if format == "json" {
+		if checks == nil {
+			checks = []lint.CheckInfo{}
+		}
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(checks)
+	}
+	for _, check := range checks {
+		line := check.Code
+		if check.DefaultSeverity != "" {
+			line += " (" + check.DefaultSeverity + ")"
+		}
+		if check.Title != "" {
+			line += ": " + check.Title
+		}
+		if _, err := fmt.Fprintln(w, line); err != nil {
+			return err
+		}
+		if check.Description != "" {
+			if _, err := fmt.Fprintf(w, "\t%s\n", check.Description); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
 type CheckerConfig struct {
 	Checker     lint.Checker
 	ExitNonZero bool
@@ -195,37 +305,52 @@ type CheckerConfig struct {
 
 func ProcessFlagSet(confs []CheckerConfig, fs *flag.FlagSet) {
 	tags := fs.Lookup("tags").Value.(flag.Getter).Get().(string)
+	goos := fs.Lookup("goos").Value.(flag.Getter).Get().(string)
+	goarch := fs.Lookup("goarch").Value.(flag.Getter).Get().(string)
 	ignore := fs.Lookup("ignore").Value.(flag.Getter).Get().(string)
 	tests := fs.Lookup("tests").Value.(flag.Getter).Get().(bool)
 	goVersion := fs.Lookup("go").Value.(flag.Getter).Get().(int)
 	format := fs.Lookup("f").Value.(flag.Getter).Get().(string)
 	printVersion := fs.Lookup("version").Value.(flag.Getter).Get().(bool)
+	list := fs.Lookup("list").Value.(flag.Getter).Get().(bool)
 	showIgnored := fs.Lookup("show-ignored").Value.(flag.Getter).Get().(bool)
+	baselineFile := fs.Lookup("baseline").Value.(flag.Getter).Get().(string)
+	writeBaseline := fs.Lookup("write-baseline").Value.(flag.Getter).Get().(string)
+	quiet := fs.Lookup("quiet").Value.(flag.Getter).Get().(bool)
+	verbose := fs.Lookup("v").Value.(flag.Getter).Get().(bool)
+	minSeverity, err := lint.ParseSeverity(fs.Lookup("min-severity").Value.(flag.Getter).Get().(string))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(ExitError)
+	}
 
 	if printVersion {
 		version.Print()
-		os.Exit(0)
+		os.Exit(ExitOK)
 	}
 
 	var cs []lint.Checker
 	for _, conf := range confs {
 		cs = append(cs, conf.Checker)
 	}
-	pss, err := Lint(cs, fs.Args(), &Options{
+	if list {
+		if err := listChecks(os.Stdout, cs, format); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(ExitError)
+		}
+		os.Exit(ExitOK)
+	}
+
+	opts := &Options{
 		Tags:          strings.Fields(tags),
+		GOOS:          goos,
+		GOARCH:        goarch,
 		LintTests:     tests,
 		Ignores:       ignore,
 		GoVersion:     goVersion,
 		ReturnIgnored: showIgnored,
-	})
-	if err != nil {
-		fmt.Fprintln(os.Stderr, err)
-		os.Exit(1)
-	}
-
-	var ps []lint.Problem
-	for _, p := range pss {
-		ps = append(ps, p...)
+		MinSeverity:   minSeverity,
+		Verbose:       verbose,
 	}
 
 	var f OutputFormatter
@@ -234,27 +359,167 @@ func ProcessFlagSet(confs []CheckerConfig, fs *flag.FlagSet) {
 		f = TextOutput{os.Stdout}
 	case "json":
 		f = JSONOutput{os.Stdout}
+	case "ndjson":
+		f = NDJSONOutput{os.Stdout}
+	case "sarif":
+		// SARIF is a single document, written once all problems
+		// are known
 	default:
 		fmt.Fprintf(os.Stderr, "unsupported output format %q\n", format)
-		os.Exit(2)
+		os.Exit(ExitError)
+	}
+
+	// Baselines name files relative to the module root, so that
+	// they can be shared between checkouts.
+	root := lint.ModuleRoot(".")
+	var baseline *lint.Baseline
+	if baselineFile != "" {
+		r, err := os.Open(baselineFile)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(ExitError)
+		}
+		baseline, err = lint.ReadBaseline(r, root)
+		r.Close()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "invalid baseline %s: %v\n", baselineFile, err)
+			os.Exit(ExitError)
+		}
+	}
+
+	// failed records the checkers that found problems of error
+	// severity
+	failed := map[string]bool{}
+	// streamed records the problems streamed as ndjson, for the
+	// summary
+	var streamed []lint.Problem
+	// ndjson is streamed as problems are found, instead of being
+	// sorted and printed at the end
+	if format == "ndjson" && writeBaseline == "" {
+		opts.Report = func(p lint.Problem) {
+			if baseline != nil && baseline.Known(p) {
+				return
+			}
+			if p.Severity >= lint.SeverityError {
+				failed[p.Checker] = true
+			}
+			f.Format(p)
+			streamed = append(streamed, p)
+		}
+	}
+
+	pss, err := Lint(cs, fs.Args(), opts)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(ExitError)
 	}
 
+	if writeBaseline != "" {
+		var all []lint.Problem
+		for _, p := range pss {
+			all = append(all, p...)
+		}
+		if err := writeBaselineFile(writeBaseline, lint.NewBaseline(all, root)); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(ExitError)
+		}
+		return
+	}
+	if baseline != nil {
+		for i := range pss {
+			pss[i] = baseline.Filter(pss[i])
+		}
+	}
+
+	var ps []lint.Problem
+	for _, p := range pss {
+		ps = append(ps, p...)
+	}
+	// problems of all checkers are interleaved by position
+	lint.SortProblems(ps)
+
+	switch format {
+	case "sarif":
+		if err := lint.WriteSARIF(os.Stdout, ps, checkInfos(cs)); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(ExitError)
+		}
+	case "ndjson":
+	default:
+		for _, p := range ps {
+			f.Format(p)
+		}
+	}
 	for _, p := range ps {
-		f.Format(p)
+		if p.Severity >= lint.SeverityError {
+			failed[p.Checker] = true
+		}
 	}
-	for i, p := range pss {
-		if len(p) != 0 && confs[i].ExitNonZero {
-			os.Exit(1)
+	if !quiet {
+		writeSummary(os.Stderr, append(ps, streamed...))
+	}
+	for _, conf := range confs {
+		if failed[conf.Checker.Name()] && conf.ExitNonZero {
+			os.Exit(ExitProblems)
 		}
 	}
 }
 
+// writeSummary writes the number of problems in ps per check to w,
+// e.g. "SA2005: 12, SA2000: 3", most frequent first. It writes
+// nothing if there are no problems.
+func writeSummary(w io.Writer, ps []lint.Problem) {
+	counts := lint.CountByCheck(ps)
+	if len(counts) == 0 {
+		return
+	}
+	parts := make([]string, len(counts))
+	for i, c := range counts {
+		parts[i] = fmt.Sprintf("%s: %d", c.Check, c.Count)
+	}
+	fmt.Fprintln(w, strings.Join(parts, ", "))
+}
+
+func writeBaselineFile(name string, b *lint.Baseline) error {
+	w, err := os.Create(name)
+	if err != nil {
+		return err
+	}
+	if _, err := b.WriteTo(w); err != nil {
+		w.Close()
+		return err
+	}
+	return w.Close()
+}
+
 type Options struct {
 	Tags          []string
 	LintTests     bool
 	Ignores       string
 	GoVersion     int
 	ReturnIgnored bool
+	// GOOS and GOARCH select the target platform; files excluded
+	// by build constraints for it aren't loaded. They default to
+	// those of go/build.Default.
+	GOOS   string
+	GOARCH string
+	// MinSeverity is the lowest severity of problems that are
+	// reported.
+	MinSeverity lint.Severity
+
+	// Report, if set, is called with every problem as soon as it is
+	// found, instead of the problem being returned by Lint.
+	Report func(lint.Problem)
+	// Verbose logs the phases of loading and checking the program,
+	// and how long they take, to standard error.
+	Verbose bool
+}
+
+// logf logs a phase that started at start, if opt.Verbose is set.
+func (opt *Options) logf(start time.Time, format string, args ...interface{}) {
+	if opt.Verbose {
+		fmt.Fprintf(os.Stderr, "%s: %v\n", fmt.Sprintf(format, args...), time.Since(start).Round(time.Millisecond))
+	}
 }
 
 func Lint(cs []lint.Checker, pkgs []string, opt *Options) ([][]lint.Problem, error) {
@@ -265,13 +530,43 @@ func Lint(cs []lint.Checker, pkgs []string, opt *Options) ([][]lint.Problem, err
 	if err != nil {
 		return nil, err
 	}
+	prog, err := LoadProgram(pkgs, opt)
+	if err != nil {
+		return nil, err
+	}
+
+	var problems [][]lint.Problem
+	for _, c := range cs {
+		runner := &runner{
+			checker:       c,
+			tags:          opt.Tags,
+			ignores:       ignores,
+			version:       opt.GoVersion,
+			returnIgnored: opt.ReturnIgnored,
+			minSeverity:   opt.MinSeverity,
+			report:        opt.Report,
+			verbose:       opt.Verbose,
+		}
+		problems = append(problems, runner.lint(prog))
+	}
+	return problems, nil
+}
+
+// LoadProgram loads the packages named by pkgs, which may be import
+// paths, patterns like ./..., or the .go files of a single package,
+// and prepares them for linting as a single program. Checks see
+// calls, locks and values crossing the boundaries of the loaded
+// packages.
+func LoadProgram(pkgs []string, opt *Options) (*lint.Program, error) {
+	if opt == nil {
+		opt = &Options{}
+	}
+	ctx := buildContext(opt)
 	paths := gotool.ImportPaths(pkgs)
-	goFiles, err := resolveRelative(paths, opt.Tags)
+	goFiles, err := resolveRelative(paths, &ctx)
 	if err != nil {
 		return nil, err
 	}
-	ctx := build.Default
-	ctx.BuildTags = opt.Tags
 	hadError := false
 	conf := &loader.Config{
 		Build:      &ctx,
@@ -290,29 +585,151 @@ func Lint(cs []lint.Checker, pkgs []string, opt *Options) ([][]lint.Problem, err
 		},
 	}
 	if goFiles {
-		conf.CreateFromFilenames("adhoc", paths...)
+		// Files named explicitly are filtered like those of a
+		// package, so that only the selected variant is analyzed.
+		var files []string
+		for _, path := range paths {
+			ok, err := ctx.MatchFile(filepath.Dir(path), filepath.Base(path))
+			if err != nil {
+				return nil, err
+			}
+			if ok {
+				files = append(files, path)
+			}
+		}
+		if len(files) == 0 {
+			return nil, errors.New("build constraints exclude all Go files")
+		}
+		conf.CreateFromFilenames("adhoc", files...)
 	} else {
 		for _, path := range paths {
 			conf.ImportPkgs[path] = opt.LintTests
 		}
 	}
+	start := time.Now()
 	lprog, err := conf.Load()
 	if err != nil {
 		return nil, err
 	}
+	opt.logf(start, "loading %d packages", len(lprog.AllPackages))
+	start = time.Now()
+	prog, err := newProgram(lprog, conf, opt.GoVersion)
+	if err != nil {
+		return nil, err
+	}
+	opt.logf(start, "building SSA")
+	return prog, nil
+}
 
-	var problems [][]lint.Problem
-	for _, c := range cs {
-		runner := &runner{
-			checker:       c,
-			tags:          opt.Tags,
-			ignores:       ignores,
-			version:       opt.GoVersion,
-			returnIgnored: opt.ReturnIgnored,
+// A Cache holds the program loaded for a set of packages, for
+// long-running processes, such as editor integrations, that check the
+// same packages repeatedly. The program is only loaded again when the
+// sources of one of its packages changed, as determined by hashing
+// their content; otherwise the same program is returned, and checkers
+// running on it again reuse what they computed on the previous run.
+//
+// A loaded program can't be updated, so after a change all packages
+// are loaded and checked again, not only the ones that changed.
+type Cache struct {
+	pkgs   []string
+	opt    *Options
+	paths  []string
+	prog   *lint.Program
+	hashes map[string][sha256.Size]byte
+}
+
+// NewCache returns a cache for the packages named by pkgs, which are
+// loaded with LoadProgram.
+func NewCache(pkgs []string, opt *Options) *Cache {
+	if opt == nil {
+		opt = &Options{}
+	}
+	return &Cache{pkgs: pkgs, opt: opt}
+}
+
+// Program returns the program of the packages of c. It is the program
+// returned by the previous call unless the patterns match other
+// packages by now or the Go files in the directory of a package of
+// the program, dependencies included, changed. Changes made while the
+// program is being loaded are only noticed once another change
+// happens.
+func (c *Cache) Program() (*lint.Program, error) {
+	paths := gotool.ImportPaths(c.pkgs)
+	if c.prog != nil && reflect.DeepEqual(paths, c.paths) {
+		start := time.Now()
+		hashes := sourceHashes(c.prog)
+		c.opt.logf(start, "hashing the sources of %d directories", len(hashes))
+		if reflect.DeepEqual(hashes, c.hashes) {
+			return c.prog, nil
 		}
-		problems = append(problems, runner.lint(lprog, conf))
 	}
-	return problems, nil
+	prog, err := LoadProgram(c.pkgs, c.opt)
+	if err != nil {
+		return nil, err
+	}
+	c.prog, c.paths, c.hashes = prog, paths, sourceHashes(prog)
+	return prog, nil
+}
+
+// sourceHashes returns the hashes of the Go files in the directories
+// of the packages of prog, by directory.
+func sourceHashes(prog *lint.Program) map[string][sha256.Size]byte {
+	hashes := map[string][sha256.Size]byte{}
+	for _, pkginfo := range prog.Prog.AllPackages {
+		for _, f := range pkginfo.Files {
+			dir := filepath.Dir(prog.Prog.Fset.Position(f.Pos()).Filename)
+			if _, ok := hashes[dir]; !ok {
+				hashes[dir] = hashDir(dir)
+			}
+		}
+	}
+	return hashes
+}
+
+// hashDir hashes the names and contents of the Go files in dir. A
+// directory that can't be read has the zero hash.
+func hashDir(dir string) [sha256.Size]byte {
+	var sum [sha256.Size]byte
+	names, err := filepath.Glob(filepath.Join(dir, "*.go"))
+	if err != nil {
+		return sum
+	}
+	h := sha256.New()
+	for _, name := range names {
+		src, err := ioutil.ReadFile(name)
+		if err != nil {
+			return sum
+		}
+		fmt.Fprintf(h, "%s %d\n", filepath.Base(name), len(src))
+		h.Write(src)
+	}
+	copy(sum[:], h.Sum(nil))
+	return sum
+}
+
+// newProgram builds the SSA form of lprog. The builder panics on code
+// it can't handle, which is returned as an error instead, so that it
+// is reported as a failure of the tool.
+func newProgram(lprog *loader.Program, conf *loader.Config, goVersion int) (prog *lint.Program, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("building SSA form: %v", r)
+		}
+	}()
+	return lint.NewProgram(lprog, conf, goVersion), nil
+}
+
+// buildContext returns the build context selected by opt.
+func buildContext(opt *Options) build.Context {
+	ctx := build.Default
+	ctx.BuildTags = opt.Tags
+	if opt.GOOS != "" {
+		ctx.GOOS = opt.GOOS
+	}
+	if opt.GOARCH != "" {
+		ctx.GOARCH = opt.GOARCH
+	}
+	return ctx
 }
 
 func shortPath(path string) string {
@@ -347,12 +764,15 @@ func ProcessArgs(name string, cs []CheckerConfig, args []string) {
 	ProcessFlagSet(cs, flags)
 }
 
-func (runner *runner) lint(lprog *loader.Program, conf *loader.Config) []lint.Problem {
+func (runner *runner) lint(prog *lint.Program) []lint.Problem {
 	l := &lint.Linter{
 		Checker:       runner.checker,
 		Ignores:       runner.ignores,
 		GoVersion:     runner.version,
 		ReturnIgnored: runner.returnIgnored,
+		MinSeverity:   runner.minSeverity,
+		Report:        runner.report,
+		Verbose:       runner.verbose,
 	}
-	return l.Lint(lprog, conf)
+	return l.LintProgram(prog)
 }
@@ -0,0 +1,183 @@
+package lint
+
+import (
+	"encoding/json"
+	"io"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+const (
+	sarifVersion = "2.1.0"
+	sarifSchema  = "https://json.schemastore.org/sarif-2.1.0.json"
+)
+
+type sarifLog struct {
+	Version string     `json:"version"`
+	Schema  string     `json:"$schema"`
+	Runs    []sarifRun `json:"runs"`
+}
+
+type sarifRun struct {
+	Tool    sarifTool     `json:"tool"`
+	Results []sarifResult `json:"results"`
+}
+
+type sarifTool struct {
+	Driver sarifDriver `json:"driver"`
+}
+
+type sarifDriver struct {
+	Name  string      `json:"name"`
+	Rules []sarifRule `json:"rules"`
+}
+
+type sarifRule struct {
+	ID                   string              `json:"id"`
+	ShortDescription     *sarifText          `json:"shortDescription,omitempty"`
+	FullDescription      *sarifText          `json:"fullDescription,omitempty"`
+	DefaultConfiguration *sarifConfiguration `json:"defaultConfiguration,omitempty"`
+}
+
+type sarifConfiguration struct {
+	Level string `json:"level"`
+}
+
+type sarifText struct {
+	Text string `json:"text"`
+}
+
+type sarifResult struct {
+	RuleID       string             `json:"ruleId"`
+	RuleIndex    int                `json:"ruleIndex"`
+	Level        string             `json:"level"`
+	Message      sarifText          `json:"message"`
+	Locations    []sarifLocation    `json:"locations,omitempty"`
+	Suppressions []sarifSuppression `json:"suppressions,omitempty"`
+}
+
+type sarifLocation struct {
+	PhysicalLocation sarifPhysicalLocation `json:"physicalLocation"`
+}
+
+type sarifPhysicalLocation struct {
+	ArtifactLocation sarifArtifactLocation `json:"artifactLocation"`
+	Region           *sarifRegion          `json:"region,omitempty"`
+}
+
+type sarifArtifactLocation struct {
+	URI string `json:"uri"`
+}
+
+type sarifRegion struct {
+	StartLine   int `json:"startLine"`
+	StartColumn int `json:"startColumn,omitempty"`
+}
+
+type sarifSuppression struct {
+	Kind string `json:"kind"`
+}
+
+// WriteSARIF writes problems to w as a SARIF 2.1.0 log with a single
+// run. Every distinct check becomes a rule, described by its entry in
+// checks, if any. Ignored problems are included, marked as suppressed
+// in source.
+func WriteSARIF(w io.Writer, problems []Problem, checks []CheckInfo) error {
+	log := sarifLog{
+		Version: sarifVersion,
+		Schema:  sarifSchema,
+		Runs: []sarifRun{{
+			Tool:    sarifTool{Driver: sarifDriver{Name: "GCBDetector", Rules: []sarifRule{}}},
+			Results: []sarifResult{},
+		}},
+	}
+	run := &log.Runs[0]
+
+	seen := map[string]bool{}
+	var codes []string
+	for _, p := range problems {
+		if !seen[p.Check] {
+			seen[p.Check] = true
+			codes = append(codes, p.Check)
+		}
+	}
+	sort.Strings(codes)
+	infos := map[string]CheckInfo{}
+	for _, info := range checks {
+		infos[info.Code] = info
+	}
+	index := map[string]int{}
+	for i, code := range codes {
+		index[code] = i
+		rule := sarifRule{ID: code}
+		info := infos[code]
+		if info.Title != "" {
+			rule.ShortDescription = &sarifText{info.Title}
+		}
+		if info.Description != "" {
+			rule.FullDescription = &sarifText{info.Description}
+		}
+		if sev, err := ParseSeverity(info.DefaultSeverity); err == nil {
+			rule.DefaultConfiguration = &sarifConfiguration{sarifLevel(sev)}
+		}
+		run.Tool.Driver.Rules = append(run.Tool.Driver.Rules, rule)
+	}
+
+	for _, p := range problems {
+		res := sarifResult{
+			RuleID:    p.Check,
+			RuleIndex: index[p.Check],
+			Level:     sarifLevel(p.Severity),
+			Message:   sarifText{p.Text},
+		}
+		if p.Position.Filename != "" {
+			loc := sarifPhysicalLocation{
+				ArtifactLocation: sarifArtifactLocation{sarifURI(p.Position.Filename)},
+			}
+			// token.Position is 1-based like SARIF regions; zero
+			// means the line or column is unknown.
+			if p.Position.Line > 0 {
+				loc.Region = &sarifRegion{StartLine: p.Position.Line}
+				if p.Position.Column > 0 {
+					loc.Region.StartColumn = p.Position.Column
+				}
+			}
+			res.Locations = []sarifLocation{{loc}}
+		}
+		if p.Ignored {
+			res.Suppressions = []sarifSuppression{{Kind: "inSource"}}
+		}
+		run.Results = append(run.Results, res)
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(log)
+}
+
+// sarifLevel returns the SARIF result level of a severity.
+func sarifLevel(sev Severity) string {
+	switch sev {
+	case SeverityInfo:
+		return "note"
+	case SeverityError:
+		return "error"
+	}
+	return "warning"
+}
+
+// sarifURI converts a file name to the URI form SARIF expects.
+// Relative names are kept relative, so that viewers resolve them
+// against the checkout.
+func sarifURI(name string) string {
+	if !filepath.IsAbs(name) {
+		return filepath.ToSlash(name)
+	}
+	name = filepath.ToSlash(name)
+	if !strings.HasPrefix(name, "/") {
+		// Windows drive letter
+		name = "/" + name
+	}
+	return "file://" + name
+}
@@ -0,0 +1,7 @@
+package pkg
+
+func fn1() {} // MATCH /This is a test problem/
+
+func fn2() { // MATCH /This is a test problem/
+	fn1()
+}
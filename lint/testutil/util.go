@@ -35,8 +35,20 @@ import (
 
 var lintMatch = flag.String("lint.match", "", "restrict testdata matches to this pattern")
 
+// TestAll runs c on the files in testdata/dir, relative to the
+// package being tested. See TestDir.
 func TestAll(t *testing.T, c lint.Checker, dir string) {
-	baseDir := filepath.Join("testdata", dir)
+	TestDir(t, c, filepath.Join("testdata", dir))
+}
+
+// TestDir runs c on the Go files in baseDir, each of which is loaded
+// as a package of its own, and compares the problems reported in
+// every file with the instructions in its comments. An instruction is
+// either "MATCH /regexp/", "MATCH:line /regexp/" for a problem on
+// another line, or "want `regexp`..." with one pattern per problem
+// expected on the line. Problems without a matching instruction fail
+// the test, as do instructions without a matching problem.
+func TestDir(t *testing.T, c lint.Checker, baseDir string) {
 	fis, err := ioutil.ReadDir(baseDir)
 	if err != nil {
 		t.Fatalf("ioutil.ReadDir: %v", err)
@@ -166,6 +178,16 @@ func parseInstructions(t *testing.T, filename string, src []byte) []instruction
 				ins = make([]instruction, 0)
 				continue
 			}
+			if strings.HasPrefix(line, "want ") {
+				rxs, err := extractWant(line)
+				if err != nil {
+					t.Fatalf("At %v:%d: %v", filename, ln, err)
+				}
+				for _, rx := range rxs {
+					ins = append(ins, instruction{Line: ln, Match: rx})
+				}
+				continue
+			}
 			if !strings.Contains(line, "MATCH") {
 				continue
 			}
@@ -228,6 +250,37 @@ func extractPattern(line string) (*regexp.Regexp, error) {
 	return rx, nil
 }
 
+// extractWant parses a want instruction in the style of
+// go/analysis's analysistest: the word want followed by one or more
+// Go string literals, each holding a regular expression that has to
+// match one problem reported on the line, e.g.
+//
+//	r.Lock() // want `Acquiring the Lock again at .*:19:8`
+func extractWant(line string) ([]*regexp.Regexp, error) {
+	rest := strings.TrimSpace(strings.TrimPrefix(line, "want "))
+	var rxs []*regexp.Regexp
+	for rest != "" {
+		lit, err := strconv.QuotedPrefix(rest)
+		if err != nil {
+			return nil, fmt.Errorf("malformed want instruction %q", line)
+		}
+		pat, err := strconv.Unquote(lit)
+		if err != nil {
+			return nil, fmt.Errorf("malformed want instruction %q", line)
+		}
+		rx, err := regexp.Compile(pat)
+		if err != nil {
+			return nil, fmt.Errorf("bad want pattern %q: %v", pat, err)
+		}
+		rxs = append(rxs, rx)
+		rest = strings.TrimSpace(rest[len(lit):])
+	}
+	if len(rxs) == 0 {
+		return nil, fmt.Errorf("want instruction without pattern %q", line)
+	}
+	return rxs, nil
+}
+
 func extractReplacement(line string) (string, bool) {
 	// Look for this:  / -> `
 	// (the end of a match and start of a backtick string),
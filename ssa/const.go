@@ -71,6 +71,12 @@ func zeroConst(t types.Type) *Const {
 		return nilConst(t)
 	case *types.Named:
 		return NewConst(zeroConst(t.Underlying()).Value, t)
+	case *types.Alias:
+		return NewConst(zeroConst(types.Unalias(t)).Value, t)
+	case *types.TypeParam:
+		// The zero value of a type parameter has no constant
+		// representation; like nil, it is denoted by a nil Value.
+		return nilConst(t)
 	case *types.Array, *types.Struct, *types.Tuple:
 		panic(fmt.Sprint("zeroConst applied to aggregate:", t))
 	}
@@ -82,7 +82,7 @@ func emitArith(f *Function, op token.Token, x, y Value, t types.Type, pos token.
 		x = emitConv(f, x, t)
 		// y may be signed or an 'untyped' constant.
 		// TODO(adonovan): whence signed values?
-		if b, ok := y.Type().Underlying().(*types.Basic); ok && b.Info()&types.IsUnsigned == 0 {
+		if b, ok := coreType(y.Type()).(*types.Basic); ok && b.Info()&types.IsUnsigned == 0 {
 			y = emitConv(f, y, types.Typ[types.Uint64])
 		}
 
@@ -108,8 +108,8 @@ func emitArith(f *Function, op token.Token, x, y Value, t types.Type, pos token.
 // comparison comparison 'x op y'.
 //
 func emitCompare(f *Function, op token.Token, x, y Value, pos token.Pos) Value {
-	xt := x.Type().Underlying()
-	yt := y.Type().Underlying()
+	xt := coreType(x.Type())
+	yt := coreType(y.Type())
 
 	// Special case to optimise a tagless SwitchStmt so that
 	// these are equivalent
@@ -365,7 +365,7 @@ func emitTailCall(f *Function, call *Call) {
 //
 func emitImplicitSelections(f *Function, v Value, indices []int) Value {
 	for _, index := range indices {
-		fld := deref(v.Type()).Underlying().(*types.Struct).Field(index)
+		fld := coreType(deref(v.Type())).(*types.Struct).Field(index)
 
 		if isPointer(v.Type()) {
 			instr := &FieldAddr{
@@ -398,7 +398,7 @@ func emitImplicitSelections(f *Function, v Value, indices []int) Value {
 // Ident id is used for position and debug info.
 //
 func emitFieldSelection(f *Function, v Value, index int, wantAddr bool, id *ast.Ident) Value {
-	fld := deref(v.Type()).Underlying().(*types.Struct).Field(index)
+	fld := coreType(deref(v.Type())).(*types.Struct).Field(index)
 	if isPointer(v.Type()) {
 		instr := &FieldAddr{
 			X:     v,
@@ -428,7 +428,7 @@ func emitFieldSelection(f *Function, v Value, index int, wantAddr bool, id *ast.
 // and returns it.
 //
 func zeroValue(f *Function, t types.Type) Value {
-	switch t.Underlying().(type) {
+	switch coreType(t).(type) {
 	case *types.Struct, *types.Array:
 		return emitLoad(f, f.addLocal(t, token.NoPos))
 	default:
@@ -393,7 +393,7 @@ type newPhiMap map[*BasicBlock][]newPhi
 func liftAlloc(df domFrontier, alloc *Alloc, newPhis newPhiMap, fresh *int) bool {
 	// Don't lift aggregates into registers, because we don't have
 	// a way to express their zero-constants.
-	switch deref(alloc.Type()).Underlying().(type) {
+	switch coreType(deref(alloc.Type())).(type) {
 	case *types.Array, *types.Struct:
 		return false
 	}
@@ -124,7 +124,12 @@ func (prog *Program) RuntimeTypes() []types.Type {
 // declaredFunc returns the concrete function/method denoted by obj.
 // Panic ensues if there is none.
 //
+// Instantiations of generic functions and methods of instantiated
+// types aren't materialized; they are represented by their generic
+// origin.
+//
 func (prog *Program) declaredFunc(obj *types.Func) *Function {
+	obj = obj.Origin()
 	if v := prog.packageLevelValue(obj); v != nil {
 		return v.(*Function)
 	}
@@ -240,6 +245,12 @@ func (prog *Program) needMethods(T types.Type, skip bool) {
 			prog.needMethods(t.At(i).Type(), false)
 		}
 
+	case *types.TypeParam:
+		// nop---type parameters have no runtime type of their own.
+
+	case *types.Alias:
+		prog.needMethods(types.Unalias(t), skip)
+
 	default:
 		panic(T)
 	}
@@ -40,7 +40,7 @@ func isBlankIdent(e ast.Expr) bool {
 
 // isPointer returns true for types whose underlying type is a pointer.
 func isPointer(typ types.Type) bool {
-	_, ok := typ.Underlying().(*types.Pointer)
+	_, ok := coreType(typ).(*types.Pointer)
 	return ok
 }
 
@@ -48,12 +48,51 @@ func isInterface(T types.Type) bool { return types.IsInterface(T) }
 
 // deref returns a pointer's element type; otherwise it returns typ.
 func deref(typ types.Type) types.Type {
-	if p, ok := typ.Underlying().(*types.Pointer); ok {
+	if p, ok := coreType(typ).(*types.Pointer); ok {
 		return p.Elem()
 	}
 	return typ
 }
 
+// coreType returns the core type of T. For ordinary types that is
+// the underlying type. For a type parameter it is the underlying type
+// shared by all types in its type set, or the constraint interface if
+// the constraint doesn't restrict the underlying type (e.g. any, or
+// an interface listing only methods).
+func coreType(T types.Type) types.Type {
+	tp, ok := types.Unalias(T).(*types.TypeParam)
+	if !ok {
+		return T.Underlying()
+	}
+	iface := tp.Constraint().Underlying().(*types.Interface)
+	var core types.Type
+	for i := 0; i < iface.NumEmbeddeds(); i++ {
+		var terms []types.Type
+		if u, ok := iface.EmbeddedType(i).(*types.Union); ok {
+			for j := 0; j < u.Len(); j++ {
+				terms = append(terms, u.Term(j).Type())
+			}
+		} else {
+			terms = append(terms, iface.EmbeddedType(i))
+		}
+		for _, t := range terms {
+			u := coreType(t)
+			if _, ok := u.(*types.Interface); ok {
+				continue
+			}
+			if core == nil {
+				core = u
+			} else if !types.Identical(core, u) {
+				return iface
+			}
+		}
+	}
+	if core == nil {
+		return iface
+	}
+	return core
+}
+
 // recvType returns the receiver type of method obj.
 func recvType(obj *types.Func) types.Type {
 	return obj.Type().(*types.Signature).Recv().Type()
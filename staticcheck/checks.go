@@ -0,0 +1,94 @@
+package staticcheck
+
+import (
+	"sort"
+
+	"github.com/Tengfei1010/GCBDetector/lint"
+)
+
+// descriptions explains what the checks find and why it is a problem.
+// The titles of the checks are in docs.
+var descriptions = map[string]string{
+	"SA2000": "Calling WaitGroup.Add in the goroutine that calls Done races with Wait: Wait may see a zero counter and return before the goroutine has started. Call Add before the go statement.",
+	"SA2001": "Locking a mutex and unlocking it right away, without anything in between, protects nothing. Either the critical section is missing or the lock is only used to wait for other holders, which deserves a comment.",
+	"SA2002": "testing.T.FailNow, SkipNow and the functions calling them, such as Fatal and Skip, stop the goroutine calling them. Called in a goroutine other than the test's, they don't stop the test.",
+	"SA2003": "Deferring Lock, or the wrong kind of unlock, right after acquiring a lock leaves it held, or releases the wrong kind, when the function returns. Usually the matching unlock was meant to be deferred.",
+	"SA2004": "Unlocking a lock right after acquiring it leaves the code that follows unprotected. Usually the unlock was meant to be deferred.",
+	"SA2005": "Acquiring a lock that is already held by the same goroutine, directly or through calls, deadlocks: Go's locks aren't reentrant. Read locks are checked by SA2049.",
+	"SA2006": "A variable written by a goroutine and accessed by the function starting it, or by another goroutine, without synchronization is a data race.",
+	"SA2008": "Reports how often the checked code uses each synchronization primitive. It doesn't find bugs.",
+	"SA2009": "A WaitGroup whose counter is changed with Add and Done but that is never waited on doesn't synchronize anything; the goroutines it accounts for may outlive their creator.",
+	"SA2010": "A lock that isn't released on some path returning from the function that acquired it stays held, and the next attempt to acquire it deadlocks. Deferring the unlock covers every path.",
+	"SA2011": "Locks acquired nested in one another in orders that form a cycle, such as A then B in one place and B then A in another, can deadlock when the places run concurrently.",
+	"SA2012": "Unlocking a lock that isn't held panics. The lock is local to the function and isn't acquired on any path before the unlock.",
+	"SA2013": "Cond.Wait can return even though the condition it waits for doesn't hold, because another goroutine may change it first. It has to be called in a loop that rechecks the condition.",
+	"SA2014": "Cond.Wait unlocks the Cond's lock before waiting and locks it again before returning, so the lock has to be held when calling it; otherwise Wait panics.",
+	"SA2015": "Closing a channel that is already closed panics. The channel may be closed twice on some path, in a later loop iteration or by a deferred close.",
+	"SA2016": "Sending on a closed channel panics. The send can happen after the channel was closed.",
+	"SA2017": "Before Go 1.22, a loop variable is shared by all iterations. A goroutine capturing it may read a later iteration's value; pass it as an argument instead.",
+	"SA2018": "Every call of time.After creates a timer that isn't released until it fires. Calling it in a loop, typically in a select, accumulates timers; use a time.Timer and Reset it.",
+	"SA2019": "The cancel function returned by context.WithCancel, WithTimeout and WithDeadline releases the resources of the context. Not calling it on every path leaks them until the parent context is canceled.",
+	"SA2020": "A select with a default case in a loop that doesn't otherwise block spins and burns a CPU while waiting. Remove the default case or wait between iterations.",
+	"SA2021": "RWMutex can't upgrade a read lock to a write lock: calling Lock while holding the read lock waits for all readers, including the caller, and deadlocks.",
+	"SA2022": "A deferred unlock runs when the function returns, not at the end of the loop iteration, so the lock acquired in the next iteration is still held and the loop deadlocks.",
+	"SA2023": "A goroutine sending on an unbuffered channel that nothing receives from blocks forever and leaks.",
+	"SA2024": "Copying a value containing a lock copies the lock's state, and the copy no longer synchronizes with the original. Pass and store such values by pointer.",
+	"SA2025": "sync.Map.LoadOrStore reports whether the key was already present. Ignoring it means the value passed in may be discarded without the code noticing.",
+	"SA2026": "Accessing a variable with sync/atomic in one place and with plain loads or stores in another is a data race; every concurrent access has to be atomic.",
+	"SA2027": "A goroutine started on a method with a value receiver runs on a copy of the receiver, including the locks it contains, so it doesn't synchronize with the caller.",
+	"SA2028": "Calling WaitGroup.Wait in the loop that starts the goroutines it waits for serializes them, and blocks before the goroutines of later iterations are even started.",
+	"SA2029": "A function calling itself, directly or through other functions, while holding a lock that the call acquires again deadlocks.",
+	"SA2030": "A goroutine sending on an unbuffered channel blocks forever if the function that started it returns without receiving, for example on an error path.",
+	"SA2031": "Maps aren't safe for concurrent use. Writing a map in one goroutine while another accesses it, without a lock, is a data race and may crash the program.",
+	"SA2032": "The deferred unlock doesn't match how the lock was acquired, e.g. RUnlock after Lock, which panics or leaves the lock held.",
+	"SA2033": "A goroutine blocking on a channel operation without also selecting on the Done channel of the context it was given leaks when the context is canceled.",
+	"SA2034": "Waiting for a goroutine, with a WaitGroup or a channel, while holding a lock that the goroutine acquires deadlocks: the goroutine can't finish until the lock is released.",
+	"SA2035": "A time.Timer or time.Ticker that is never stopped isn't released until it fires, and a Ticker never is. Stop it when it's no longer needed.",
+	"SA2036": "Goroutines started by init functions run concurrently with the rest of the initialization and main, which makes the program's start-up order hard to reason about.",
+	"SA2037": "Receiving from or sending on a nil channel blocks forever, so a select whose channels are all nil, and that has no default case, never proceeds.",
+	"SA2038": "Blocking on a channel while holding a lock deadlocks if the goroutine on the other end of the channel needs the lock to get there.",
+	"SA2039": "Sleeping while holding a lock blocks every goroutine waiting for the lock for at least the duration of the sleep.",
+	"SA2040": "An HTTP handler waiting for a goroutine without a timeout, and without observing the request's context, keeps the request open for as long as the goroutine takes, even if the client has gone away.",
+	"SA2041": "The goroutines of an errgroup.Group started with Go have to be waited for with Wait, which also returns their first error. Not waiting leaks them; discarding the error returned by Wait ignores their failures.",
+	"SA2042": "An Acquire of a semaphore.Weighted that isn't matched by a Release on every path uses up its weight for good, and eventually acquiring it blocks forever.",
+	"SA2043": "A goroutine running a loop without an exit that doesn't communicate on channels or check a context runs until the program ends and can't be stopped. That may be intended for daemons, but is often a leak.",
+	"SA2044": "Unlocking a lock that was only acquired on some paths leading to the unlock, e.g. in an if statement, panics when the lock wasn't acquired.",
+	"SA2045": "Calling WaitGroup.Done more often than Add incremented the counter makes it negative, which panics.",
+	"SA2046": "Acquiring two locks nested in one another in different orders in different places can deadlock when those places run concurrently. The order used most often is assumed to be the intended one.",
+	"SA2047": "A variable written by a goroutine, or by the function starting it while the goroutine runs, and accessed by the other without holding a lock is a data race. Unlike SA2006, goroutines running named functions and variables reached through pointers and globals are checked too.",
+	"SA2048": "Sending on an unbuffered channel while holding a lock deadlocks if every goroutine receiving from the channel has to acquire the same lock first. Only run with -experimental.",
+	"SA2049": "Calling RLock on an RWMutex whose write lock the goroutine holds always deadlocks. Calling it while holding the read lock already deadlocks if another goroutine calls Lock in between, since pending writers block new readers; that case is reported as a warning.",
+	"SA2050": "A goroutine started after the channel it receives from was closed never gets any data: every receive yields the zero value immediately and ranging over the channel ends at once. Channels of struct{}, which only signal, are left alone.",
+	"SA2051": "Deferred calls run when the function returns, not at the end of the loop iteration. Deferring Close, Unlock or the like in a loop keeps every iteration's resource until the loop and the rest of the function are done; move the loop body into a function. -defer-in-loop-closers-only limits the check to such calls.",
+	"SA2052": "A WaitGroup can only be reused for a new round of goroutines once the previous Wait has returned. Calling Add in a loop while a goroutine started in the previous iteration may still be in Wait is a race, and may make Wait panic.",
+	"SA2053": "Deferred functions run in reverse order once the function returns. A deferred function acquiring a lock that is still held at that point, because it isn't released or because its unlock was deferred earlier and so runs later, deadlocks.",
+	"SA2054": "A sync.Mutex whose critical sections mostly only read the data it guards serializes readers that could run concurrently under the read lock of an RWMutex. Only run if listed in enabledChecks; it is a suggestion, and RWMutex costs more per operation.",
+	"SA2055": "On 386, ARM and other 32-bit platforms, the 64-bit functions of sync/atomic panic unless their operand is 64-bit aligned, which only the first word of a variable or allocated struct is guaranteed to be. A field preceded by fields whose sizes don't add up to a multiple of 8 isn't. Move it to the start of the struct, or use atomic.Int64 and atomic.Uint64, which are always aligned.",
+	"SA2056": "A field that is accessed with a lock held in the exported methods of its type is also accessed without holding the lock.",
+	"SA2057": "A channel used as a semaphore and a mutex acquired nested in one another in both orders can deadlock.",
+	"SA2058": "An error returned by a function called in a goroutine that is neither checked nor passed on is lost, since the goroutine has no caller to return it to.",
+	"SA2059": "The goroutines started after incrementing a WaitGroup's counter don't decrement it by the same amount, so either Wait never returns or the counter goes negative.",
+	"SA2060": "Receiving from a closed channel returns immediately, so a loop receiving from a channel that gets closed, and that never exits, spins forever.",
+	"SA2061": "A lock that is only ever acquired by code running on the main goroutine doesn't synchronize anything.",
+	"SA2062": "A deferred cancel cancels the context when the function returns, while a goroutine that was started with the context and isn't waited for may still be using it.",
+	"SA2063": "A field is accessed while holding a lock other than the one held for most of its other accesses, so the accesses don't exclude each other.",
+}
+
+// Checks describes the checks that are run, see Funcs, sorted by code.
+func (c *Checker) Checks() []lint.CheckInfo {
+	var checks []lint.CheckInfo
+	for code := range c.Funcs() {
+		sev, ok := DefaultSeverity[code]
+		if !ok {
+			sev = lint.SeverityWarning
+		}
+		checks = append(checks, lint.CheckInfo{
+			Code:            code,
+			Title:           docs[code],
+			Description:     descriptions[code],
+			DefaultSeverity: sev.String(),
+		})
+	}
+	sort.Slice(checks, func(i, j int) bool { return checks[i].Code < checks[j].Code })
+	return checks
+}
@@ -0,0 +1,158 @@
+package staticcheck
+
+import (
+	"go/ast"
+
+	"github.com/Tengfei1010/GCBDetector/ssa"
+)
+
+// leakPath searches for a path from start to an exit of its function,
+// or back to start itself, that doesn't pass through an instruction
+// for which done returns true. It returns the instruction ending such
+// a path, i.e. a *ssa.Return or start if the path is a loop, or nil
+// if every path releases the resource.
+//
+// Deferred calls are seen like any other instruction: done should
+// accept a *ssa.Defer of the cleanup, which then covers every exit
+// reached after it.
+func leakPath(start ssa.Instruction, done func(ssa.Instruction) bool) ssa.Instruction {
+	b := start.Block()
+	for i, ins := range b.Instrs {
+		if ins == start {
+			return searchLeak(b, b.Instrs[i+1:], start, done)
+		}
+	}
+	return nil
+}
+
+// exitWithout returns a *ssa.Return of fn that can be reached from its
+// entry without passing through an instruction for which done returns
+// true, or nil if there is none.
+func exitWithout(fn *ssa.Function, done func(ssa.Instruction) bool) ssa.Instruction {
+	if len(fn.Blocks) == 0 {
+		return nil
+	}
+	return searchLeak(fn.Blocks[0], fn.Blocks[0].Instrs, nil, done)
+}
+
+// searchLeak implements leakPath and exitWithout, starting the search
+// at instrs, the tail of b.
+func searchLeak(b *ssa.BasicBlock, instrs []ssa.Instruction, start ssa.Instruction, done func(ssa.Instruction) bool) ssa.Instruction {
+	seen := map[*ssa.BasicBlock]bool{}
+	var search func(b *ssa.BasicBlock, instrs []ssa.Instruction) ssa.Instruction
+	search = func(b *ssa.BasicBlock, instrs []ssa.Instruction) ssa.Instruction {
+		for _, ins := range instrs {
+			if ins == start {
+				return start
+			}
+			if done(ins) {
+				return nil
+			}
+			if _, ok := ins.(*ssa.Return); ok {
+				return ins
+			}
+		}
+		for _, succ := range b.Succs {
+			if seen[succ] {
+				continue
+			}
+			seen[succ] = true
+			if ins := search(succ, succ.Instrs); ins != nil {
+				return ins
+			}
+		}
+		return nil
+	}
+	return search(b, instrs)
+}
+
+// pathAvoiding reports whether to can be executed after from, possibly
+// in a later loop iteration, on a path that doesn't pass through an
+// instruction for which avoid returns true.
+func pathAvoiding(from, to ssa.Instruction, avoid func(ssa.Instruction) bool) bool {
+	b := from.Block()
+	for i, ins := range b.Instrs {
+		if ins == from {
+			for _, ins := range b.Instrs[i+1:] {
+				if ins == to {
+					return true
+				}
+				if avoid(ins) {
+					return false
+				}
+			}
+			break
+		}
+	}
+	seen := map[*ssa.BasicBlock]bool{}
+	var search func(b *ssa.BasicBlock) bool
+	search = func(b *ssa.BasicBlock) bool {
+		for _, succ := range b.Succs {
+			if seen[succ] {
+				continue
+			}
+			seen[succ] = true
+			found, blocked := false, false
+			for _, ins := range succ.Instrs {
+				if ins == to {
+					found = true
+					break
+				}
+				if avoid(ins) {
+					blocked = true
+					break
+				}
+			}
+			if found {
+				return true
+			}
+			if !blocked && search(succ) {
+				return true
+			}
+		}
+		return false
+	}
+	return search(b)
+}
+
+// varName returns the name of the source variable v is assigned to,
+// or the empty string if there is none.
+func varName(v ssa.Value) string {
+	refs := v.Referrers()
+	if refs == nil {
+		return ""
+	}
+	for _, ref := range *refs {
+		dr, ok := ref.(*ssa.DebugRef)
+		if !ok {
+			continue
+		}
+		if id, ok := dr.Expr.(*ast.Ident); ok && !dr.IsAddr {
+			return id.Name
+		}
+	}
+	return ""
+}
+
+// escapes reports whether v may be used beyond the calls made on it
+// in its function: stored, returned, captured by a closure, converted
+// to an interface or passed to a call for which allowed returns false.
+// Callers treat escaping resources as released elsewhere.
+func escapes(v ssa.Value, allowed func(common *ssa.CallCommon) bool) bool {
+	refs := v.Referrers()
+	if refs == nil {
+		return false
+	}
+	for _, ref := range *refs {
+		switch ref := ref.(type) {
+		case *ssa.DebugRef, *ssa.BlankStore, *ssa.FieldAddr:
+		case ssa.CallInstruction:
+			if !allowed(ref.Common()) {
+				return true
+			}
+		default:
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,130 @@
+package staticcheck
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"time"
+
+	"github.com/Tengfei1010/GCBDetector/lint"
+)
+
+// ConfigFile is the name of the configuration file looked up in the
+// root of the checked module.
+const ConfigFile = ".gcbdetector.json"
+
+// Config holds the settings of a Checker that can be kept in a
+// configuration file, so that they don't have to be repeated on every
+// invocation. Fields that are missing from the file leave the
+// corresponding settings of the Checker alone.
+type Config struct {
+	CheckGenerated   *bool    `json:"checkGenerated"`
+	GeneratedMarkers []string `json:"generatedMarkers"`
+	GeneratedFiles   []string `json:"generatedFiles"`
+	LockTypes        []string `json:"lockTypes"`
+	UnlockHelpers    []string `json:"unlockHelpers"`
+	// Severity maps check codes to "info", "warning" or "error".
+	Severity       map[string]string `json:"severity"`
+	DisabledChecks []string          `json:"disabledChecks"`
+	EnabledChecks  []string          `json:"enabledChecks"`
+	Experimental   *bool             `json:"experimental"`
+	IncludeTests   *bool             `json:"includeTests"`
+	// DeferInLoopClosersOnly restricts SA2051, see the Checker field.
+	DeferInLoopClosersOnly *bool `json:"deferInLoopClosersOnly"`
+	// PerFuncTimeout is a duration, such as "30s".
+	PerFuncTimeout string `json:"perFuncTimeout"`
+}
+
+// LoadConfig reads the ConfigFile in the root of the module dir is
+// in, see lint.ModuleRoot. It returns nil and no error if there is no
+// such file.
+func LoadConfig(dir string) (*Config, error) {
+	name := filepath.Join(lint.ModuleRoot(dir), ConfigFile)
+	f, err := os.Open(name)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	cfg := &Config{}
+	dec := json.NewDecoder(f)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(cfg); err != nil {
+		return nil, fmt.Errorf("%s: %v", name, err)
+	}
+	return cfg, nil
+}
+
+// Apply applies cfg to c. Generated markers and files, lock types and
+// unlock helpers are added to those already configured. It has to be
+// called before c is used.
+func (cfg *Config) Apply(c *Checker) error {
+	for code, s := range cfg.Severity {
+		if _, ok := docs[code]; !ok {
+			return fmt.Errorf("severity of unknown check %q", code)
+		}
+		sev, err := lint.ParseSeverity(s)
+		if err != nil {
+			return fmt.Errorf("severity of %s: %v", code, err)
+		}
+		if c.Severity == nil {
+			c.Severity = map[string]lint.Severity{}
+		}
+		c.Severity[code] = sev
+	}
+	for _, code := range cfg.DisabledChecks {
+		if _, ok := docs[code]; !ok {
+			return fmt.Errorf("unknown check %q can't be disabled", code)
+		}
+	}
+	for _, code := range cfg.EnabledChecks {
+		if !optInChecks[code] {
+			return fmt.Errorf("check %q isn't off by default and can't be enabled", code)
+		}
+	}
+	for _, s := range cfg.GeneratedMarkers {
+		if _, err := regexp.Compile(s); err != nil {
+			return fmt.Errorf("generated marker: %v", err)
+		}
+	}
+	for _, pattern := range cfg.GeneratedFiles {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return fmt.Errorf("generated files %q: %v", pattern, err)
+		}
+	}
+	var timeout time.Duration
+	if cfg.PerFuncTimeout != "" {
+		d, err := time.ParseDuration(cfg.PerFuncTimeout)
+		if err != nil {
+			return fmt.Errorf("per-function timeout: %v", err)
+		}
+		timeout = d
+	}
+	if cfg.CheckGenerated != nil {
+		c.CheckGenerated = *cfg.CheckGenerated
+	}
+	if cfg.Experimental != nil {
+		c.Experimental = *cfg.Experimental
+	}
+	if cfg.IncludeTests != nil {
+		c.IncludeTests = *cfg.IncludeTests
+	}
+	if cfg.DeferInLoopClosersOnly != nil {
+		c.DeferInLoopClosersOnly = *cfg.DeferInLoopClosersOnly
+	}
+	if cfg.PerFuncTimeout != "" {
+		c.PerFuncTimeout = timeout
+	}
+	c.GeneratedMarkers = append(c.GeneratedMarkers, cfg.GeneratedMarkers...)
+	c.GeneratedFiles = append(c.GeneratedFiles, cfg.GeneratedFiles...)
+	c.LockTypes = append(c.LockTypes, cfg.LockTypes...)
+	c.UnlockHelpers = append(c.UnlockHelpers, cfg.UnlockHelpers...)
+	c.DisabledChecks = append(c.DisabledChecks, cfg.DisabledChecks...)
+	c.EnabledChecks = append(c.EnabledChecks, cfg.EnabledChecks...)
+	return nil
+}
@@ -0,0 +1,95 @@
+package staticcheck
+
+import (
+	"go/token"
+	"go/types"
+
+	"github.com/Tengfei1010/GCBDetector/ssa"
+)
+
+// funcValueIndex records the values stored into function-typed struct
+// fields and package-level variables, so that indirect calls through
+// them, such as s.unlockFn(), can be resolved to the closures they
+// invoke.
+type funcValueIndex struct {
+	fields  map[string][]ssa.Value
+	globals map[*ssa.Global][]ssa.Value
+}
+
+func newFuncValueIndex(fns []*ssa.Function) *funcValueIndex {
+	idx := &funcValueIndex{
+		fields:  map[string][]ssa.Value{},
+		globals: map[*ssa.Global][]ssa.Value{},
+	}
+	for _, fn := range fns {
+		for _, b := range fn.Blocks {
+			for _, ins := range b.Instrs {
+				store, ok := ins.(*ssa.Store)
+				if !ok {
+					continue
+				}
+				if _, ok := store.Val.Type().Underlying().(*types.Signature); !ok {
+					continue
+				}
+				switch addr := store.Addr.(type) {
+				case *ssa.FieldAddr:
+					k := fieldName(addr)
+					idx.fields[k] = append(idx.fields[k], store.Val)
+				case *ssa.Global:
+					idx.globals[addr] = append(idx.globals[addr], store.Val)
+				}
+			}
+		}
+	}
+	return idx
+}
+
+// targets returns the functions v may evaluate to. resolved is false
+// if some of the values v may hold can't be determined, for example
+// because they are passed in as arguments.
+func (idx *funcValueIndex) targets(v ssa.Value) (fns []*ssa.Function, resolved bool) {
+	resolved = true
+	seen := map[ssa.Value]bool{}
+	var visit func(v ssa.Value)
+	visit = func(v ssa.Value) {
+		if seen[v] {
+			return
+		}
+		seen[v] = true
+		switch v := v.(type) {
+		case *ssa.Function:
+			fns = append(fns, v)
+		case *ssa.MakeClosure:
+			fns = append(fns, v.Fn.(*ssa.Function))
+		case *ssa.Phi:
+			for _, e := range v.Edges {
+				visit(e)
+			}
+		case *ssa.Const:
+			// nil; calling it panics
+		case *ssa.UnOp:
+			if v.Op != token.MUL {
+				resolved = false
+				return
+			}
+			var stored []ssa.Value
+			switch addr := v.X.(type) {
+			case *ssa.FieldAddr:
+				stored = idx.fields[fieldName(addr)]
+			case *ssa.Global:
+				stored = idx.globals[addr]
+			}
+			if len(stored) == 0 {
+				resolved = false
+				return
+			}
+			for _, s := range stored {
+				visit(s)
+			}
+		default:
+			resolved = false
+		}
+	}
+	visit(v)
+	return fns, resolved
+}
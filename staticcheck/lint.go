@@ -9,14 +9,21 @@
 package staticcheck
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"go/ast"
 	"go/token"
 	"go/types"
+	"os"
+	"path"
+	"path/filepath"
 	"regexp"
+	"runtime"
 	"sort"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/Tengfei1010/GCBDetector/callgraph"
 	"github.com/Tengfei1010/GCBDetector/callgraph/bbcallgraph"
@@ -25,6 +32,7 @@ import (
 	. "github.com/Tengfei1010/GCBDetector/lint/lintdsl"
 	"github.com/Tengfei1010/GCBDetector/ssa"
 	"github.com/Tengfei1010/GCBDetector/staticcheck/util"
+	"golang.org/x/tools/go/ast/astutil"
 	"golang.org/x/tools/go/loader"
 )
 
@@ -35,20 +43,198 @@ func (rs runeSlice) Less(i int, j int) bool { return rs[i] < rs[j] }
 func (rs runeSlice) Swap(i int, j int)      { rs[i], rs[j] = rs[j], rs[i] }
 
 type Checker struct {
+	// CheckGenerated enables reporting problems in generated files.
 	CheckGenerated bool
+	// GeneratedMarkers are regular expressions identifying generated
+	// files by the comments before their package clause: a file is
+	// generated if a line of them, including the comment markers,
+	// matches one of the expressions, e.g.
+	// `^// Code generated .* DO NOT EDIT\.$`. If there are none, files
+	// starting with a comment containing "Code generated by" or "DO
+	// NOT EDIT" are.
+	GeneratedMarkers []string
+	// GeneratedFiles are patterns, in the syntax of path.Match, of
+	// the base names of files that are generated whatever their
+	// content, e.g. "*.pb.go".
+	GeneratedFiles []string
+	generated      map[string]bool
+	// Debug enables diagnostic output on standard error.
+	Debug bool
+	// Verbose logs the phases of Init and of the double lock
+	// analysis, and how long they take, to standard error.
+	Verbose bool
+	// GoVersion is the minor Go version of the checked module, e.g.
+	// 21 for Go 1.21. If it is zero, the version the program is
+	// checked against (see lint.Program.GoVersion) is used.
+	GoVersion      int
 	funcDescs      *functions.Descriptions
+	funcValues     *funcValueIndex
 	deprecatedObjs map[types.Object]string
+
+	// Severity maps check codes to the severity of the problems they
+	// report. NewChecker populates it with DefaultSeverity; checks
+	// missing from it report warnings.
+	Severity map[string]lint.Severity
+
+	// ReportPrimitiveUsage, if set, is called by SA2008 with the
+	// synchronization primitives used by the checked program. Checks
+	// may run concurrently; so may calls to it for different jobs.
+	ReportPrimitiveUsage func(PrimitiveStats)
+
+	// LockTypes lists methods, besides those of sync.Mutex,
+	// sync.RWMutex and sync.Locker, that acquire or release a lock,
+	// by their fully qualified name, e.g.
+	// "(*example.com/spin.SpinLock).Lock". Methods whose name
+	// contains "Unlock" or "Release" release the lock, others acquire
+	// it; those whose name starts with "RLock" or "RUnlock" operate on
+	// a read lock.
+	LockTypes []string
+	// NoLockHeuristic disables recognizing calls of other methods
+	// whose name contains "lock", such as Lock and RUnlock, as lock
+	// operations.
+	NoLockHeuristic bool
+	// UnlockHelpers lists functions, by their fully qualified name,
+	// e.g. "example.com/store.unlockAndReturn", that release the lock
+	// passed as their first argument.
+	UnlockHelpers []string
+	// DisabledChecks lists the codes of checks that aren't run.
+	DisabledChecks []string
+	// EnabledChecks lists the codes of checks that are off by
+	// default, see optInChecks, that are run.
+	EnabledChecks []string
+	// PerFuncTimeout, if positive, limits the time SA2005 and SA2049
+	// spend on the lock acquisitions of a single function. Functions
+	// exceeding it, typically huge generated ones, are skipped and
+	// reported as such, so that they don't stall the analysis of the
+	// rest of the program.
+	PerFuncTimeout time.Duration
+	// DeferInLoopClosersOnly restricts SA2051 to defers of calls
+	// releasing a resource, such as Close, Unlock and Release,
+	// leaving deferred recovers, logging and the like alone.
+	DeferInLoopClosersOnly bool
+	// IncludeTests enables checking the functions in test files.
+	// SA2002, which is about tests, checks them regardless.
+	IncludeTests bool
+	// Experimental enables the checks in experimentalChecks, which
+	// correlate operations across goroutines and are more costly,
+	// and less proven, than the others.
+	Experimental  bool
+	lockMethods   map[string]lockMethod
+	unlockHelpers map[string]bool
+
+	lockStatesMu sync.Mutex
+	lockStates   map[*ssa.Function]*lockSets
+
+	bbGraphsMu sync.Mutex
+	bbGraphs   map[*ssa.Function]*bbcallgraph.BBGraph
+
+	doubleLocksMu sync.Mutex
+	doubleLockRes *doubleLockResult
+
+	// prog is the program Init last prepared the checker for.
+	prog *lint.Program
 }
 
 func NewChecker() *Checker {
-	return &Checker{}
+	sev := make(map[string]lint.Severity, len(DefaultSeverity))
+	for k, v := range DefaultSeverity {
+		sev[k] = v
+	}
+	return &Checker{Severity: sev}
+}
+
+// Analyze runs all checks with their default settings on prog, as
+// created by lint.NewProgram, and returns the problems found, sorted
+// by position. Nothing is printed.
+func Analyze(prog *lint.Program) ([]lint.Problem, error) {
+	if prog == nil || prog.SSA == nil || prog.Prog == nil {
+		return nil, errors.New("staticcheck: program wasn't created by lint.NewProgram")
+	}
+	l := &lint.Linter{
+		Checker:   NewChecker(),
+		GoVersion: prog.GoVersion,
+	}
+	return l.LintProgram(prog), nil
+}
+
+// DefaultSeverity holds the default severities of the checks. Checks
+// that find certain deadlocks and panics report errors; heuristic
+// checks warnings, and checks whose findings are not bugs by
+// themselves informational problems.
+var DefaultSeverity = map[string]lint.Severity{
+	"SA2000": lint.SeverityError,
+	"SA2001": lint.SeverityWarning,
+	"SA2002": lint.SeverityError,
+	"SA2003": lint.SeverityError,
+	"SA2004": lint.SeverityWarning,
+	"SA2005": lint.SeverityError,
+	"SA2006": lint.SeverityWarning,
+	"SA2008": lint.SeverityInfo,
+	"SA2009": lint.SeverityWarning,
+	"SA2010": lint.SeverityError,
+	"SA2011": lint.SeverityError,
+	"SA2012": lint.SeverityError,
+	"SA2013": lint.SeverityWarning,
+	"SA2014": lint.SeverityError,
+	"SA2015": lint.SeverityError,
+	"SA2016": lint.SeverityError,
+	"SA2017": lint.SeverityWarning,
+	"SA2018": lint.SeverityWarning,
+	"SA2019": lint.SeverityWarning,
+	"SA2020": lint.SeverityWarning,
+	"SA2021": lint.SeverityError,
+	"SA2022": lint.SeverityError,
+	"SA2023": lint.SeverityWarning,
+	"SA2024": lint.SeverityError,
+	"SA2025": lint.SeverityWarning,
+	"SA2026": lint.SeverityError,
+	"SA2027": lint.SeverityError,
+	"SA2028": lint.SeverityError,
+	"SA2029": lint.SeverityError,
+	"SA2030": lint.SeverityWarning,
+	"SA2031": lint.SeverityError,
+	"SA2032": lint.SeverityError,
+	"SA2033": lint.SeverityInfo,
+	"SA2034": lint.SeverityError,
+	"SA2035": lint.SeverityWarning,
+	"SA2036": lint.SeverityInfo,
+	"SA2037": lint.SeverityError,
+	"SA2038": lint.SeverityWarning,
+	"SA2039": lint.SeverityWarning,
+	"SA2040": lint.SeverityInfo,
+	"SA2041": lint.SeverityWarning,
+	"SA2042": lint.SeverityError,
+	"SA2043": lint.SeverityInfo,
+	"SA2044": lint.SeverityError,
+	"SA2045": lint.SeverityError,
+	"SA2046": lint.SeverityWarning,
+	"SA2047": lint.SeverityWarning,
+	"SA2048": lint.SeverityError,
+	"SA2049": lint.SeverityError,
+	"SA2050": lint.SeverityWarning,
+	"SA2051": lint.SeverityWarning,
+	"SA2052": lint.SeverityError,
+	"SA2053": lint.SeverityError,
+	"SA2054": lint.SeverityInfo,
+	"SA2055": lint.SeverityWarning,
+	"SA2056": lint.SeverityWarning,
+	"SA2057": lint.SeverityError,
+	"SA2058": lint.SeverityWarning,
+	"SA2059": lint.SeverityError,
+	"SA2060": lint.SeverityError,
+	"SA2061": lint.SeverityInfo,
+	"SA2062": lint.SeverityWarning,
+	"SA2063": lint.SeverityWarning,
 }
 
+// Severities implements lint.SeverityChecker.
+func (c *Checker) Severities() map[string]lint.Severity { return c.Severity }
+
 func (*Checker) Name() string   { return "staticcheck" }
 func (*Checker) Prefix() string { return "SA" }
 
 func (c *Checker) Funcs() map[string]lint.Func {
-	return map[string]lint.Func{
+	funcs := map[string]lint.Func{
 		"SA2000": c.CheckWaitgroupAdd,
 		"SA2001": c.CheckEmptyCriticalSection,
 		"SA2002": c.CheckConcurrentTesting,
@@ -56,22 +242,211 @@ func (c *Checker) Funcs() map[string]lint.Func {
 		"SA2004": c.CheckUnlockAfterLock,
 		"SA2005": c.CheckDoubleLock,
 		"SA2006": c.CheckAnonRace,
-		//"SA2007": c.CheckWaitgroupBlocking,
 		"SA2008": c.CheckPrimitiveUsage,
+		"SA2009": c.CheckWaitgroupWithoutWait,
+		"SA2010": c.CheckMissingUnlock,
+		"SA2011": c.CheckLockOrder,
+		"SA2012": c.CheckUnlockWithoutLock,
+		"SA2013": c.CheckCondWaitLoop,
+		"SA2014": c.CheckCondWaitUnlocked,
+		"SA2015": c.CheckDoubleClose,
+		"SA2016": c.CheckSendOnClosed,
+		"SA2017": c.CheckLoopVarCapture,
+		"SA2018": c.CheckTimeAfterInLoop,
+		"SA2019": c.CheckLostCancel,
+		"SA2020": c.CheckSelectDefaultSpin,
+		"SA2021": c.CheckRLockUpgrade,
+		"SA2022": c.CheckDeferUnlockInLoop,
+		"SA2023": c.CheckUnreceivedSend,
+		"SA2024": c.CheckCopyLock,
+		"SA2025": c.CheckLoadOrStoreLoaded,
+		"SA2026": c.CheckMixedAtomic,
+		"SA2027": c.CheckGoValueReceiver,
+		"SA2028": c.CheckWaitgroupBlocking,
+		"SA2029": c.CheckRecursiveLock,
+		"SA2030": c.CheckAbandonedSend,
+		"SA2031": c.CheckConcurrentMap,
+		"SA2032": c.CheckDeferWrongUnlock,
+		"SA2033": c.CheckGoroutineIgnoresContext,
+		"SA2034": c.CheckLockHeldAcrossJoin,
+		"SA2035": c.CheckTimerStop,
+		"SA2036": c.CheckGoInInit,
+		"SA2037": c.CheckNilSelect,
+		"SA2038": c.CheckLockHeldOverChannel,
+		"SA2039": c.CheckSleepWhileLocked,
+		"SA2040": c.CheckHandlerWaitsWithoutTimeout,
+		"SA2041": c.CheckErrgroupWithoutWait,
+		"SA2042": c.CheckSemaphoreRelease,
+		"SA2043": c.CheckNonTerminatingGoroutine,
+		"SA2044": c.CheckConditionalLock,
+		"SA2045": c.CheckNegativeWaitgroup,
+		"SA2046": c.CheckInconsistentLockOrder,
+		"SA2047": c.CheckGoroutineRace,
+		"SA2048": c.CheckSendToLockedReceiver,
+		"SA2049": c.CheckRecursiveRLock,
+		"SA2050": c.CheckReceiveFromClosed,
+		"SA2051": c.CheckDeferInLoop,
+		"SA2052": c.CheckWaitgroupReuse,
+		"SA2053": c.CheckDeferredRelock,
+		"SA2054": c.CheckReadMostlyMutex,
+		"SA2055": c.CheckMisalignedAtomic,
+		"SA2056": c.CheckUnlockedFieldAccess,
+		"SA2057": c.CheckSemaphoreAndMutex,
+		"SA2058": c.CheckGoroutineDroppedError,
+		"SA2059": c.CheckWaitgroupAccounting,
+		"SA2060": c.CheckSpinOnClosedChannel,
+		"SA2061": c.CheckUnnecessaryLock,
+		"SA2062": c.CheckEarlyCancel,
+		"SA2063": c.CheckWrongLockHeld,
+	}
+	for _, code := range c.DisabledChecks {
+		delete(funcs, code)
+	}
+	if !c.Experimental {
+		for code := range experimentalChecks {
+			delete(funcs, code)
+		}
 	}
+	enabled := map[string]bool{}
+	for _, code := range c.EnabledChecks {
+		enabled[code] = true
+	}
+	for code := range optInChecks {
+		if !enabled[code] {
+			delete(funcs, code)
+		}
+	}
+	return funcs
+}
+
+// experimentalChecks are the checks only run if Experimental is set.
+var experimentalChecks = map[string]bool{
+	"SA2048": true,
+}
+
+// optInChecks are the checks only run if they are listed in
+// EnabledChecks. They make suggestions rather than find bugs.
+var optInChecks = map[string]bool{
+	"SA2054": true,
+}
+
+// docs holds short descriptions of the checks, used as SARIF rule
+// descriptions.
+var docs = map[string]string{
+	"SA2000": "WaitGroup.Add called inside the goroutine it accounts for",
+	"SA2001": "Empty critical section",
+	"SA2002": "Called testing.T.FailNow or SkipNow in a goroutine",
+	"SA2003": "Deferred Lock right after locking",
+	"SA2004": "Unlock right after locking",
+	"SA2005": "Lock acquired twice",
+	"SA2006": "Variable shared with a goroutine without synchronization",
+	"SA2008": "Concurrency primitive usage statistics",
+	"SA2009": "WaitGroup used with Add and Done but never waited on",
+	"SA2010": "Lock not released on every path",
+	"SA2011": "Inconsistent lock order",
+	"SA2012": "Unlock of a lock that isn't held",
+	"SA2013": "Cond.Wait not called in a loop",
+	"SA2014": "Cond.Wait called without holding the Cond's lock",
+	"SA2015": "Channel closed twice",
+	"SA2016": "Send on a channel that may be closed",
+	"SA2017": "Goroutine captures a loop variable",
+	"SA2018": "time.After called in a loop",
+	"SA2019": "Context cancel function not called",
+	"SA2020": "Busy wait with select and default in a loop",
+	"SA2021": "Read lock upgraded to a write lock",
+	"SA2022": "Deferred unlock in a loop",
+	"SA2023": "Goroutine sends on a channel that is never received from",
+	"SA2024": "Lock copied by value",
+	"SA2025": "Loaded result of sync.Map.LoadOrStore ignored",
+	"SA2026": "Variable accessed both atomically and plainly",
+	"SA2027": "Goroutine started on a method whose value receiver contains a lock",
+	"SA2028": "WaitGroup.Wait called in the loop that starts the goroutines",
+	"SA2029": "Lock acquired again by a recursive call",
+	"SA2030": "Goroutine blocked sending on a channel its creator stopped receiving from",
+	"SA2031": "Map accessed concurrently without synchronization",
+	"SA2032": "Deferred unlock doesn't match the lock method",
+	"SA2033": "Goroutine blocks without observing context cancellation",
+	"SA2034": "Waiting for a goroutine while holding a lock it acquires",
+	"SA2035": "Timer or Ticker not stopped",
+	"SA2036": "Goroutine started in an init function",
+	"SA2037": "Select that blocks forever on nil channels",
+	"SA2038": "Channel operation while holding a lock",
+	"SA2039": "time.Sleep called while holding a lock",
+	"SA2040": "HTTP handler waits for a goroutine without a timeout",
+	"SA2041": "errgroup.Group used with Go but not waited on",
+	"SA2042": "Semaphore acquired without being released",
+	"SA2043": "Goroutine that never terminates",
+	"SA2044": "Lock acquired conditionally but released unconditionally",
+	"SA2045": "WaitGroup.Done called more often than Add",
+	"SA2046": "Locks acquired in inconsistent order",
+	"SA2047": "Variable shared between goroutines accessed without synchronization",
+	"SA2048": "Channel send while holding a lock its receiver needs",
+	"SA2049": "RWMutex read locked while already held",
+	"SA2050": "Goroutine receives from a channel closed before it started",
+	"SA2051": "Defer in a loop",
+	"SA2052": "WaitGroup reused before the previous Wait returned",
+	"SA2053": "Deferred function acquires a lock that is still held",
+	"SA2054": "Mutex mostly locked for reading",
+	"SA2055": "64-bit atomic operation on a field that isn't 64-bit aligned on 32-bit platforms",
+	"SA2056": "Guarded field accessed without its lock",
+	"SA2057": "Semaphore and mutex acquired in inconsistent order",
+	"SA2058": "Error dropped in a goroutine",
+	"SA2059": "WaitGroup counter can't reach zero",
+	"SA2060": "Loop spinning on a closed channel",
+	"SA2061": "Lock only used on the main goroutine",
+	"SA2062": "Context canceled before the goroutine using it finishes",
+	"SA2063": "Field accessed holding the wrong lock",
+}
+
+// Keep implements lint.FilteringChecker. Problems in generated files
+// are only reported if CheckGenerated is set.
+func (c *Checker) Keep(p lint.Problem) bool {
+	return c.CheckGenerated || !c.generated[p.Position.Filename]
 }
 
-func (c *Checker) filterGenerated(files []*ast.File) []*ast.File {
-	if c.CheckGenerated {
-		return files
+// findGenerated returns the names of the generated files of prog, see
+// GeneratedMarkers and GeneratedFiles. Invalid expressions and
+// patterns are skipped.
+func (c *Checker) findGenerated(prog *lint.Program) map[string]bool {
+	var markers []*regexp.Regexp
+	for _, s := range c.GeneratedMarkers {
+		if rx, err := regexp.Compile(s); err == nil {
+			markers = append(markers, rx)
+		}
+	}
+	isGenerated := func(f *ast.File, name string) bool {
+		for _, pattern := range c.GeneratedFiles {
+			if ok, _ := path.Match(pattern, filepath.Base(name)); ok {
+				return true
+			}
+		}
+		if len(markers) == 0 {
+			return IsGenerated(f)
+		}
+		for _, cg := range f.Comments {
+			if cg.Pos() > f.Package {
+				break
+			}
+			for _, comment := range cg.List {
+				for _, line := range strings.Split(comment.Text, "\n") {
+					for _, rx := range markers {
+						if rx.MatchString(line) {
+							return true
+						}
+					}
+				}
+			}
+		}
+		return false
 	}
-	var out []*ast.File
-	for _, f := range files {
-		if !IsGenerated(f) {
-			out = append(out, f)
+	generated := map[string]bool{}
+	for _, f := range prog.Files {
+		name := prog.DisplayPosition(f.Pos()).Filename
+		if isGenerated(f, name) {
+			generated[name] = true
 		}
 	}
-	return out
+	return generated
 }
 
 func (c *Checker) findDeprecated(prog *lint.Program) {
@@ -161,9 +536,31 @@ func (c *Checker) findDeprecated(prog *lint.Program) {
 }
 
 func (c *Checker) Init(prog *lint.Program) {
+	c.lockMethods = parseLockMethods(c.LockTypes)
+	c.unlockHelpers = make(map[string]bool, len(c.UnlockHelpers))
+	for _, name := range c.UnlockHelpers {
+		c.unlockHelpers[name] = true
+	}
+	c.generated = c.findGenerated(prog)
+	if prog == c.prog {
+		// already prepared
+		return
+	}
+	c.prog = prog
+	c.lockStatesMu.Lock()
+	c.lockStates = nil
+	c.lockStatesMu.Unlock()
+	c.bbGraphsMu.Lock()
+	c.bbGraphs = nil
+	c.bbGraphsMu.Unlock()
+	c.doubleLocksMu.Lock()
+	c.doubleLockRes = nil
+	c.doubleLocksMu.Unlock()
+
 	wg := &sync.WaitGroup{}
 	wg.Add(2)
 	go func() {
+		start := time.Now()
 		c.funcDescs = functions.NewDescriptions(prog.SSA)
 		for _, fn := range prog.AllFunctions {
 			if fn.Blocks != nil {
@@ -171,20 +568,41 @@ func (c *Checker) Init(prog *lint.Program) {
 				ssa.OptimizeBlocks(fn)
 			}
 		}
+		c.funcValues = newFuncValueIndex(prog.AllFunctions)
+		c.logf(start, "computing the descriptions of %d functions", len(prog.AllFunctions))
 		wg.Done()
 	}()
 
 	go func() {
+		start := time.Now()
 		c.deprecatedObjs = map[types.Object]string{}
 		c.findDeprecated(prog)
+		c.logf(start, "finding deprecated objects")
 		wg.Done()
 	}()
 
 	wg.Wait()
 }
 
+// logf logs a phase that started at start, if Verbose is set.
+func (c *Checker) logf(start time.Time, format string, args ...interface{}) {
+	if c.Verbose {
+		fmt.Fprintf(os.Stderr, "%s: %v\n", fmt.Sprintf(format, args...), time.Since(start).Round(time.Millisecond))
+	}
+}
+
+// Reset drops what the checker computed for the program it ran on
+// last, so that running the checks on that program again starts over.
+// This is needed after changing LockTypes or UnlockHelpers. Otherwise,
+// running the checks again on the same program reuses the optimized
+// SSA form, the call graph and the lock sets; lintutil.Cache returns
+// the same program as long as its sources don't change.
+func (c *Checker) Reset() {
+	c.prog = nil
+}
+
 func (c *Checker) isInLoop(b *ssa.BasicBlock) bool {
-	sets := c.funcDescs.Get(b.Parent()).Loops
+	sets := c.funcDescs.Loops(b.Parent())
 	for _, set := range sets {
 		if set[b] {
 			return true
@@ -193,6 +611,20 @@ func (c *Checker) isInLoop(b *ssa.BasicBlock) bool {
 	return false
 }
 
+// loopBlocks returns the blocks of all loops b is part of.
+func (c *Checker) loopBlocks(b *ssa.BasicBlock) functions.Loop {
+	blocks := functions.Loop{}
+	for _, set := range c.funcDescs.Loops(b.Parent()) {
+		if !set[b] {
+			continue
+		}
+		for other := range set {
+			blocks[other] = true
+		}
+	}
+	return blocks
+}
+
 func applyStdlibKnowledge(fn *ssa.Function) {
 	if len(fn.Blocks) == 0 {
 		return
@@ -383,7 +815,7 @@ func unwrapFunction(val ssa.Value) *ssa.Function {
 
 func shortCallName(call *ssa.CallCommon) string {
 	if call.IsInvoke() {
-		return ""
+		return call.Method.Name()
 	}
 	switch v := call.Value.(type) {
 	case *ssa.Function:
@@ -423,6 +855,26 @@ func loopedRegexp(name string) CallCheck {
 	}
 }
 
+// CheckTimeAfterInLoop flags time.After in loops. A call in a select
+// case that always leaves the loop is harmless, but that is hard to
+// prove and is reported as well.
+func (c *Checker) CheckTimeAfterInLoop(j *lint.Job) {
+	for _, ssafn := range c.checkedFuncs(j) {
+		for _, block := range ssafn.Blocks {
+			if !c.isInLoop(block) {
+				continue
+			}
+			for _, ins := range block.Instrs {
+				call, ok := ins.(*ssa.Call)
+				if !ok || !IsCallTo(call.Common(), "time.After") {
+					continue
+				}
+				j.Errorf(call, "calling time.After in a loop leaks a timer until it fires; consider time.NewTimer and Reset")
+			}
+		}
+	}
+}
+
 func buildTagsIdentical(s1, s2 []string) bool {
 	if len(s1) != len(s2) {
 		return false
@@ -441,67 +893,372 @@ func buildTagsIdentical(s1, s2 []string) bool {
 	return true
 }
 
-func isCallToLock(callCommon *ssa.CallCommon) bool {
-	if IsCallTo(callCommon, "(*sync.Mutex).Lock") ||
-		IsCallTo(callCommon, "(*sync.RWMutex).RLock") ||
-		IsCallTo(callCommon, "(*sync.RWMutex).Lock") {
-		return true
+// lockKind is the kind of lock acquired by a call.
+type lockKind int
+
+const (
+	notLock      lockKind = iota
+	mutexLock             // (*sync.Mutex).Lock
+	rwMutexLock           // (*sync.RWMutex).Lock
+	rwMutexRLock          // (*sync.RWMutex).RLock
+	otherLock             // Lock method of another type
+	otherRLock            // RLock method of another type
+)
+
+func (k lockKind) String() string {
+	switch k {
+	case mutexLock:
+		return "Mutex.Lock"
+	case rwMutexLock:
+		return "RWMutex.Lock"
+	case rwMutexRLock:
+		return "RWMutex.RLock"
+	case otherLock:
+		return "Lock"
+	case otherRLock:
+		return "RLock"
 	}
+	return "not a lock"
+}
 
-	// TODO: maybe has FN
-	callStr := strings.ToLower(callCommon.String())
-	if strings.Contains(callStr, ".lock(") ||
-		strings.Contains(callStr, ".rlock(") {
+// read reports whether k acquires a read lock.
+func (k lockKind) read() bool {
+	return k == rwMutexRLock || k == otherRLock
+}
 
-		// Here we ignore the function which has a parameter
-		if len(callCommon.Args) > 1 {
-			return false
+// lockMethod describes a method listed in Checker.LockTypes.
+type lockMethod struct {
+	kind    lockKind
+	release bool
+}
+
+// parseLockMethods classifies the methods named in names, see
+// Checker.LockTypes.
+func parseLockMethods(names []string) map[string]lockMethod {
+	methods := make(map[string]lockMethod, len(names))
+	for _, name := range names {
+		method := name[strings.LastIndex(name, ".")+1:]
+		m := lockMethod{kind: otherLock}
+		if strings.HasPrefix(method, "RLock") || strings.HasPrefix(method, "RUnlock") {
+			m.kind = otherRLock
 		}
+		lower := strings.ToLower(method)
+		m.release = strings.Contains(lower, "unlock") || strings.Contains(lower, "release")
+		methods[name] = m
+	}
+	return methods
+}
 
-		return true
+// configuredLock looks callCommon up in c.LockTypes. It returns the
+// kind of lock callCommon acquires or, if release is set, releases.
+// ok is false if the callee isn't listed.
+func (c *Checker) configuredLock(callCommon *ssa.CallCommon, release bool) (kind lockKind, ok bool) {
+	if callCommon.IsInvoke() {
+		if callCommon.Method.FullName() == "(sync.Locker).Lock" {
+			if release {
+				return notLock, true
+			}
+			return otherLock, true
+		}
+		if callCommon.Method.FullName() == "(sync.Locker).Unlock" {
+			if release {
+				return otherLock, true
+			}
+			return notLock, true
+		}
+		return notLock, false
 	}
-	return false
+	m, ok := c.lockMethods[CallName(callCommon)]
+	if !ok {
+		return notLock, false
+	}
+	if m.release != release {
+		return notLock, true
+	}
+	return m.kind, true
 }
 
-func isCallToUnlock(callCommon *ssa.CallCommon) bool {
-	if IsCallTo(callCommon, "(*sync.Mutex).Unlock") ||
-		IsCallTo(callCommon, "(*sync.RWMutex).RUnlock") ||
-		IsCallTo(callCommon, "(*sync.RWMutex).UnLock") {
-		return true
+// lockKindOf returns the kind of lock callCommon acquires, or notLock
+// if it isn't a call acquiring a lock.
+func (c *Checker) lockKindOf(callCommon *ssa.CallCommon) lockKind {
+	switch {
+	case IsCallTo(callCommon, "(*sync.Mutex).Lock"):
+		return mutexLock
+	case IsCallTo(callCommon, "(*sync.RWMutex).Lock"):
+		return rwMutexLock
+	case IsCallTo(callCommon, "(*sync.RWMutex).RLock"):
+		return rwMutexRLock
+	}
+	if kind, ok := c.configuredLock(callCommon, false); ok {
+		return kind
+	}
+	if c.NoLockHeuristic {
+		return notLock
 	}
 
 	// TODO: maybe has FN
 	callStr := strings.ToLower(callCommon.String())
-	if strings.Contains(callStr, ".unlock") ||
-		strings.Contains(callStr, ".runlock") {
-		return true
+	// Here we ignore the function which has a parameter
+	if len(callCommon.Args) > 1 {
+		return notLock
+	}
+	if strings.Contains(callStr, ".rlock(") {
+		return otherRLock
 	}
+	if strings.Contains(callStr, ".lock(") {
+		return otherLock
+	}
+	return notLock
+}
 
-	return false
+func (c *Checker) isCallToLock(callCommon *ssa.CallCommon) bool {
+	return c.lockKindOf(callCommon) != notLock
+}
+
+// unlockKindOf returns the kind of lock callCommon releases, as the
+// lockKind of the matching acquisition, or notLock if it isn't a call
+// releasing a lock.
+func (c *Checker) unlockKindOf(callCommon *ssa.CallCommon) lockKind {
+	switch {
+	case IsCallTo(callCommon, "(*sync.Mutex).Unlock"):
+		return mutexLock
+	case IsCallTo(callCommon, "(*sync.RWMutex).Unlock"):
+		return rwMutexLock
+	case IsCallTo(callCommon, "(*sync.RWMutex).RUnlock"):
+		return rwMutexRLock
+	}
+	if kind, ok := c.configuredLock(callCommon, true); ok {
+		return kind
+	}
+	if c.unlockHelpers[CallName(callCommon)] && len(callCommon.Args) > 0 {
+		if strings.Contains(strings.ToLower(callCommon.StaticCallee().Name()), "runlock") {
+			return otherRLock
+		}
+		return otherLock
+	}
+	if c.NoLockHeuristic {
+		return notLock
+	}
+	callStr := strings.ToLower(callCommon.String())
+	if strings.Contains(callStr, ".runlock") {
+		return otherRLock
+	}
+	if strings.Contains(callStr, ".unlock") {
+		return otherLock
+	}
+	return notLock
+}
+
+func (c *Checker) isCallToUnlock(callCommon *ssa.CallCommon) bool {
+	return c.unlockKindOf(callCommon) != notLock
+}
+
+// getLockPrefix returns a key identifying the lock operated on by
+// lockCall. The key is derived from the lock's resolved identity, see
+// lockValueKey, so the same lock reached through different temporaries
+// or functions gets the same key.
+func getLockPrefix(lockCall ssa.CallInstruction) string {
+	recv := lockReceiver(lockCall.Common())
+	if recv == nil {
+		return lockCall.Common().String()
+	}
+	return lockValueKey(recv)
+}
+
+// lockReceiver returns the lock a lock or unlock call operates on: the
+// interface value for invoke calls, such as l.Lock() on a
+// sync.Locker, and the first argument, the receiver of methods,
+// otherwise. It returns nil for calls without arguments.
+func lockReceiver(common *ssa.CallCommon) ssa.Value {
+	if common.IsInvoke() {
+		return common.Value
+	}
+	if len(common.Args) == 0 {
+		return nil
+	}
+	return common.Args[0]
+}
+
+// lockName describes the lock common operates on for messages, e.g.
+// "field mu".
+func lockName(common *ssa.CallCommon) string {
+	recv := lockReceiver(common)
+	if common.IsInvoke() {
+		// the sync.Locker is loaded from where it is stored
+		load, ok := recv.(*ssa.UnOp)
+		if !ok || load.Op != token.MUL {
+			return "the lock"
+		}
+		recv = load.X
+	}
+	if recv == nil {
+		return "the lock"
+	}
+	return addressName(recv)
+}
+
+// lockValueKey returns a key identifying the lock v, a pointer to a
+// lock or a sync.Locker, refers to. Globals are identified by the
+// variable, locals by their allocation site and locks in fields of
+// globals and locals by the variable and the field. Other fields are
+// identified by the struct type and the field, ignoring which
+// instance of the struct they belong to. Values that can't be
+// resolved further are identified by the SSA value itself.
+func lockValueKey(v ssa.Value) string {
+	key, _ := lockIdentity(v)
+	return key
+}
+
+// storedOnce returns the value stored in the local variable addr if
+// it is assigned exactly once.
+func storedOnce(addr *ssa.Alloc) (ssa.Value, bool) {
+	var stored []ssa.Value
+	for _, ref := range *addr.Referrers() {
+		if st, ok := ref.(*ssa.Store); ok && st.Addr == addr {
+			stored = append(stored, st.Val)
+		}
+	}
+	if len(stored) != 1 {
+		return nil, false
+	}
+	return stored[0], true
+}
 
+// lockIdentity resolves v, a pointer to a lock or a sync.Locker, as
+// described for lockValueKey. key is the lock's lockValueKey. field is
+// set if the lock is held in a struct field, directly or through a
+// pointer or sync.Locker stored in it, and names the field qualified by
+// its struct type, ignoring which instance of the struct it belongs to.
+func lockIdentity(v ssa.Value) (key, field string) {
+	return lockIdentitySeen(v, map[ssa.Value]bool{})
 }
 
-func getLockPrefix(lockCall *ssa.Call) string {
-	if len(lockCall.Common().Args) < 1 {
-		lockStr := lockCall.Common().String()
-		if strings.Contains(lockStr, "invoke") {
-			// invoke t65.Lock()return t65
-			start := strings.Index(lockStr, " ")
-			end := strings.Index(lockStr, ".")
-			if start != -1 && end != -1 {
-				return lockStr[start:end]
+func lockIdentitySeen(v ssa.Value, seen map[ssa.Value]bool) (key, field string) {
+	unique := func(v ssa.Value) string {
+		if v.Parent() == nil {
+			return v.String()
+		}
+		return v.Parent().String() + "." + v.Name()
+	}
+	if seen[v] {
+		// a cycle of phis
+		return unique(v), ""
+	}
+	seen[v] = true
+	defer delete(seen, v)
+
+	switch v := v.(type) {
+	case *ssa.Global:
+		return v.String(), ""
+	case *ssa.Alloc:
+		return unique(v), ""
+	case *ssa.FieldAddr:
+		field := fieldName(v)
+		switch v.X.(type) {
+		case *ssa.Global, *ssa.Alloc:
+			name := Dereference(v.X.Type()).Underlying().(*types.Struct).Field(v.Field).Name()
+			key, _ := lockIdentitySeen(v.X, seen)
+			return key + "." + name, field
+		}
+		return field, field
+	case *ssa.UnOp:
+		if v.Op != token.MUL {
+			return unique(v), ""
+		}
+		// a pointer to a lock stored in a variable or field
+		addr := v.X
+		if fv, ok := addr.(*ssa.FreeVar); ok {
+			if b, ok := freeVarBinding(fv); ok {
+				addr = b
 			}
 		}
-		return lockCall.Common().String()
+		switch addr := addr.(type) {
+		case *ssa.Alloc:
+			if stored, ok := storedOnce(addr); ok {
+				return lockIdentitySeen(stored, seen)
+			}
+		case *ssa.Global, *ssa.FieldAddr:
+			key, field := lockIdentitySeen(addr, seen)
+			return "*" + key, field
+		}
+		return unique(v), ""
+	case *ssa.FreeVar:
+		if b, ok := freeVarBinding(v); ok {
+			return lockIdentitySeen(b, seen)
+		}
+		return unique(v), ""
+	case *ssa.MakeInterface:
+		return lockIdentitySeen(v.X, seen)
+	case *ssa.ChangeType:
+		return lockIdentitySeen(v.X, seen)
+	case *ssa.ChangeInterface:
+		return lockIdentitySeen(v.X, seen)
+	case *ssa.Phi:
+		for i, e := range v.Edges {
+			k, f := lockIdentitySeen(e, seen)
+			if i > 0 && (k != key || f != field) {
+				return unique(v), ""
+			}
+			key, field = k, f
+		}
+		return key, field
+	}
+	return unique(v), ""
+}
+
+// fieldLockKey identifies a lock held in a struct field by the struct
+// type and the field name, e.g. "pkg.T.mu", see lockIdentity. This
+// deliberately ignores which instance of T the lock belongs to. It
+// returns false for locks that aren't held in a field.
+func fieldLockKey(call ssa.CallInstruction) (string, bool) {
+	recv := lockReceiver(call.Common())
+	if recv == nil {
+		return "", false
+	}
+	_, field := lockIdentity(recv)
+	return field, field != ""
+}
+
+// fieldName returns the name of the field addressed by fa, qualified
+// by its struct type.
+func fieldName(fa *ssa.FieldAddr) string {
+	T := Dereference(fa.X.Type())
+	field := T.Underlying().(*types.Struct).Field(fa.Field)
+	return types.TypeString(T, nil) + "." + field.Name()
+}
+
+func isLockType(T types.Type) bool {
+	T = Dereference(T)
+	if tp, ok := T.(*types.TypeParam); ok {
+		// a type parameter constrained by sync.Locker, or by any
+		// interface providing Lock and Unlock
+		return hasMethod(tp, "Lock") && hasMethod(tp, "Unlock")
+	}
+	switch types.TypeString(T, nil) {
+	case "sync.Mutex", "sync.RWMutex", "sync.Locker":
+		return true
 	}
+	return false
+}
+
+func hasMethod(T types.Type, name string) bool {
+	obj, _, _ := types.LookupFieldOrMethod(T, true, nil, name)
+	_, ok := obj.(*types.Func)
+	return ok
+}
 
-	value := lockCall.Common().Args[0]
-	return value.String()
+// lockInstr is a call acquiring a lock.
+type lockInstr struct {
+	call *ssa.Call
+	kind lockKind
 }
 
-func collectLockInstrs(function *ssa.Function) map[string][]ssa.Instruction {
+// collectLockInstrs returns the calls acquiring a lock in function,
+// keyed by getLockPrefix. Read and write acquisitions of the same
+// RWMutex share a key and are told apart by their kind.
+func (c *Checker) collectLockInstrs(function *ssa.Function) map[string][]lockInstr {
 
-	result := make(map[string][]ssa.Instruction)
+	result := make(map[string][]lockInstr)
 
 	for _, bb := range function.Blocks {
 
@@ -512,10 +1269,12 @@ func collectLockInstrs(function *ssa.Function) map[string][]ssa.Instruction {
 				continue
 			}
 
-			if isCallToLock(call.Common()) {
-				fmt.Println(call.Common())
+			if kind := c.lockKindOf(call.Common()); kind != notLock {
+				if c.Debug {
+					fmt.Fprintln(os.Stderr, call.Common())
+				}
 				lockValue := getLockPrefix(call)
-				result[lockValue] = append(result[lockValue], instr)
+				result[lockValue] = append(result[lockValue], lockInstr{call, kind})
 			}
 		}
 	}
@@ -524,7 +1283,65 @@ func collectLockInstrs(function *ssa.Function) map[string][]ssa.Instruction {
 
 }
 
-func isLockToLockInSameBlock(fLock *ssa.Call, sLock *ssa.Call) bool {
+// doubleLockSearch holds the state of a search for a second
+// acquisition of lock that isn't preceded by a release.
+type doubleLockSearch struct {
+	c    *Checker
+	ctx  context.Context
+	lock *ssa.Call
+	key  string
+	// uncertain is set when an indirect call that might release the
+	// lock couldn't be resolved.
+	uncertain bool
+}
+
+// releases reports whether call releases the lock, either directly or
+// through a function value, e.g. s.unlockFn(), that resolves to
+// functions unlocking it.
+func (s *doubleLockSearch) releases(call *ssa.Call) bool {
+	common := call.Common()
+	if s.c.isCallToUnlock(common) {
+		return sameLock(call, s.lock)
+	}
+	if _, ok := common.Value.(*ssa.Builtin); ok {
+		return false
+	}
+	if common.IsInvoke() || common.StaticCallee() != nil || s.c.isCallToLock(common) {
+		return false
+	}
+	fns, resolved := s.c.funcValues.targets(common.Value)
+	for _, fn := range fns {
+		if s.c.unlocks(fn, s.lock) {
+			return true
+		}
+	}
+	if !resolved {
+		s.uncertain = true
+	}
+	return false
+}
+
+// sameLock reports whether the lock calls a and b operate on the same
+// lock, as identified by lockKey. Locks stored in struct fields are
+// compared by field, so that an unlock in a closure matches the lock in
+// the enclosing method.
+func sameLock(a, b ssa.CallInstruction) bool {
+	return lockKey(a) == lockKey(b)
+}
+
+// unlocks reports whether fn itself contains a call releasing lock.
+func (c *Checker) unlocks(fn *ssa.Function, lock *ssa.Call) bool {
+	for _, b := range fn.Blocks {
+		for _, ins := range b.Instrs {
+			if c.releasesSameLock(ins, lock) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (s *doubleLockSearch) isLockToLockInSameBlock(fLock *ssa.Call, sLock *ssa.Call) bool {
 
 	curBlock := fLock.Block()
 
@@ -544,7 +1361,9 @@ func isLockToLockInSameBlock(fLock *ssa.Call, sLock *ssa.Call) bool {
 			fInstrIndex = index
 		}
 
-		if isCallToUnlock(call.Common()) && getLockPrefix(call) == getLockPrefix(fLock) {
+		// only calls between the two locks matter
+		between := (fInstrIndex != -1) != (sInstrIndex != -1)
+		if between && s.releases(call) {
 			unlockIndex = index
 			if (fInstrIndex < unlockIndex && sInstrIndex == -1 && fInstrIndex != -1) ||
 				(sInstrIndex < unlockIndex && fInstrIndex == -1 && sInstrIndex != -1) {
@@ -565,6 +1384,30 @@ func isLockToLockInSameBlock(fLock *ssa.Call, sLock *ssa.Call) bool {
 }
 
 func (c *Checker) CheckWaitgroupAdd(j *lint.Job) {
+	// Any Add in the goroutine races with the Wait it is meant to
+	// delay, not just one in its first statement. Nested function
+	// literals are skipped; goroutines started by them are checked
+	// on their own.
+	fnAdd := func(node ast.Node) bool {
+		switch node := node.(type) {
+		case *ast.FuncLit:
+			return false
+		case *ast.CallExpr:
+			sel, ok := node.Fun.(*ast.SelectorExpr)
+			if !ok {
+				return true
+			}
+			fn, ok := ObjectOf(j, sel.Sel).(*types.Func)
+			if !ok {
+				return true
+			}
+			if fn.FullName() == "(*sync.WaitGroup).Add" {
+				j.Errorf(sel, "should call %s before starting the goroutine to avoid a race",
+					Render(j, node))
+			}
+		}
+		return true
+	}
 	fn := func(node ast.Node) bool {
 		g, ok := node.(*ast.GoStmt)
 		if !ok {
@@ -574,29 +1417,7 @@ func (c *Checker) CheckWaitgroupAdd(j *lint.Job) {
 		if !ok {
 			return true
 		}
-		if len(fun.Body.List) == 0 {
-			return true
-		}
-		stmt, ok := fun.Body.List[0].(*ast.ExprStmt)
-		if !ok {
-			return true
-		}
-		call, ok := stmt.X.(*ast.CallExpr)
-		if !ok {
-			return true
-		}
-		sel, ok := call.Fun.(*ast.SelectorExpr)
-		if !ok {
-			return true
-		}
-		fn, ok := ObjectOf(j, sel.Sel).(*types.Func)
-		if !ok {
-			return true
-		}
-		if fn.FullName() == "(*sync.WaitGroup).Add" {
-			j.Errorf(sel, "should call %s before starting the goroutine to avoid a race",
-				Render(j, stmt))
-		}
+		ast.Inspect(fun.Body, fnAdd)
 		return true
 	}
 	for _, f := range j.Program.Files {
@@ -616,15 +1437,7 @@ func (c *Checker) CheckEmptyCriticalSection(j *lint.Job) {
 	// is protected by the mutex). In an SSA-based approach, however,
 	// it would miss a lot of real bugs.
 
-	mutexParams := func(s ast.Stmt) (x ast.Expr, funcName string, ok bool) {
-		expr, ok := s.(*ast.ExprStmt)
-		if !ok {
-			return nil, "", false
-		}
-		call, ok := expr.X.(*ast.CallExpr)
-		if !ok {
-			return nil, "", false
-		}
+	mutexCall := func(call *ast.CallExpr) (x ast.Expr, funcName string, ok bool) {
 		sel, ok := call.Fun.(*ast.SelectorExpr)
 		if !ok {
 			return nil, "", false
@@ -641,8 +1454,61 @@ func (c *Checker) CheckEmptyCriticalSection(j *lint.Job) {
 
 		return sel.X, fn.Name(), true
 	}
+	mutexParams := func(s ast.Stmt) (x ast.Expr, funcName string, ok bool) {
+		expr, ok := s.(*ast.ExprStmt)
+		if !ok {
+			return nil, "", false
+		}
+		call, ok := expr.X.(*ast.CallExpr)
+		if !ok {
+			return nil, "", false
+		}
+		return mutexCall(call)
+	}
+	isPair := func(lock, unlock string) bool {
+		return (lock == "Lock" && unlock == "Unlock") ||
+			(lock == "RLock" && unlock == "RUnlock")
+	}
+
+	// fnBody flags functions ending in a lock and a deferred unlock,
+	// optionally followed by a bare return: the unlock runs right
+	// after the lock. Only the function's own
+	// block is considered, as the function continues with the lock
+	// held after the end of a nested block.
+	fnBody := func(body *ast.BlockStmt) {
+		if body == nil {
+			return
+		}
+		list := body.List
+		if n := len(list); n > 0 {
+			if ret, ok := list[n-1].(*ast.ReturnStmt); ok && len(ret.Results) == 0 {
+				list = list[:n-1]
+			}
+		}
+		if len(list) < 2 {
+			return
+		}
+		sel1, method1, ok1 := mutexParams(list[len(list)-2])
+		def, ok := list[len(list)-1].(*ast.DeferStmt)
+		if !ok1 || !ok {
+			return
+		}
+		sel2, method2, ok2 := mutexCall(def.Call)
+		if !ok2 || Render(j, sel1) != Render(j, sel2) {
+			return
+		}
+		if isPair(method1, method2) {
+			j.Errorf(def, "empty critical section")
+		}
+	}
 
 	fn := func(node ast.Node) bool {
+		switch node := node.(type) {
+		case *ast.FuncDecl:
+			fnBody(node.Body)
+		case *ast.FuncLit:
+			fnBody(node.Body)
+		}
 		block, ok := node.(*ast.BlockStmt)
 		if !ok {
 			return true
@@ -657,8 +1523,7 @@ func (c *Checker) CheckEmptyCriticalSection(j *lint.Job) {
 			if !ok1 || !ok2 || Render(j, sel1) != Render(j, sel2) {
 				continue
 			}
-			if (method1 == "Lock" && method2 == "Unlock") ||
-				(method1 == "RLock" && method2 == "RUnlock") {
+			if isPair(method1, method2) {
 				j.Errorf(block.List[i+1], "empty critical section")
 			}
 		}
@@ -670,6 +1535,20 @@ func (c *Checker) CheckEmptyCriticalSection(j *lint.Job) {
 }
 
 func (c *Checker) CheckConcurrentTesting(j *lint.Job) {
+	isFatal := func(n *callgraph.Node) bool {
+		recv := n.Func.Signature.Recv()
+		if recv == nil || !IsType(recv.Type(), "*testing.common") {
+			return false
+		}
+		switch n.Func.Name() {
+		case "FailNow", "Fatal", "Fatalf", "SkipNow", "Skip", "Skipf":
+			return true
+		}
+		return false
+	}
+
+	// tests are what this check is about, so they are checked
+	// regardless of IncludeTests
 	for _, ssafn := range j.Program.InitialFunctions {
 		for _, block := range ssafn.Blocks {
 			for _, ins := range block.Instrs {
@@ -689,39 +1568,31 @@ func (c *Checker) CheckConcurrentTesting(j *lint.Job) {
 				if fn.Blocks == nil {
 					continue
 				}
-				for _, block := range fn.Blocks {
-					for _, ins := range block.Instrs {
-						call, ok := ins.(*ssa.Call)
-						if !ok {
-							continue
-						}
-						if call.Call.IsInvoke() {
-							continue
-						}
-						callee := call.Call.StaticCallee()
-						if callee == nil {
-							continue
-						}
-						recv := callee.Signature.Recv()
-						if recv == nil {
-							continue
-						}
-						if !IsType(recv.Type(), "*testing.common") {
-							continue
-						}
-						fn, ok := call.Call.StaticCallee().Object().(*types.Func)
-						if !ok {
-							continue
-						}
-						name := fn.Name()
-						switch name {
-						case "FailNow", "Fatal", "Fatalf", "SkipNow", "Skip", "Skipf":
-						default:
-							continue
-						}
-						j.Errorf(gostmt, "the goroutine calls T.%s, which must be called in the same goroutine as the test", name)
-					}
+				// Goroutines started by the goroutine aren't
+				// followed; their go statements are checked on
+				// their own.
+				node := c.funcDescs.CallGraph.Nodes[fn]
+				if node == nil {
+					continue
+				}
+				path := callgraph.PathSearchIgnoreGoCall(node, isFatal)
+				if len(path) == 0 {
+					continue
+				}
+				name := path[len(path)-1].Callee.Func.Name()
+				var helpers []string
+				if _, ok := gostmt.Call.Value.(*ssa.Function); ok {
+					helpers = append(helpers, fn.Name())
+				}
+				for _, e := range path[:len(path)-1] {
+					helpers = append(helpers, e.Callee.Func.Name())
+				}
+				if len(helpers) == 0 {
+					j.Errorf(gostmt, "the goroutine calls T.%s, which must be called in the same goroutine as the test", name)
+					continue
 				}
+				j.Errorf(gostmt, "the goroutine calls T.%s via %s, which must be called in the same goroutine as the test",
+					name, strings.Join(helpers, " -> "))
 			}
 		}
 	}
@@ -729,7 +1600,7 @@ func (c *Checker) CheckConcurrentTesting(j *lint.Job) {
 
 func (c *Checker) CheckDeferLock(j *lint.Job) {
 
-	for _, ssafn := range j.Program.InitialFunctions {
+	for _, ssafn := range c.checkedFuncs(j) {
 		for _, block := range ssafn.Blocks {
 			instrs := FilterDebug(block.Instrs)
 			if len(instrs) < 2 {
@@ -740,7 +1611,7 @@ func (c *Checker) CheckDeferLock(j *lint.Job) {
 				if !ok {
 					continue
 				}
-				if !isCallToLock(call.Common()) {
+				if !c.isCallToLock(call.Common()) {
 					continue
 				}
 
@@ -748,29 +1619,38 @@ func (c *Checker) CheckDeferLock(j *lint.Job) {
 				if !ok {
 					continue
 				}
-				if !isCallToLock(nins.Common()) {
-					continue
-				}
-				if call.Common().Args[0] != nins.Call.Args[0] {
+				deferred := c.lockKindOf(nins.Common())
+				if deferred == notLock || !sameReceiver(call.Common(), nins.Common()) {
 					continue
 				}
-				name := shortCallName(call.Common())
-				alt := ""
-				switch name {
-				case "Lock":
-					alt = "Unlock"
-				case "RLock":
+				kind := c.lockKindOf(call.Common())
+				alt := "Unlock"
+				if kind.read() {
 					alt = "RUnlock"
 				}
-				j.Errorf(nins, "deferring %s right after having locked already; did you mean to defer %s?", name, alt)
+				if kind.read() != deferred.read() {
+					j.Errorf(nins, "deferring %s right after acquiring the lock with %s; did you mean to defer %s?",
+						shortCallName(nins.Common()), shortCallName(call.Common()), alt)
+					continue
+				}
+				j.Errorf(nins, "deferring %s right after having locked already; did you mean to defer %s?", shortCallName(nins.Common()), alt)
 			}
 		}
 	}
 }
 
+// sameReceiver reports whether the method calls a and b are made on
+// the same value.
+func sameReceiver(a, b *ssa.CallCommon) bool {
+	if a.IsInvoke() || b.IsInvoke() {
+		return a.IsInvoke() && b.IsInvoke() && a.Value == b.Value
+	}
+	return len(a.Args) > 0 && len(b.Args) > 0 && a.Args[0] == b.Args[0]
+}
+
 func (c *Checker) CheckUnlockAfterLock(j *lint.Job) {
 
-	for _, ssafn := range j.Program.InitialFunctions {
+	for _, ssafn := range c.checkedFuncs(j) {
 		for _, block := range ssafn.Blocks {
 
 			instrs := FilterDebug(block.Instrs)
@@ -784,178 +1664,2061 @@ func (c *Checker) CheckUnlockAfterLock(j *lint.Job) {
 				if !ok {
 					continue
 				}
-				if !isCallToLock(call.Common()) {
+				if !c.isCallToLock(call.Common()) {
 					continue
 				}
 				nins, ok := instrs[i+1].(*ssa.Call)
 				if !ok {
 					continue
 				}
-				if !isCallToUnlock(nins.Common()) {
+				if !c.isCallToUnlock(nins.Common()) {
 					continue
 				}
-				if call.Common().Args[0] != nins.Call.Args[0] {
+				if recv := lockReceiver(call.Common()); recv == nil || recv != lockReceiver(nins.Common()) {
 					continue
 				}
-				name := shortCallName(call.Common())
-				alt := ""
-				switch name {
-				case "Lock":
-					alt = "Unlock"
-				case "RLock":
-					alt = "RUnlock"
+				if adjacentStmts(j.File(call), call.Pos(), nins.Pos()) {
+					// an empty critical section, flagged by SA2001
+					continue
 				}
-				j.Errorf(nins, "Unlock %s right after locking; did you mean to defer %s?", name, alt)
+				unlock := shortCallName(nins.Common())
+				j.Errorf(nins, "%s right after %s; did you mean to defer %s?", unlock, shortCallName(call.Common()), unlock)
 			}
 		}
 	}
 }
 
-func isUnlockBeforeLock(sNode *bbcallgraph.BBNode, lockKey string) bool {
-	lockIndex := -1
-	unLockIndex := -1
-
-	for index, ins := range sNode.BB.Instrs {
-		call, ok := ins.(*ssa.Call)
-		if !ok {
-			continue
+// adjacentStmts reports whether the statements of f at the positions
+// a and b are distinct and follow each other in the same block.
+func adjacentStmts(f *ast.File, a, b token.Pos) bool {
+	if f == nil {
+		return false
+	}
+	stmtAt := func(pos token.Pos) (ast.Stmt, *ast.BlockStmt) {
+		path, _ := astutil.PathEnclosingInterval(f, pos, pos)
+		for i, n := range path {
+			stmt, ok := n.(ast.Stmt)
+			if !ok || i+1 == len(path) {
+				continue
+			}
+			if block, ok := path[i+1].(*ast.BlockStmt); ok {
+				return stmt, block
+			}
 		}
-		if isCallToUnlock(call.Common()) && getLockPrefix(call) == lockKey {
-			unLockIndex = index
+		return nil, nil
+	}
+	sa, block := stmtAt(a)
+	sb, blockB := stmtAt(b)
+	if block == nil || block != blockB {
+		return false
+	}
+	for i := range block.List[:len(block.List)-1] {
+		if block.List[i] == sa && block.List[i+1] == sb {
+			return true
 		}
+	}
+	return false
+}
 
-		if isCallToLock(call.Common()) && getLockPrefix(call) == lockKey {
-			lockIndex = index
+// releasesSameLock reports whether ins is a call releasing the lock
+// lock acquires, see sameLock. Deferred calls, which only run when the
+// function returns, don't count; see releasesLock for those.
+func (c *Checker) releasesSameLock(ins ssa.Instruction, lock ssa.CallInstruction) bool {
+	call, ok := ins.(*ssa.Call)
+	return ok && c.isCallToUnlock(call.Common()) && sameLock(call, lock)
+}
+
+// releasesLock reports whether ins releases lock, either by a call or
+// deferred call to Unlock, or by deferring a closure that unlocks it.
+func (c *Checker) releasesLock(ins ssa.Instruction, lock *ssa.Call) bool {
+	call, ok := ins.(ssa.CallInstruction)
+	if !ok {
+		return false
+	}
+	if _, ok := call.(*ssa.Go); ok {
+		return false
+	}
+	if c.isCallToUnlock(call.Common()) {
+		return sameLock(call, lock)
+	}
+	if d, ok := call.(*ssa.Defer); ok {
+		if mc, ok := d.Call.Value.(*ssa.MakeClosure); ok {
+			return c.unlocks(mc.Fn.(*ssa.Function), lock)
 		}
 	}
+	return false
+}
 
-	if unLockIndex < lockIndex {
-		return true
+func (c *Checker) CheckMissingUnlock(j *lint.Job) {
+	for _, ssafn := range c.checkedFuncs(j) {
+		var locks []*ssa.Call
+		for _, block := range ssafn.Blocks {
+			for _, ins := range block.Instrs {
+				if call, ok := ins.(*ssa.Call); ok && c.isCallToLock(call.Common()) {
+					locks = append(locks, call)
+				}
+			}
+		}
+		for _, lock := range locks {
+			// A deferred unlock releases the lock at every return
+			// it dominates, even if it was deferred before the lock
+			// was acquired.
+			var defers []*ssa.Defer
+			var releases []ssa.Instruction
+			for _, block := range ssafn.Blocks {
+				for _, ins := range block.Instrs {
+					if !c.releasesLock(ins, lock) {
+						continue
+					}
+					releases = append(releases, ins)
+					if d, ok := ins.(*ssa.Defer); ok {
+						defers = append(defers, d)
+					}
+				}
+			}
+			done := func(ins ssa.Instruction) bool {
+				if c.releasesLock(ins, lock) {
+					return true
+				}
+				if ret, ok := ins.(*ssa.Return); ok {
+					for _, d := range defers {
+						// a return is its block's last
+						// instruction
+						if d.Block().Dominates(ret.Block()) {
+							return true
+						}
+					}
+				}
+				return false
+			}
+			if len(releases) == 0 {
+				// functions such as lock helpers deliberately
+				// return with the lock held
+				continue
+			}
+			leak := leakPath(lock, done)
+			if _, ok := leak.(*ssa.Return); !ok {
+				continue
+			}
+			if c.sameBranch(lock, releases) {
+				// if b { mu.Lock() }; ...; if b { mu.Unlock() }
+				// only leaks on paths where b changes its value
+				continue
+			}
+			name := shortCallName(lock.Common())
+			if leak.Pos().IsValid() {
+				j.Errorf(lock, "the lock acquired by %s is not released on the path returning at %v", name, j.Program.DisplayPosition(leak.Pos()))
+			} else {
+				j.Errorf(lock, "the lock acquired by %s is not released on the path reaching the end of the function", name)
+			}
+		}
 	}
-
-	return false
 }
 
-func findPath(fNode *bbcallgraph.BBNode, sNode *bbcallgraph.BBNode, lockKey string) bool {
-	// unlock is in fNode' block, we need not to search
-	isNeededSearch := true
-	for _, ins := range fNode.BB.Instrs {
-		call, ok := ins.(*ssa.Call)
+// branchConditions returns the ifs whose branch b can only be reached
+// through, keyed by their condition and mapped to the block starting
+// the branch. Conditions computed in loops, which may differ between
+// iterations, are left out.
+func (c *Checker) branchConditions(b *ssa.BasicBlock) map[ssa.Value]*ssa.BasicBlock {
+	conds := map[ssa.Value]*ssa.BasicBlock{}
+	for d := b.Idom(); d != nil; d = d.Idom() {
+		iff, ok := d.Instrs[len(d.Instrs)-1].(*ssa.If)
 		if !ok {
 			continue
 		}
-		if isCallToUnlock(call.Common()) && getLockPrefix(call) == lockKey {
-			isNeededSearch = false
+		if def, ok := iff.Cond.(ssa.Instruction); ok && c.isInLoop(def.Block()) {
+			continue
+		}
+		for _, succ := range d.Succs {
+			if len(succ.Preds) == 1 && succ.Dominates(b) {
+				conds[iff.Cond] = succ
+			}
 		}
 	}
+	return conds
+}
 
-	// unlock is before second lock, we need not to search
-	/*
-	   func f1 () {
-		 r.Lock()
-	     ....
-	     f2()
-	     ....
-	   }
-
-
-	   func f2() {
-		  ....
-		  r.Unlock()
-	      ....
-	      r.lock()
-		  ....
-	   }
-	 */
+// sameBranch reports whether lock and one of releases are in the same
+// branch of two different ifs on the same condition, as in
+// if b { mu.Lock() }; ...; if b { mu.Unlock() }.
+func (c *Checker) sameBranch(lock ssa.Instruction, releases []ssa.Instruction) bool {
+	lockConds := c.branchConditions(lock.Block())
+	if len(lockConds) == 0 {
+		return false
+	}
+	for _, rel := range releases {
+		for cond, branch := range c.branchConditions(rel.Block()) {
+			lockBranch, ok := lockConds[cond]
+			if !ok || lockBranch == branch {
+				// the same if guards both
+				continue
+			}
+			if (lockBranch == lockBranch.Preds[0].Succs[0]) == (branch == branch.Preds[0].Succs[0]) {
+				return true
+			}
+		}
+	}
+	return false
+}
 
-	if isUnlockBeforeLock(sNode, lockKey) {
-		isNeededSearch = false
+// CheckSemaphoreRelease flags acquisitions of a
+// golang.org/x/sync/semaphore.Weighted that are never released, or
+// that aren't released on every path returning from a function that
+// releases the semaphore itself.
+func (c *Checker) CheckSemaphoreRelease(j *lint.Job) {
+	const (
+		acquire    = "(*golang.org/x/sync/semaphore.Weighted).Acquire"
+		tryAcquire = "(*golang.org/x/sync/semaphore.Weighted).TryAcquire"
+		release    = "(*golang.org/x/sync/semaphore.Weighted).Release"
+	)
+	// the semaphore and weight arguments of acquisitions and
+	// releases
+	sem := func(call ssa.CallInstruction) ssa.Value { return call.Common().Args[0] }
+	weight := func(call ssa.CallInstruction) ssa.Value {
+		args := call.Common().Args
+		return args[len(args)-1]
 	}
 
-	if isNeededSearch {
-		result := bbcallgraph.LockPathSearch(
-			fNode, sNode, lockKey, func(node *bbcallgraph.BBNode) bool {
+	var releases []ssa.CallInstruction
+	for _, fn := range j.Program.AllFunctions {
+		for _, block := range fn.Blocks {
+			for _, ins := range block.Instrs {
+				if call, ok := ins.(ssa.CallInstruction); ok && IsCallTo(call.Common(), release) {
+					releases = append(releases, call)
+				}
+			}
+		}
+	}
+	keys := map[ssa.Value][]valueKey{}
+	resolve := func(v ssa.Value) []valueKey {
+		ks, ok := keys[v]
+		if !ok {
+			ks, _ = c.valueKeys(v)
+			keys[v] = ks
+		}
+		return ks
+	}
+	same := func(a, b ssa.CallInstruction) bool {
+		if sem(a) == sem(b) {
+			return true
+		}
+		ka, kb := resolve(sem(a)), resolve(sem(b))
+		return len(ka) > 0 && len(kb) > 0 && sharesObject(ka, kb)
+	}
 
-				for _, ins := range node.BB.Instrs {
-					call, ok := ins.(*ssa.Call)
-					if !ok {
+	for _, ssafn := range c.checkedFuncs(j) {
+		for _, block := range ssafn.Blocks {
+			for _, ins := range block.Instrs {
+				acq, ok := ins.(*ssa.Call)
+				if !ok || !IsCallTo(acq.Common(), acquire) && !IsCallTo(acq.Common(), tryAcquire) {
+					continue
+				}
+				name := addressName(sem(acq))
+				var local []ssa.CallInstruction
+				released := false
+				for _, rel := range releases {
+					if !same(acq, rel) {
 						continue
 					}
+					released = true
+					if rel.Parent() == ssafn {
+						local = append(local, rel)
+					}
+				}
+				if !released {
+					j.Errorf(acq, "semaphore %s is acquired but never released; once its weight is used up, acquiring it blocks forever", name)
+					continue
+				}
+				if len(local) == 0 {
+					// released elsewhere, e.g. by a goroutine
+					// started here
+					continue
+				}
 
-					if isCallToUnlock(call.Common()) && getLockPrefix(call) == lockKey {
-						return false
+				// Acquire fails if its context is done, and
+				// TryAcquire if the semaphore isn't available;
+				// neither has to be released then.
+				failed := map[*ssa.BasicBlock]bool{}
+				for _, ref := range FilterDebug(*acq.Referrers()) {
+					var ifs []ssa.Instruction
+					var onTrue bool
+					switch ref := ref.(type) {
+					case *ssa.If:
+						// TryAcquire's result used as a condition
+						ifs, onTrue = []ssa.Instruction{ref}, false
+					case *ssa.UnOp:
+						if ref.Op == token.NOT {
+							ifs, onTrue = FilterDebug(*ref.Referrers()), true
+						}
+					case *ssa.BinOp:
+						if k, ok := ref.Y.(*ssa.Const); ok && k.IsNil() {
+							ifs, onTrue = FilterDebug(*ref.Referrers()), ref.Op == token.NEQ
+						}
+					}
+					for _, ins := range ifs {
+						if cond, ok := ins.(*ssa.If); ok {
+							if onTrue {
+								failed[cond.Block().Succs[0]] = true
+							} else {
+								failed[cond.Block().Succs[1]] = true
+							}
+						}
+					}
+				}
+				var defers []*ssa.Defer
+				for _, rel := range local {
+					if d, ok := rel.(*ssa.Defer); ok {
+						defers = append(defers, d)
+					}
+				}
+				done := func(ins ssa.Instruction) bool {
+					if failed[ins.Block()] {
+						return true
+					}
+					for _, rel := range local {
+						if ins == rel {
+							return true
+						}
+					}
+					if ret, ok := ins.(*ssa.Return); ok {
+						for _, d := range defers {
+							if d.Block().Dominates(ret.Block()) {
+								return true
+							}
+						}
+					}
+					return false
+				}
+				if leak, ok := leakPath(acq, done).(*ssa.Return); ok {
+					if leak.Pos().IsValid() {
+						j.Errorf(acq, "semaphore %s is not released on the path returning at %v", name, j.Program.DisplayPosition(leak.Pos()))
+					} else {
+						j.Errorf(acq, "semaphore %s is not released on the path reaching the end of the function", name)
 					}
+					continue
+				}
 
-					if isCallToLock(call.Common()) && getLockPrefix(call) == lockKey {
+				// note releasing a different weight than acquired,
+				// if all of them are constant
+				n, ok := weight(acq).(*ssa.Const)
+				if !ok {
+					continue
+				}
+				var other *ssa.Const
+				for _, rel := range local {
+					k, ok := weight(rel).(*ssa.Const)
+					if !ok || k.Int64() == n.Int64() {
+						other = nil
 						break
 					}
+					other = k
 				}
-				return true
-
-			})
-
-		if len(result) > 0 {
-			return true
+				if other != nil {
+					j.Errorf(acq, "semaphore %s is acquired with weight %d but released with weight %d", name, n.Int64(), other.Int64())
+				}
+			}
 		}
 	}
+}
 
-	return false
+// nestedLock is the acquisition of the lock inner while holding the
+// lock outer.
+type nestedLock struct {
+	outer, inner string
+	call         *ssa.Call
 }
 
-func (c *Checker) _isDoubleLock(fInstr *ssa.Call, sInstr *ssa.Call, lockKey string) bool {
+// lockOrder holds the nested acquisitions of locks in a set of
+// functions. Locks are identified by the objects they resolve to, so
+// that the same lock is recognized in different functions.
+type lockOrder struct {
+	nested []nestedLock
+	ids    map[valueKey]string
+	names  map[string]string
+}
 
-	// TODO: right?
-	fName := shortCallName(fInstr.Common())
-	sName := shortCallName(sInstr.Common())
-	if fName != sName {
-		return false
+// lockOrder returns the nested acquisitions of locks in fns, in the
+// order of the functions and of their instructions.
+func (c *Checker) lockOrder(fns []*ssa.Function) *lockOrder {
+	o := &lockOrder{ids: map[valueKey]string{}, names: map[string]string{}}
+	key := func(call ssa.CallInstruction) (string, bool) {
+		common := call.Common()
+		if common.IsInvoke() || len(common.Args) == 0 {
+			return fieldLockKey(call)
+		}
+		keys, ok := c.valueKeys(common.Args[0])
+		if !ok || len(keys) != 1 {
+			return "", false
+		}
+		id, ok := o.ids[keys[0]]
+		if !ok {
+			id = fmt.Sprint(len(o.ids))
+			o.ids[keys[0]] = id
+			o.names[id] = keys[0].name()
+		}
+		return id, true
 	}
+	for _, ssafn := range fns {
+		var ls *lockSets
+		for _, block := range ssafn.Blocks {
+			for _, ins := range block.Instrs {
+				call, ok := ins.(*ssa.Call)
+				if !ok || !c.isCallToLock(call.Common()) {
+					continue
+				}
+				inner, ok := key(call)
+				if !ok {
+					continue
+				}
+				if ls == nil {
+					ls = computeLockSets(ssafn, c.callLockOps(key))
+				}
+				var outers []string
+				for outer := range ls.at(call).must {
+					if outer != inner {
+						outers = append(outers, outer)
+					}
+				}
+				sort.Strings(outers)
+				for _, outer := range outers {
+					o.nested = append(o.nested, nestedLock{outer, inner, call})
+				}
+			}
+		}
+	}
+	return o
+}
 
-	fFunc := fInstr.Parent()
-	sFunc := sInstr.Parent()
-
-	isNotNeedFindPathSearch := false
-
-	// create basic block call graph
-	bg := bbcallgraph.BBCallGraph(fFunc)
+// name returns the name of the lock identified by id.
+func (o *lockOrder) name(id string) string {
+	if n, ok := o.names[id]; ok {
+		return n
+	}
+	return id
+}
 
-	if fInstr.Block() == sInstr.Block() {
-		if isLockToLockInSameBlock(fInstr, sInstr) {
-			isNotNeedFindPathSearch = true
+func (c *Checker) CheckLockOrder(j *lint.Job) {
+	order := c.lockOrder(c.checkedFuncs(j))
+	name := order.name
+
+	// edges[edge{a, b}] is the first acquisition of b while holding a
+	type edge struct{ from, to string }
+	edges := map[edge]*ssa.Call{}
+	succs := map[string][]string{}
+	for _, n := range order.nested {
+		e := edge{n.outer, n.inner}
+		if old, ok := edges[e]; ok && old.Pos() <= n.call.Pos() {
+			continue
 		}
-
-		// maybe in a loop
-		if !isNotNeedFindPathSearch && c.isInLoop(fInstr.Block()) {
-			fNode := bg.CreateBBNode(fInstr.Block())
-			sNode := bg.CreateBBNode(sInstr.Block())
-			isNotNeedFindPathSearch = findPath(fNode, sNode, lockKey)
+		if _, ok := edges[e]; !ok {
+			succs[n.outer] = append(succs[n.outer], n.inner)
 		}
+		edges[e] = n.call
+	}
 
-	} else if fFunc == sFunc {
-		// in the same function
-		/* not is same block, find a path
-		func f() {
-			a : =0
-			r.Lock()
-			a = 10
-			if i > 0 {
-				r2 = f2()
-				if r2 > 10 {
-					r.Lock()
-					a = a - 4
-					......
+	// path returns a path of locks from a to b, or nil if there is
+	// none.
+	path := func(a, b string) []string {
+		prev := map[string]string{a: ""}
+		queue := []string{a}
+		for len(queue) > 0 {
+			n := queue[0]
+			queue = queue[1:]
+			if n == b {
+				var p []string
+				for ; n != a; n = prev[n] {
+					p = append([]string{n}, p...)
+				}
+				return append([]string{a}, p...)
+			}
+			next := append([]string(nil), succs[n]...)
+			sort.Strings(next)
+			for _, m := range next {
+				if _, ok := prev[m]; !ok {
+					prev[m] = n
+					queue = append(queue, m)
 				}
 			}
 		}
-		*/
-		fNode := bg.CreateBBNode(fInstr.Block())
-		sNode := bg.CreateBBNode(sInstr.Block())
-		isNotNeedFindPathSearch = findPath(fNode, sNode, lockKey)
+		return nil
 	}
 
-	if !isNotNeedFindPathSearch {
-
-		/*
+	var sorted []edge
+	for e := range edges {
+		sorted = append(sorted, e)
+	}
+	sort.Slice(sorted, func(i, k int) bool {
+		return edges[sorted[i]].Pos() < edges[sorted[k]].Pos()
+	})
+	reported := map[string]bool{}
+	for _, e := range sorted {
+		back := path(e.to, e.from)
+		if back == nil {
+			continue
+		}
+		cycle := append([]string{e.from}, back[:len(back)-1]...)
+		members := append([]string(nil), cycle...)
+		sort.Strings(members)
+		if reported[strings.Join(members, ",")] {
+			continue
+		}
+		reported[strings.Join(members, ",")] = true
+
+		other := edges[edge{back[0], back[1]}]
+		if len(cycle) == 2 {
+			j.Errorf(edges[e], "%s is acquired while holding %s, but %s is acquired while holding %s at %v; the two orders can deadlock",
+				name(e.to), name(e.from), name(e.from), name(e.to), j.Program.DisplayPosition(other.Pos()))
+			continue
+		}
+		var cycleNames []string
+		for _, id := range append(cycle, e.from) {
+			cycleNames = append(cycleNames, name(id))
+		}
+		j.Errorf(edges[e], "%s is acquired while holding %s, closing the lock order cycle %s; %s is acquired while holding %s at %v",
+			name(e.to), name(e.from), strings.Join(cycleNames, " -> "), name(back[1]), name(back[0]), j.Program.DisplayPosition(other.Pos()))
+	}
+}
+
+// CheckInconsistentLockOrder flags pairs of locks that are acquired in
+// both orders, nested in one another, anywhere in the program. The
+// order used more often is taken to be the intended one; the
+// acquisitions using the other order are reported.
+func (c *Checker) CheckInconsistentLockOrder(j *lint.Job) {
+	order := c.lockOrder(c.checkedFuncs(j))
+	// sites[p][0] are the acquisitions of p.b while holding p.a,
+	// sites[p][1] those of p.a while holding p.b
+	type pair struct{ a, b string }
+	sites := map[pair]*[2][]*ssa.Call{}
+	var pairs []pair
+	for _, n := range order.nested {
+		p, dir := pair{n.outer, n.inner}, 0
+		if n.inner < n.outer {
+			p, dir = pair{n.inner, n.outer}, 1
+		}
+		s, ok := sites[p]
+		if !ok {
+			s = &[2][]*ssa.Call{}
+			sites[p] = s
+			pairs = append(pairs, p)
+		}
+		s[dir] = append(s[dir], n.call)
+	}
+
+	for _, p := range pairs {
+		s := sites[p]
+		if len(s[0]) == 0 || len(s[1]) == 0 {
+			continue
+		}
+		for _, calls := range s {
+			sort.Slice(calls, func(i, k int) bool { return calls[i].Pos() < calls[k].Pos() })
+		}
+		// on a tie, the order used first is the intended one
+		major := 0
+		if len(s[1]) > len(s[0]) || len(s[1]) == len(s[0]) && s[1][0].Pos() < s[0][0].Pos() {
+			major = 1
+		}
+		outer, inner := order.name(p.a), order.name(p.b)
+		if major == 0 {
+			outer, inner = inner, outer
+		}
+		where := fmt.Sprintf("at %v", j.Program.DisplayPosition(s[major][0].Pos()))
+		if len(s[major]) > 1 {
+			where = fmt.Sprintf("%d times, first at %v", len(s[major]), j.Program.DisplayPosition(s[major][0].Pos()))
+		}
+		for _, call := range s[1-major] {
+			j.Errorf(call, "%s is acquired while holding %s, but %s is acquired while holding %s elsewhere, %s; acquire the two locks in one order",
+				inner, outer, outer, inner, where)
+		}
+	}
+}
+
+// lockedBefore reports whether a lock call on mu may be executed
+// before the unlock ins in ins's function.
+// Only the matching kind of lock counts: RLock for RUnlock and Lock for
+// Unlock.
+func (c *Checker) lockedBefore(ins ssa.CallInstruction, mu ssa.Value) bool {
+	want := ""
+	switch shortCallName(ins.Common()) {
+	case "Unlock":
+		want = "Lock"
+	case "RUnlock":
+		want = "RLock"
+	}
+	isLock := func(ins ssa.Instruction) bool {
+		call, ok := ins.(*ssa.Call)
+		if !ok || !c.isCallToLock(call.Common()) {
+			return false
+		}
+		if want != "" && shortCallName(call.Common()) != want {
+			return false
+		}
+		return lockReceiver(call.Common()) == mu
+	}
+	b := ins.Block()
+	for _, other := range b.Instrs {
+		if other == ins {
+			break
+		}
+		if isLock(other) {
+			return true
+		}
+	}
+	seen := map[*ssa.BasicBlock]bool{}
+	var search func(b *ssa.BasicBlock) bool
+	search = func(b *ssa.BasicBlock) bool {
+		for _, pred := range b.Preds {
+			if seen[pred] {
+				continue
+			}
+			seen[pred] = true
+			for _, other := range pred.Instrs {
+				if isLock(other) {
+					return true
+				}
+			}
+			if search(pred) {
+				return true
+			}
+		}
+		return false
+	}
+	return search(b)
+}
+
+func (c *Checker) CheckUnlockWithoutLock(j *lint.Job) {
+	// only the lock's own methods may use it; a lock passed
+	// elsewhere may be acquired there and handed back to us
+	isMethod := func(common *ssa.CallCommon) bool {
+		callee := common.StaticCallee()
+		return callee != nil && callee.Signature.Recv() != nil && isLockType(callee.Signature.Recv().Type())
+	}
+	for _, ssafn := range c.checkedFuncs(j) {
+		for _, block := range ssafn.Blocks {
+			for _, ins := range block.Instrs {
+				call, ok := ins.(ssa.CallInstruction)
+				if !ok || !c.isCallToUnlock(call.Common()) {
+					continue
+				}
+				if _, ok := call.(*ssa.Go); ok {
+					continue
+				}
+				if len(call.Common().Args) == 0 {
+					continue
+				}
+				mu, ok := call.Common().Args[0].(*ssa.Alloc)
+				if !ok || !isLockType(mu.Type()) || escapes(mu, isMethod) {
+					continue
+				}
+				if c.lockedBefore(call, mu) {
+					continue
+				}
+				j.Errorf(ins, "%s of %s, which is never locked before it in this function", shortCallName(call.Common()), lockDisplayName(mu))
+			}
+		}
+	}
+}
+
+// lockDisplayName returns the name of the local variable holding the
+// lock mu, or a generic description if it has none.
+func lockDisplayName(mu *ssa.Alloc) string {
+	if name := (valueKey{v: mu}).name(); name != "" {
+		return name
+	}
+	return "a lock"
+}
+
+// CheckConditionalLock flags unlocking a lock, on every path, that is
+// only acquired on some of the paths leading to the unlock, as in
+//
+//	if b {
+//		mu.Lock()
+//	}
+//	mu.Unlock()
+func (c *Checker) CheckConditionalLock(j *lint.Job) {
+	for _, ssafn := range c.checkedFuncs(j) {
+		var locks []*ssa.Call
+		for _, block := range ssafn.Blocks {
+			for _, ins := range block.Instrs {
+				if call, ok := ins.(*ssa.Call); ok && c.isCallToLock(call.Common()) {
+					locks = append(locks, call)
+				}
+			}
+		}
+		if len(locks) == 0 {
+			continue
+		}
+		for _, block := range ssafn.Blocks {
+			for _, ins := range block.Instrs {
+				unlock, ok := ins.(ssa.CallInstruction)
+				if !ok || !c.isCallToUnlock(unlock.Common()) {
+					continue
+				}
+				if _, ok := unlock.(*ssa.Go); ok {
+					continue
+				}
+				key := lockKey(unlock)
+				if c.LockState(unlock)[key] != MayHeld {
+					continue
+				}
+				idom := block.Idom()
+				if idom == nil {
+					continue
+				}
+				for _, lock := range locks {
+					if !sameLock(lock, unlock) || lock.Block().Dominates(block) || !idom.Dominates(lock.Block()) {
+						continue
+					}
+					// The lock is acquired in a branch that rejoins
+					// at the unlock. Reaching the unlock only
+					// through idom again, as in a later loop
+					// iteration, doesn't count.
+					if !pathAvoiding(lock, unlock, func(ins ssa.Instruction) bool { return ins.Block() == idom }) {
+						continue
+					}
+					j.Errorf(unlock, "%s of %s, which is only locked at %v on some of the paths reaching it; unlocking it when it isn't locked panics",
+						shortCallName(unlock.Common()), lockName(unlock.Common()), j.Program.DisplayPosition(lock.Pos()))
+					break
+				}
+			}
+		}
+	}
+}
+
+func (c *Checker) CheckCondWaitLoop(j *lint.Job) {
+	// calledInLoops reports whether fn has callers, all of which
+	// call it in a loop, as is the case for helpers wrapping Wait.
+	calledInLoops := func(fn *ssa.Function) bool {
+		node := c.funcDescs.CallGraph.Nodes[fn]
+		if node == nil || len(node.In) == 0 {
+			return false
+		}
+		for _, edge := range node.In {
+			if edge.Site == nil || !c.isInLoop(edge.Site.Block()) {
+				return false
+			}
+		}
+		return true
+	}
+	for _, ssafn := range c.checkedFuncs(j) {
+		for _, block := range ssafn.Blocks {
+			for _, ins := range block.Instrs {
+				call, ok := ins.(*ssa.Call)
+				if !ok || !IsCallTo(call.Common(), "(*sync.Cond).Wait") {
+					continue
+				}
+				if c.isInLoop(block) || calledInLoops(ssafn) {
+					continue
+				}
+				j.Errorf(call, "Cond.Wait should be called in a loop that rechecks the condition, as in for !condition { c.Wait() }, because the condition may no longer hold when Wait returns")
+			}
+		}
+	}
+}
+
+// condLockers resolves cond, a *sync.Cond, to the lockers passed to
+// sync.NewCond when creating it. stored maps fields and globals to the
+// conds stored in them. ok is false if some of the lockers can't be
+// determined.
+func condLockers(cond ssa.Value, stored map[interface{}][]ssa.Value) (lockers []ssa.Value, ok bool) {
+	ok = true
+	seen := map[ssa.Value]bool{}
+	var resolve, resolveLoad func(v ssa.Value)
+	// resolveLoad resolves the conds stored at addr.
+	resolveLoad = func(addr ssa.Value) {
+		var conds []ssa.Value
+		switch addr := addr.(type) {
+		case *ssa.FieldAddr:
+			conds = stored[fieldName(addr)]
+		case *ssa.Global:
+			conds = stored[addr]
+		case *ssa.Alloc:
+			for _, ref := range *addr.Referrers() {
+				if st, isStore := ref.(*ssa.Store); isStore && st.Addr == addr {
+					conds = append(conds, st.Val)
+				}
+			}
+		case *ssa.FreeVar:
+			if b, found := freeVarBinding(addr); found {
+				resolveLoad(b)
+				return
+			}
+		}
+		if len(conds) == 0 {
+			ok = false
+		}
+		for _, c := range conds {
+			resolve(c)
+		}
+	}
+	resolve = func(v ssa.Value) {
+		if seen[v] {
+			return
+		}
+		seen[v] = true
+		switch v := v.(type) {
+		case *ssa.Call:
+			if !IsCallTo(v.Common(), "sync.NewCond") {
+				ok = false
+				return
+			}
+			lockers = append(lockers, v.Common().Args[0])
+		case *ssa.UnOp:
+			if v.Op != token.MUL {
+				ok = false
+				return
+			}
+			resolveLoad(v.X)
+		case *ssa.Phi:
+			for _, e := range v.Edges {
+				resolve(e)
+			}
+		case *ssa.FreeVar:
+			b, found := freeVarBinding(v)
+			if !found {
+				ok = false
+				return
+			}
+			resolve(b)
+		default:
+			ok = false
+		}
+	}
+	resolve(cond)
+	return lockers, ok
+}
+
+// lockerKey returns the key by which lockKey identifies calls locking
+// l, a sync.Locker, and a name for l to use in messages.
+func lockerKey(l ssa.Value) (key, name string, ok bool) {
+	if mi, ok := l.(*ssa.MakeInterface); ok {
+		l = mi.X
+	}
+	switch l := l.(type) {
+	case *ssa.FieldAddr:
+		return fieldName(l), fieldName(l), true
+	case *ssa.Global:
+		return lockValueKey(l), l.Name(), true
+	case *ssa.Alloc:
+		return lockValueKey(l), lockDisplayName(l), true
+	}
+	return "", "", false
+}
+
+func (c *Checker) CheckCondWaitUnlocked(j *lint.Job) {
+	stored := map[interface{}][]ssa.Value{}
+	for _, fn := range j.Program.AllFunctions {
+		for _, block := range fn.Blocks {
+			for _, ins := range block.Instrs {
+				store, ok := ins.(*ssa.Store)
+				if !ok {
+					continue
+				}
+				call, ok := store.Val.(*ssa.Call)
+				if !ok || !IsCallTo(call.Common(), "sync.NewCond") {
+					continue
+				}
+				switch addr := store.Addr.(type) {
+				case *ssa.FieldAddr:
+					stored[fieldName(addr)] = append(stored[fieldName(addr)], call)
+				case *ssa.Global:
+					stored[addr] = append(stored[addr], call)
+				}
+			}
+		}
+	}
+
+	for _, ssafn := range c.checkedFuncs(j) {
+		for _, block := range ssafn.Blocks {
+			for _, ins := range block.Instrs {
+				call, ok := ins.(*ssa.Call)
+				if !ok || !IsCallTo(call.Common(), "(*sync.Cond).Wait") {
+					continue
+				}
+				lockers, ok := condLockers(call.Common().Args[0], stored)
+				if !ok || len(lockers) == 0 {
+					continue
+				}
+				key, name, ok := lockerKey(lockers[0])
+				if !ok {
+					continue
+				}
+				consistent := true
+				for _, l := range lockers[1:] {
+					if k, _, ok := lockerKey(l); !ok || k != key {
+						consistent = false
+					}
+				}
+				if !consistent {
+					continue
+				}
+				held := c.LockState(call)
+				if held[key] == MustHeld || held["sync.Cond.L"] == MustHeld {
+					// c.L.Lock() identifies the lock by the
+					// Cond's field
+					continue
+				}
+				j.Errorf(call, "Cond.Wait is called without holding %s, the Cond's lock; Wait unlocks it before waiting", name)
+			}
+		}
+	}
+}
+
+func (c *Checker) CheckRLockUpgrade(j *lint.Job) {
+	for _, ssafn := range c.checkedFuncs(j) {
+		var rlocks, locks []*ssa.Call
+		for _, block := range ssafn.Blocks {
+			for _, ins := range block.Instrs {
+				call, ok := ins.(*ssa.Call)
+				if !ok {
+					continue
+				}
+				switch kind := c.lockKindOf(call.Common()); {
+				case kind == notLock:
+				case kind.read():
+					rlocks = append(rlocks, call)
+				default:
+					locks = append(locks, call)
+				}
+			}
+		}
+		for _, lock := range locks {
+			for _, rlock := range rlocks {
+				if !sameLock(lock, rlock) {
+					continue
+				}
+				// a deferred RUnlock only runs when the
+				// function returns
+				runlock := func(ins ssa.Instruction) bool {
+					return c.releasesSameLock(ins, rlock)
+				}
+				if !pathAvoiding(rlock, lock, runlock) {
+					continue
+				}
+				j.Errorf(lock, "Lock is called while the read lock acquired at %v is still held; RWMutex can't upgrade a read lock and this deadlocks",
+					j.Program.DisplayPosition(rlock.Pos()))
+				break
+			}
+		}
+	}
+}
+
+// lockRoot returns the global or parameter the lock at addr is
+// reached from, or nil if it is neither.
+func lockRoot(addr ssa.Value) ssa.Value {
+	for {
+		switch v := addr.(type) {
+		case *ssa.FieldAddr:
+			addr = v.X
+		case *ssa.UnOp:
+			if v.Op != token.MUL {
+				return nil
+			}
+			addr = v.X
+		case *ssa.Global, *ssa.Parameter:
+			return v
+		default:
+			return nil
+		}
+	}
+}
+
+func (c *Checker) CheckRecursiveLock(j *lint.Job) {
+	for _, ssafn := range c.checkedFuncs(j) {
+		node := c.funcDescs.CallGraph.Nodes[ssafn]
+		if node == nil {
+			continue
+		}
+		var locks []*ssa.Call
+		for _, block := range ssafn.Blocks {
+			for _, ins := range block.Instrs {
+				call, ok := ins.(*ssa.Call)
+				if !ok || lockReceiver(call.Common()) == nil {
+					continue
+				}
+				// recursive read locks only deadlock with a
+				// waiting writer
+				if kind := c.lockKindOf(call.Common()); kind == notLock || kind.read() {
+					continue
+				}
+				locks = append(locks, call)
+			}
+		}
+		if len(locks) == 0 {
+			continue
+		}
+
+		for _, lock := range locks {
+			root := lockRoot(lockReceiver(lock.Common()))
+			if root == nil {
+				continue
+			}
+			unlock := func(ins ssa.Instruction) bool {
+				return c.releasesSameLock(ins, lock)
+			}
+			for _, e := range node.Out {
+				site, ok := e.Site.(*ssa.Call)
+				if !ok || !lock.Block().Dominates(site.Block()) || !pathAvoiding(lock, site, unlock) {
+					continue
+				}
+				if e.Callee.Func == ssafn {
+					// a parameter is the same lock only if it is
+					// passed on unchanged
+					if p, ok := root.(*ssa.Parameter); ok {
+						i := 0
+						for i < len(ssafn.Params) && ssafn.Params[i] != p {
+							i++
+						}
+						args := site.Common().Args
+						if i >= len(args) || args[i] != ssa.Value(p) {
+							continue
+						}
+					}
+					j.Errorf(site, "recursive call of %s while holding the lock acquired at %v; the call acquires the lock again there and deadlocks",
+						ssafn.Name(), j.Program.DisplayPosition(lock.Pos()))
+					break
+				}
+				if _, ok := root.(*ssa.Global); !ok {
+					// other functions may lock a different
+					// instance
+					continue
+				}
+				path := callgraph.PathSearchIgnoreGoCall(e.Callee, func(n *callgraph.Node) bool {
+					return n.Func == ssafn
+				})
+				if len(path) == 0 {
+					continue
+				}
+				chain := []string{e.Callee.Func.Name()}
+				for _, e := range path {
+					chain = append(chain, e.Callee.Func.Name())
+				}
+				j.Errorf(site, "call of %s leads back to %s via %s while holding the lock acquired at %v; the lock is acquired again there and deadlocks",
+					e.Callee.Func.Name(), ssafn.Name(), strings.Join(chain, " -> "), j.Program.DisplayPosition(lock.Pos()))
+				break
+			}
+		}
+	}
+}
+
+func (c *Checker) CheckDeferUnlockInLoop(j *lint.Job) {
+	for _, ssafn := range c.checkedFuncs(j) {
+		var locks []*ssa.Call
+		var defers []*ssa.Defer
+		for _, block := range ssafn.Blocks {
+			for _, ins := range block.Instrs {
+				switch ins := ins.(type) {
+				case *ssa.Call:
+					if c.isCallToLock(ins.Common()) {
+						locks = append(locks, ins)
+					}
+				case *ssa.Defer:
+					if c.isCallToUnlock(ins.Common()) && c.isInLoop(ins.Block()) {
+						defers = append(defers, ins)
+					}
+				}
+			}
+		}
+		for _, d := range defers {
+			if lock := c.relockedInLoop(d, locks); lock != nil {
+				j.Errorf(d, "deferred %s runs when the function returns, not at the end of the loop iteration, so the lock acquired at %v is acquired again while held; unlock explicitly or move the loop body into a function",
+					shortCallName(d.Common()), j.Program.DisplayPosition(lock.Pos()))
+			}
+		}
+	}
+}
+
+// relockedInLoop returns the call among locks that acquires, in the
+// loop containing the deferred unlock d, the lock d releases, if any.
+func (c *Checker) relockedInLoop(d *ssa.Defer, locks []*ssa.Call) *ssa.Call {
+	want := "Lock"
+	if shortCallName(d.Common()) == "RUnlock" {
+		want = "RLock"
+	}
+	// a lock acquired once before the loop is released once on
+	// return, which is fine
+	loop := c.loopBlocks(d.Block())
+	for _, lock := range locks {
+		if loop[lock.Block()] && shortCallName(lock.Common()) == want && sameLock(lock, d) {
+			return lock
+		}
+	}
+	return nil
+}
+
+// isCloser reports whether call releases a resource, such as Close,
+// Unlock and Release do, or is a function literal that does.
+func (c *Checker) isCloser(call *ssa.CallCommon) bool {
+	if c.isCallToUnlock(call) {
+		return true
+	}
+	switch shortCallName(call) {
+	case "Close", "Unlock", "Release":
+		return true
+	}
+	var fn *ssa.Function
+	switch v := call.Value.(type) {
+	case *ssa.MakeClosure:
+		fn, _ = v.Fn.(*ssa.Function)
+	case *ssa.Function:
+		if v.Parent() != nil {
+			fn = v
+		}
+	}
+	if fn == nil {
+		return false
+	}
+	for _, block := range fn.Blocks {
+		for _, ins := range block.Instrs {
+			if call, ok := ins.(ssa.CallInstruction); ok && c.isCloser(call.Common()) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (c *Checker) CheckDeferInLoop(j *lint.Job) {
+	for _, ssafn := range c.checkedFuncs(j) {
+		var locks []*ssa.Call
+		var defers []*ssa.Defer
+		for _, block := range ssafn.Blocks {
+			for _, ins := range block.Instrs {
+				switch ins := ins.(type) {
+				case *ssa.Call:
+					if c.isCallToLock(ins.Common()) {
+						locks = append(locks, ins)
+					}
+				case *ssa.Defer:
+					if c.isInLoop(ins.Block()) {
+						defers = append(defers, ins)
+					}
+				}
+			}
+		}
+		for _, d := range defers {
+			if c.isCallToUnlock(d.Common()) && c.relockedInLoop(d, locks) != nil {
+				// reported by SA2022
+				continue
+			}
+			closer := c.isCloser(d.Common())
+			if c.DeferInLoopClosersOnly && !closer {
+				continue
+			}
+			if name := shortCallName(d.Common()); closer && name != "" {
+				j.Errorf(d, "deferred %s runs when the function returns, not at the end of the loop iteration, so what it releases piles up until then; move the loop body into a function", name)
+				continue
+			}
+			j.Errorf(d, "defer in a loop runs when the function returns, not at the end of the iteration, so the deferred calls pile up until then; move the loop body into a function")
+		}
+	}
+}
+
+// deferredLocks returns the calls acquiring a lock in the function
+// deferred by d, if d defers a function other than a lock method.
+// Locks the function releases before acquiring them are left out.
+func (c *Checker) deferredLocks(d *ssa.Defer) []*ssa.Call {
+	fn := d.Common().StaticCallee()
+	if fn == nil || len(fn.Blocks) == 0 || c.isCallToLock(d.Common()) {
+		return nil
+	}
+	var locks []*ssa.Call
+	for _, block := range fn.Blocks {
+		for _, ins := range block.Instrs {
+			call, ok := ins.(*ssa.Call)
+			if !ok || !c.isCallToLock(call.Common()) {
+				continue
+			}
+			releases := func(ins ssa.Instruction) bool {
+				return c.releasesSameLock(ins, call)
+			}
+			if entry := fn.Blocks[0].Instrs[0]; entry == call || pathAvoiding(entry, call, releases) {
+				locks = append(locks, call)
+			}
+		}
+	}
+	return locks
+}
+
+// defersRelease reports whether ins defers releasing the lock
+// identified by k, directly or in a deferred function.
+func (c *Checker) defersRelease(ins ssa.Instruction, k string) bool {
+	d, ok := ins.(*ssa.Defer)
+	if !ok {
+		return false
+	}
+	isRelease := func(call ssa.CallInstruction) bool {
+		if !c.isCallToUnlock(call.Common()) {
+			return false
+		}
+		return lockKey(call) == k
+	}
+	if isRelease(d) {
+		return true
+	}
+	fn := d.Common().StaticCallee()
+	if fn == nil {
+		return false
+	}
+	for _, block := range fn.Blocks {
+		for _, ins := range block.Instrs {
+			if call, ok := ins.(ssa.CallInstruction); ok && isRelease(call) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (c *Checker) CheckDeferredRelock(j *lint.Job) {
+	for _, ssafn := range c.checkedFuncs(j) {
+		var defers []*ssa.Defer
+		var returns []*ssa.RunDefers
+		for _, block := range ssafn.Blocks {
+			for _, ins := range block.Instrs {
+				switch ins := ins.(type) {
+				case *ssa.Defer:
+					defers = append(defers, ins)
+				case *ssa.RunDefers:
+					returns = append(returns, ins)
+				}
+			}
+		}
+		never := func(ssa.Instruction) bool { return false }
+
+	deferLoop:
+		for _, d := range defers {
+			for _, lock := range c.deferredLocks(d) {
+				k := lockKey(lock)
+				for _, ret := range returns {
+					if c.LockState(ret)[k] != MustHeld {
+						continue
+					}
+					// Defers run in reverse order: those
+					// registered after d run before it and may
+					// release the lock.
+					if !pathAvoiding(d, ret, func(ins ssa.Instruction) bool { return c.defersRelease(ins, k) }) {
+						continue
+					}
+					for _, block := range ssafn.Blocks {
+						for _, ins := range block.Instrs {
+							if ins != d && c.defersRelease(ins, k) && pathAvoiding(ins, d, never) {
+								j.Errorf(lock, "%s is acquired by the function deferred at %v while it is still held: defers run in reverse order, so the unlock deferred at %v runs after it; this deadlocks",
+									lockName(lock.Common()), j.Program.DisplayPosition(d.Pos()), j.Program.DisplayPosition(ins.Pos()))
+								continue deferLoop
+							}
+						}
+					}
+					j.Errorf(lock, "%s is acquired by the function deferred at %v while it is still held when %s returns; this deadlocks",
+						lockName(lock.Common()), j.Program.DisplayPosition(d.Pos()), ssafn.Name())
+					continue deferLoop
+				}
+			}
+		}
+	}
+}
+
+// localAddr reports whether addr points into a variable allocated by
+// its own function, possibly through fields and elements.
+func localAddr(addr ssa.Value) bool {
+	for {
+		switch v := addr.(type) {
+		case *ssa.FieldAddr:
+			addr = v.X
+		case *ssa.IndexAddr:
+			addr = v.X
+		case *ssa.Alloc:
+			return true
+		default:
+			return false
+		}
+	}
+}
+
+// mayWrite reports whether ins may modify memory that isn't local to
+// its function. Calls of functions whose body isn't available, or that
+// can't be resolved, are assumed to; functions called by the callee
+// aren't looked at.
+func (c *Checker) mayWrite(ins ssa.Instruction, depth int) bool {
+	switch ins := ins.(type) {
+	case *ssa.Store:
+		return !localAddr(ins.Addr)
+	case *ssa.MapUpdate:
+		_, made := ins.Map.(*ssa.MakeMap)
+		return !made
+	case *ssa.Send:
+		return true
+	case ssa.CallInstruction:
+		common := ins.Common()
+		if b, ok := common.Value.(*ssa.Builtin); ok {
+			switch b.Name() {
+			case "delete", "close", "copy", "clear":
+				return true
+			}
+			return false
+		}
+		if c.isCallToLock(common) || c.isCallToUnlock(common) {
+			return false
+		}
+		fn := common.StaticCallee()
+		if fn == nil || len(fn.Blocks) == 0 || depth > 0 {
+			return true
+		}
+		for _, block := range fn.Blocks {
+			for _, ins := range block.Instrs {
+				if c.mayWrite(ins, depth+1) {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+// mayRead reports whether ins reads memory that isn't local to its
+// function, or calls a function.
+func (c *Checker) mayRead(ins ssa.Instruction) bool {
+	switch ins := ins.(type) {
+	case *ssa.UnOp:
+		return ins.Op == token.MUL && !localAddr(ins.X)
+	case *ssa.Lookup:
+		return true
+	case ssa.CallInstruction:
+		common := ins.Common()
+		return !c.isCallToLock(common) && !c.isCallToUnlock(common)
+	}
+	return false
+}
+
+// criticalSectionAccesses reports whether the critical section
+// starting at lock, up to the releases of the lock, may read and may
+// write memory, see mayRead and mayWrite.
+func (c *Checker) criticalSectionAccesses(lock *ssa.Call) (reads, writes bool) {
+	seen := map[*ssa.BasicBlock]bool{}
+	var walk func(instrs []ssa.Instruction, b *ssa.BasicBlock)
+	walk = func(instrs []ssa.Instruction, b *ssa.BasicBlock) {
+		for _, ins := range instrs {
+			if c.releasesSameLock(ins, lock) {
+				return
+			}
+			if c.mayWrite(ins, 0) {
+				writes = true
+				return
+			}
+			if c.mayRead(ins) {
+				reads = true
+			}
+		}
+		for _, succ := range b.Succs {
+			if !seen[succ] && !writes {
+				seen[succ] = true
+				walk(succ.Instrs, succ)
+			}
+		}
+	}
+	b := lock.Block()
+	for i, ins := range b.Instrs {
+		if ins == lock {
+			walk(b.Instrs[i+1:], b)
+			break
+		}
+	}
+	return reads, writes
+}
+
+func (c *Checker) CheckReadMostlyMutex(j *lint.Job) {
+	type usage struct {
+		first         *ssa.Call
+		reads, writes int
+	}
+	mutexes := map[string]*usage{}
+	for _, ssafn := range c.checkedFuncs(j) {
+		for _, block := range ssafn.Blocks {
+			for _, ins := range block.Instrs {
+				call, ok := ins.(*ssa.Call)
+				if !ok || c.lockKindOf(call.Common()) != mutexLock {
+					continue
+				}
+				k := lockKey(call)
+				u, ok := mutexes[k]
+				if !ok {
+					u = &usage{first: call}
+					mutexes[k] = u
+				}
+				if call.Pos() < u.first.Pos() {
+					u.first = call
+				}
+				switch reads, writes := c.criticalSectionAccesses(call); {
+				case writes:
+					u.writes++
+				case reads:
+					u.reads++
+				}
+			}
+		}
+	}
+	for _, u := range mutexes {
+		// only suggest a change for mutexes that are locked often
+		// and mostly for reading
+		if u.reads < 3 || u.reads < 3*u.writes {
+			continue
+		}
+		j.Errorf(u.first, "%s is a sync.Mutex, but %d of the %d critical sections locking it only read; an RWMutex would let them run concurrently",
+			lockName(u.first.Common()), u.reads, u.reads+u.writes)
+	}
+}
+
+// precedingLock returns the closest call acquiring the same lock as
+// unlock that is executed before it on every path, if any.
+func (c *Checker) precedingLock(unlock ssa.CallInstruction) *ssa.Call {
+	b := unlock.Block()
+	instrs := b.Instrs
+	for i, ins := range instrs {
+		if ins == unlock {
+			instrs = instrs[:i]
+			break
+		}
+	}
+	for b != nil {
+		for i := len(instrs) - 1; i >= 0; i-- {
+			call, ok := instrs[i].(*ssa.Call)
+			if !ok || !sameLock(call, unlock) {
+				continue
+			}
+			if c.isCallToUnlock(call.Common()) {
+				// released again before unlock
+				return nil
+			}
+			if c.isCallToLock(call.Common()) {
+				return call
+			}
+		}
+		b = b.Idom()
+		if b != nil {
+			instrs = b.Instrs
+		}
+	}
+	return nil
+}
+
+func (c *Checker) CheckDeferWrongUnlock(j *lint.Job) {
+	for _, ssafn := range c.checkedFuncs(j) {
+		for _, block := range ssafn.Blocks {
+			for _, ins := range block.Instrs {
+				d, ok := ins.(*ssa.Defer)
+				if !ok {
+					continue
+				}
+				unlock := c.unlockKindOf(d.Common())
+				if unlock == notLock {
+					continue
+				}
+				lock := c.precedingLock(d)
+				if lock == nil {
+					continue
+				}
+				kind := c.lockKindOf(lock.Common())
+				if kind.read() == unlock.read() {
+					continue
+				}
+				want := "Unlock"
+				if kind.read() {
+					want = "RUnlock"
+				}
+				j.Errorf(d, "deferring %s, but the lock was acquired with %s at %v; did you mean to defer %s?",
+					shortCallName(d.Common()), shortCallName(lock.Common()), j.Program.DisplayPosition(lock.Pos()), want)
+			}
+		}
+	}
+}
+
+// heldLock returns the acquisition of a lock held on every path
+// reaching ins, if any, and a name for the lock.
+func (c *Checker) heldLock(ins ssa.Instruction) (*ssa.Call, string) {
+	var keys []string
+	for k, st := range c.LockState(ins) {
+		if st == MustHeld {
+			keys = append(keys, k)
+		}
+	}
+	if len(keys) == 0 {
+		return nil, ""
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		// the closest acquisition executed before ins on every path
+		b := ins.Block()
+		instrs := b.Instrs
+		for i, other := range instrs {
+			if other == ins {
+				instrs = instrs[:i]
+				break
+			}
+		}
+		for b != nil {
+			for i := len(instrs) - 1; i >= 0; i-- {
+				lock, ok := instrs[i].(*ssa.Call)
+				if !ok || !c.isCallToLock(lock.Common()) {
+					continue
+				}
+				if lockKey(lock) != k {
+					continue
+				}
+				return lock, lockName(lock.Common())
+			}
+			b = b.Idom()
+			if b != nil {
+				instrs = b.Instrs
+			}
+		}
+	}
+	return nil, ""
+}
+
+func (c *Checker) CheckLockHeldOverChannel(j *lint.Job) {
+	// Semaphores are left to SA2057, which checks the order they
+	// and locks are acquired in.
+	sems := findSemaphores(c.checkedFuncs(j))
+	isSem := func(ch ssa.Value) bool {
+		k, ok := chanFieldKey(ch)
+		return ok && sems[k]
+	}
+
+	for _, ssafn := range c.checkedFuncs(j) {
+		for _, block := range ssafn.Blocks {
+			for _, ins := range block.Instrs {
+				var op string
+				switch ins := ins.(type) {
+				case *ssa.Send:
+					if c.buffered(ins.Chan) || isSem(ins.Chan) {
+						continue
+					}
+					op = "send"
+				case *ssa.UnOp:
+					if ins.Op != token.ARROW || isSem(ins.X) {
+						continue
+					}
+					op = "receive"
+				case *ssa.Select:
+					if !ins.Blocking {
+						continue
+					}
+					op = "select"
+				default:
+					continue
+				}
+				lock, name := c.heldLock(ins)
+				if lock == nil {
+					continue
+				}
+				j.Errorf(ins, "channel %s while holding %s, locked at %v; the goroutine that would unblock it may need the lock, so release it first",
+					op, name, j.Program.DisplayPosition(lock.Pos()))
+			}
+		}
+	}
+}
+
+func (c *Checker) CheckSendToLockedReceiver(j *lint.Job) {
+	type receive struct {
+		ins  ssa.Instruction
+		keys []valueKey
+		// locked is whether the receive is a plain one, not a
+		// case of a select, made while holding locks.
+		locked bool
+	}
+	var receives []receive
+	for _, ssafn := range c.checkedFuncs(j) {
+		for _, block := range ssafn.Blocks {
+			for _, ins := range block.Instrs {
+				var chans []ssa.Value
+				locked := false
+				switch ins := ins.(type) {
+				case *ssa.UnOp:
+					if ins.Op != token.ARROW {
+						continue
+					}
+					chans = []ssa.Value{ins.X}
+					locked = len(c.LockState(ins)) > 0
+				case *ssa.Select:
+					for _, st := range ins.States {
+						if st.Dir == types.RecvOnly {
+							chans = append(chans, st.Chan)
+						}
+					}
+				}
+				for _, ch := range chans {
+					keys, ok := c.valueKeys(ch)
+					if ok && len(keys) > 0 {
+						receives = append(receives, receive{ins, keys, locked})
+					}
+				}
+			}
+		}
+	}
+
+	for _, ssafn := range c.checkedFuncs(j) {
+		for _, block := range ssafn.Blocks {
+			for _, ins := range block.Instrs {
+				send, ok := ins.(*ssa.Send)
+				if !ok || c.buffered(send.Chan) {
+					continue
+				}
+				lock, name := c.heldLock(send)
+				if lock == nil {
+					continue
+				}
+				key := lockKey(lock)
+				keys, ok := c.valueKeys(send.Chan)
+				if !ok || len(keys) == 0 {
+					continue
+				}
+				// every receive on the channel, in another function,
+				// has to wait for the lock first
+				var first ssa.Instruction
+				for _, r := range receives {
+					if r.ins.Parent() == ssafn || !sharesObject(keys, r.keys) {
+						continue
+					}
+					if !r.locked || c.LockState(r.ins)[key] != MustHeld {
+						first = nil
+						break
+					}
+					if first == nil || r.ins.Pos() < first.Pos() {
+						first = r.ins
+					}
+				}
+				if first == nil {
+					continue
+				}
+				j.Errorf(send, "channel send while holding %s, locked at %v, but the channel is only received from while holding it too, e.g. at %v; the receiver can't acquire the lock to receive, so the send never completes",
+					name, j.Program.DisplayPosition(lock.Pos()), j.Program.DisplayPosition(first.Pos()))
+			}
+		}
+	}
+}
+
+func (c *Checker) CheckSleepWhileLocked(j *lint.Job) {
+	for _, ssafn := range c.checkedFuncs(j) {
+		for _, block := range ssafn.Blocks {
+			for _, ins := range block.Instrs {
+				call, ok := ins.(*ssa.Call)
+				if !ok || !IsCallTo(call.Common(), "time.Sleep") {
+					continue
+				}
+				lock, name := c.heldLock(call)
+				if lock == nil {
+					continue
+				}
+				j.Errorf(call, "time.Sleep while holding %s, locked at %v; everything waiting for the lock is blocked for the duration of the sleep",
+					name, j.Program.DisplayPosition(lock.Pos()))
+			}
+		}
+	}
+}
+
+// isCancelChan reports whether receiving from ch waits for a timeout
+// or a cancellation: ch is the result of time.After or of a context's
+// Done method, or the channel of a Timer or Ticker.
+func isCancelChan(ch ssa.Value) bool {
+	switch ch := ch.(type) {
+	case *ssa.Call:
+		common := ch.Common()
+		if common.IsInvoke() {
+			return common.Method.Name() == "Done" && IsType(common.Value.Type(), "context.Context")
+		}
+		return IsCallTo(common, "time.After") || IsCallTo(common, "(context.Context).Done")
+	case *ssa.UnOp:
+		fa, ok := ch.X.(*ssa.FieldAddr)
+		if !ok || ch.Op != token.MUL {
+			return false
+		}
+		switch types.TypeString(Dereference(fa.X.Type()), nil) {
+		case "time.Timer", "time.Ticker":
+			return true
+		}
+	}
+	return false
+}
+
+func (c *Checker) CheckHandlerWaitsWithoutTimeout(j *lint.Job) {
+	for _, ssafn := range c.checkedFuncs(j) {
+		handler := false
+		for _, p := range ssafn.Params {
+			if IsType(p.Type(), "*net/http.Request") {
+				handler = true
+			}
+		}
+		if !handler {
+			continue
+		}
+
+		// the channels goroutines started by the handler send on
+		type send struct {
+			keys []valueKey
+			g    *ssa.Go
+		}
+		var sends []send
+		for _, block := range ssafn.Blocks {
+			for _, ins := range block.Instrs {
+				g, ok := ins.(*ssa.Go)
+				if !ok {
+					continue
+				}
+				fn := g.Common().StaticCallee()
+				if fn == nil {
+					continue
+				}
+				for _, b := range fn.Blocks {
+					for _, ins := range b.Instrs {
+						if s, ok := ins.(*ssa.Send); ok {
+							if keys, ok := c.valueKeys(s.Chan); ok && len(keys) > 0 {
+								sends = append(sends, send{keys, g})
+							}
+						}
+					}
+				}
+			}
+		}
+		if len(sends) == 0 {
+			continue
+		}
+
+		// sender returns the go statement starting a goroutine that
+		// sends on the unbuffered channel ch, if any.
+		sender := func(ch ssa.Value) *ssa.Go {
+			if c.buffered(ch) {
+				return nil
+			}
+			keys, ok := c.valueKeys(ch)
+			if !ok {
+				return nil
+			}
+			for _, s := range sends {
+				if sharesObject(keys, s.keys) {
+					return s.g
+				}
+			}
+			return nil
+		}
+
+		for _, block := range ssafn.Blocks {
+			for _, ins := range block.Instrs {
+				var g *ssa.Go
+				switch ins := ins.(type) {
+				case *ssa.UnOp:
+					if ins.Op == token.ARROW {
+						g = sender(ins.X)
+					}
+				case *ssa.Select:
+					if !ins.Blocking {
+						continue
+					}
+					bounded := false
+					for _, st := range ins.States {
+						if st.Dir != types.RecvOnly {
+							continue
+						}
+						if isCancelChan(st.Chan) {
+							bounded = true
+							break
+						}
+						if g == nil {
+							g = sender(st.Chan)
+						}
+					}
+					if bounded {
+						g = nil
+					}
+				}
+				if g == nil {
+					continue
+				}
+				j.Errorf(ins, "the handler waits for the goroutine started at %v without a timeout or a case for the request's cancellation, "+
+					"so it keeps waiting after the client has gone away; select on the request context's Done channel as well, "+
+					"and make the channel buffered so that the goroutine can still send and exit",
+					j.Program.DisplayPosition(g.Pos()))
+			}
+		}
+	}
+}
+
+// goroutineLocks returns the calls in the function started by g that
+// acquire a lock.
+func (c *Checker) goroutineLocks(g *ssa.Go) []*ssa.Call {
+	callee := g.Common().StaticCallee()
+	if callee == nil {
+		return nil
+	}
+	var out []*ssa.Call
+	for _, b := range callee.Blocks {
+		for _, ins := range b.Instrs {
+			call, ok := ins.(*ssa.Call)
+			if ok && c.isCallToLock(call.Common()) {
+				out = append(out, call)
+			}
+		}
+	}
+	return out
+}
+
+func (c *Checker) CheckLockHeldAcrossJoin(j *lint.Job) {
+	for _, ssafn := range c.checkedFuncs(j) {
+		var locks []*ssa.Call
+		var gos []*ssa.Go
+		var joins []ssa.Instruction
+		for _, block := range ssafn.Blocks {
+			for _, ins := range block.Instrs {
+				switch ins := ins.(type) {
+				case *ssa.Call:
+					if c.isCallToLock(ins.Common()) {
+						locks = append(locks, ins)
+					}
+				case *ssa.Go:
+					gos = append(gos, ins)
+				}
+				if isJoin(ins) {
+					joins = append(joins, ins)
+				}
+			}
+		}
+		if len(locks) == 0 || len(gos) == 0 || len(joins) == 0 {
+			continue
+		}
+
+	gos:
+		for _, g := range gos {
+			held := c.LockState(g)
+			for _, inner := range c.goroutineLocks(g) {
+				for _, lock := range locks {
+					if !sameLock(lock, inner) {
+						continue
+					}
+					if c.lockKindOf(lock.Common()).read() && c.lockKindOf(inner.Common()).read() {
+						// readers don't exclude each other
+						continue
+					}
+					key := lockKey(lock)
+					if held[key] != MustHeld {
+						continue
+					}
+					releases := func(ins ssa.Instruction) bool {
+						return c.releasesSameLock(ins, lock)
+					}
+					for _, join := range joins {
+						if c.LockState(join)[key] != MustHeld || !pathAvoiding(g, join, releases) {
+							continue
+						}
+						j.Errorf(join, "waiting while holding the lock acquired at %v, which the goroutine started at %v acquires at %v; this deadlocks",
+							j.Program.DisplayPosition(lock.Pos()), j.Program.DisplayPosition(g.Pos()), j.Program.DisplayPosition(inner.Pos()))
+						continue gos
+					}
+				}
+			}
+		}
+	}
+}
+
+func (s *doubleLockSearch) isUnlockBeforeLock(sNode *bbcallgraph.BBNode) bool {
+	lockIndex := -1
+	unLockIndex := -1
+
+	for index, ins := range sNode.BB.Instrs {
+		call, ok := ins.(*ssa.Call)
+		if !ok {
+			continue
+		}
+		if s.releases(call) {
+			unLockIndex = index
+		}
+
+		if s.c.isCallToLock(call.Common()) && getLockPrefix(call) == s.key {
+			lockIndex = index
+		}
+	}
+
+	// A block without any unlock can't release the lock before the
+	// second acquisition. This matters for unstructured control flow,
+	// e.g. a goto that jumps over the Unlock straight into the block
+	// that locks again.
+	if unLockIndex != -1 && unLockIndex < lockIndex {
+		return true
+	}
+
+	return false
+}
+
+func (s *doubleLockSearch) findPath(fNode *bbcallgraph.BBNode, sNode *bbcallgraph.BBNode) bool {
+	// unlock is in fNode' block, we need not to search
+	isNeededSearch := true
+	for _, ins := range fNode.BB.Instrs {
+		call, ok := ins.(*ssa.Call)
+		if !ok {
+			continue
+		}
+		if s.releases(call) {
+			isNeededSearch = false
+		}
+	}
+
+	// unlock is before second lock, we need not to search
+	/*
+	   func f1 () {
+		 r.Lock()
+	     ....
+	     f2()
+	     ....
+	   }
+
+
+	   func f2() {
+		  ....
+		  r.Unlock()
+	      ....
+	      r.lock()
+		  ....
+	   }
+	 */
+
+	if s.isUnlockBeforeLock(sNode) {
+		isNeededSearch = false
+	}
+
+	if isNeededSearch {
+		result := bbcallgraph.LockPathSearch(
+			fNode, sNode, s.key, func(node *bbcallgraph.BBNode) bool {
+				if s.ctx.Err() != nil {
+					// out of time; give up the search
+					return false
+				}
+
+				for _, ins := range node.BB.Instrs {
+					call, ok := ins.(*ssa.Call)
+					if !ok {
+						continue
+					}
+
+					if s.releases(call) {
+						return false
+					}
+
+					if s.c.isCallToLock(call.Common()) && getLockPrefix(call) == s.key {
+						break
+					}
+				}
+				return true
+
+			})
+
+		if len(result) > 0 {
+			return true
+		}
+	}
+
+	return false
+}
+
+// bbCallGraph returns the basic block graph of fn. It is built once
+// and shared between callers, which must not add nodes or edges to it.
+func (c *Checker) bbCallGraph(fn *ssa.Function) *bbcallgraph.BBGraph {
+	c.bbGraphsMu.Lock()
+	defer c.bbGraphsMu.Unlock()
+	bg, ok := c.bbGraphs[fn]
+	if !ok {
+		bg = bbcallgraph.BBCallGraph(fn)
+		if c.bbGraphs == nil {
+			c.bbGraphs = map[*ssa.Function]*bbcallgraph.BBGraph{}
+		}
+		c.bbGraphs[fn] = bg
+	}
+	return bg
+}
+
+// exclusiveBranches reports whether a and b are in sibling branches
+// of the same conditional, so that a single call of their function
+// never executes both.
+func exclusiveBranches(a, b ssa.Instruction) bool {
+	ba, bb := a.Block(), b.Block()
+	if ba.Parent() != bb.Parent() || ba == bb || ba.Dominates(bb) || bb.Dominates(ba) {
+		return false
+	}
+	dom := ba
+	for dom != nil && !dom.Dominates(bb) {
+		dom = dom.Idom()
+	}
+	if dom == nil || len(dom.Instrs) == 0 {
+		return false
+	}
+	if _, ok := dom.Instrs[len(dom.Instrs)-1].(*ssa.If); !ok {
+		return false
+	}
+	// in a loop, the other branch may be taken in a later iteration,
+	// and both branches may lead to a common successor
+	never := func(ssa.Instruction) bool { return false }
+	return !pathAvoiding(a, b, never) && !pathAvoiding(b, a, never)
+}
+
+// _isDoubleLock reports whether sInstr may acquire the lock again
+// while fInstr still holds it. uncertain is true if the lock might be
+// released in between by an indirect call whose targets can't be
+// resolved. If ctx is done before the search completes, its error is
+// returned and the result is to be discarded.
+func (c *Checker) _isDoubleLock(ctx context.Context, fInstr *ssa.Call, sInstr *ssa.Call, lockKey string) (found bool, uncertain bool, err error) {
+	defer func() {
+		if err = ctx.Err(); err != nil {
+			found, uncertain = false, false
+		}
+	}()
+
+	if exclusiveBranches(fInstr, sInstr) {
+		return false, false, nil
+	}
+
+	search := &doubleLockSearch{c: c, ctx: ctx, lock: fInstr, key: lockKey}
+
+	fFunc := fInstr.Parent()
+	sFunc := sInstr.Parent()
+
+	isNotNeedFindPathSearch := false
+
+	// basic block call graph; it has nodes for all blocks of fFunc
+	bg := c.bbCallGraph(fFunc)
+
+	if fInstr.Block() == sInstr.Block() {
+		if search.isLockToLockInSameBlock(fInstr, sInstr) {
+			isNotNeedFindPathSearch = true
+		}
+
+		// maybe in a loop
+		if !isNotNeedFindPathSearch && c.isInLoop(fInstr.Block()) {
+			fNode := bg.CreateBBNode(fInstr.Block())
+			sNode := bg.CreateBBNode(sInstr.Block())
+			isNotNeedFindPathSearch = search.findPath(fNode, sNode)
+		}
+
+	} else if fFunc == sFunc {
+		// in the same function
+		/* not is same block, find a path
+		func f() {
+			a : =0
+			r.Lock()
+			a = 10
+			if i > 0 {
+				r2 = f2()
+				if r2 > 10 {
+					r.Lock()
+					a = a - 4
+					......
+				}
+			}
+		}
+		*/
+		fNode := bg.CreateBBNode(fInstr.Block())
+		sNode := bg.CreateBBNode(sInstr.Block())
+		isNotNeedFindPathSearch = search.findPath(fNode, sNode)
+	}
+
+	if !isNotNeedFindPathSearch {
+
+		/*
 		func f1() {
 		  r.Lock()
 		  .....     # no unlock
@@ -963,353 +3726,4003 @@ func (c *Checker) _isDoubleLock(fInstr *ssa.Call, sInstr *ssa.Call, lockKey stri
 		  ....
 		}
 
-		func f2() {
-		  ......    # no unlock
-		  r.Lock()
-		  ......
+		func f2() {
+		  ......    # no unlock
+		  r.Lock()
+		  ......
+		}
+		 */
+
+		fFuncNode := c.funcDescs.CallGraph.Nodes[fFunc]
+		if fFuncNode == nil || ctx.Err() != nil {
+			return false, false, nil
+		}
+		//fmt.Println(fFunc.Name() + "---->" + sFunc.Name())
+
+		pathResult := callgraph.PathSearchIgnoreGoCall(
+			fFuncNode, func(other *callgraph.Node) bool {
+				return other.Func == sFunc
+			})
+
+		// Careful pathResult != nil is not equal len(pathResult) > 0
+		if len(pathResult) > 0 {
+
+			// TODO: optimize it!!!
+			// sInstr isn't in fFunc; don't add its block to bg
+			sNode := &bbcallgraph.BBNode{BB: sInstr.Block()}
+			if search.isUnlockBeforeLock(sNode) {
+				// if there is an unlock before second lock, we should ignore it?
+				return false, false, nil
+			}
+
+			firstEdge := pathResult[0]
+			callInstruction := firstEdge.Site
+			sInstr, ok := callInstruction.(*ssa.Call)
+			if !ok || exclusiveBranches(fInstr, sInstr) {
+				return false, false, nil
+			}
+			// no unlock from lockInstruction to callInstruction
+			// no unlock before second locking, see line#977
+			if fInstr.Block() == sInstr.Block() {
+				if search.isLockToLockInSameBlock(fInstr, sInstr) {
+					return true, search.uncertain, nil
+				}
+			} else {
+
+				fNode := bg.CreateBBNode(fInstr.Block())
+				sNode := bg.CreateBBNode(sInstr.Block())
+
+				finded := search.findPath(fNode, sNode)
+
+				//if finded {
+				//	fmt.Println(pathResult)
+				//}
+				return finded, search.uncertain, nil
+			}
+		}
+	}
+	return isNotNeedFindPathSearch, search.uncertain, nil
+}
+
+// A doubleLock is an acquisition of a lock, second, that may happen
+// while the same goroutine still holds it since first.
+type doubleLock struct {
+	first, second lockInstr
+	// uncertain is set if the lock may have been released in
+	// between by a call through a function value that couldn't be
+	// resolved.
+	uncertain bool
+}
+
+// doubleLockResult is what findDoubleLocks found in a program.
+type doubleLockResult struct {
+	found   []doubleLock
+	skipped map[*ssa.Function]*ssa.Call
+}
+
+// doubleLocks returns the result of findDoubleLocks for j's program.
+// SA2005 and SA2049 share it, so that the pairwise search runs, and
+// functions exceeding PerFuncTimeout are skipped, only once.
+//
+// The analysis runs on the first call; its result is cached.
+func (c *Checker) doubleLocks(j *lint.Job) (found []doubleLock, skipped map[*ssa.Function]*ssa.Call) {
+	c.doubleLocksMu.Lock()
+	defer c.doubleLocksMu.Unlock()
+	if c.doubleLockRes == nil {
+		found, skipped := c.findDoubleLocks(j)
+		c.doubleLockRes = &doubleLockResult{found, skipped}
+	}
+	return c.doubleLockRes.found, c.doubleLockRes.skipped
+}
+
+// findDoubleLocks returns the acquisitions that may happen while the
+// lock is still held, sorted by the position of the second one. A read
+// lock followed by a write lock is an upgrade, left to SA2021, and
+// isn't returned. A pair of sites is returned once, preferring a
+// certain finding over an uncertain one; generic functions have one
+// instance per instantiation, all at the same positions. Acquisitions
+// of the locks of different variables, see distinctLocks, aren't
+// paired. skipped maps the functions that exceeded PerFuncTimeout to
+// one of their acquisitions, see reportSkipped.
+func (c *Checker) findDoubleLocks(j *lint.Job) (found []doubleLock, skipped map[*ssa.Function]*ssa.Call) {
+	match := func(first, second lockKind) bool {
+		return !first.read() || second.read()
+	}
+
+	lockInstructions := make(map[string][]lockInstr)
+
+	for _, ssafn := range c.checkedFuncs(j) {
+
+		//if !(ssafn.Name() == "checkGrowBaseDeviceFS" || ssafn.Name() == "removeDevice") {
+		//	continue
+		//}
+
+		lockResultBB := c.collectLockInstrs(ssafn)
+
+		for lockKey, lockInstrs := range lockResultBB {
+			// collect all lock acquiring
+			lockInstructions[lockKey] = append(lockInstructions[lockKey], lockInstrs...)
+		}
+	}
+
+	var (
+		mu sync.Mutex
+		// reported maps the positions of a pair of acquisitions,
+		// in either order, to its index in found
+		reported = map[[2]token.Pos]int{}
+	)
+
+	// reportPair records second acquiring the lock held since first.
+	reportPair := func(first, second lockInstr, uncertain bool) {
+		pair := [2]token.Pos{first.call.Pos(), second.call.Pos()}
+		if pair[1] < pair[0] {
+			pair[0], pair[1] = pair[1], pair[0]
+		}
+		f := doubleLock{first, second, uncertain}
+		mu.Lock()
+		defer mu.Unlock()
+		if i, ok := reported[pair]; ok {
+			if found[i].uncertain && !uncertain {
+				found[i] = f
+			}
+			return
+		}
+		reported[pair] = len(found)
+		found = append(found, f)
+	}
+
+	var (
+		budgetMu sync.Mutex
+		// spent is the time spent on the pairs whose first
+		// acquisition is in a function
+		spent = map[*ssa.Function]time.Duration{}
+	)
+	skipped = map[*ssa.Function]*ssa.Call{}
+	// isDoubleLock calls _isDoubleLock within what is left of the
+	// PerFuncTimeout of fInstr's function. ok is false if there is
+	// nothing left.
+	isDoubleLock := func(fInstr, sInstr *ssa.Call, lockKey string) (found, uncertain, ok bool) {
+		if c.PerFuncTimeout <= 0 {
+			found, uncertain, _ = c._isDoubleLock(context.Background(), fInstr, sInstr, lockKey)
+			return found, uncertain, true
+		}
+		fn := fInstr.Parent()
+		budgetMu.Lock()
+		_, skip := skipped[fn]
+		left := c.PerFuncTimeout - spent[fn]
+		budgetMu.Unlock()
+		if skip {
+			return false, false, false
+		}
+
+		start := time.Now()
+		ctx, cancel := context.WithTimeout(context.Background(), left)
+		found, uncertain, err := c._isDoubleLock(ctx, fInstr, sInstr, lockKey)
+		cancel()
+
+		budgetMu.Lock()
+		defer budgetMu.Unlock()
+		spent[fn] += time.Since(start)
+		if err != nil || spent[fn] >= c.PerFuncTimeout {
+			if _, ok := skipped[fn]; !ok {
+				skipped[fn] = fInstr
+			}
+		}
+		return found, uncertain, err == nil
+	}
+
+	checkKey := func(lockKey string, lockInstrs []lockInstr) {
+		// the instructions are collected from functions in no
+		// particular order
+		sort.Slice(lockInstrs, func(i, k int) bool {
+			return lockInstrs[i].call.Pos() < lockInstrs[k].call.Pos()
+		})
+		for i := 0; i < len(lockInstrs); i++ {
+
+			for t := i; t < len(lockInstrs); t++ {
+
+				fInstr := lockInstrs[i].call
+				sInstr := lockInstrs[t].call
+				if distinctLocks(fInstr, sInstr) {
+					continue
+				}
+
+				if match(lockInstrs[i].kind, lockInstrs[t].kind) {
+					if found, uncertain, _ := isDoubleLock(fInstr, sInstr, lockKey); found {
+						reportPair(lockInstrs[i], lockInstrs[t], uncertain)
+					}
+				}
+
+				if fInstr == sInstr || !match(lockInstrs[t].kind, lockInstrs[i].kind) {
+					continue
+				}
+				if found, uncertain, _ := isDoubleLock(sInstr, fInstr, lockKey); found {
+					reportPair(lockInstrs[t], lockInstrs[i], uncertain)
+				}
+			}
+		}
+	}
+
+	// The pairwise comparison is quadratic in the number of
+	// acquisitions of a lock; spread the locks over a bounded
+	// number of workers.
+	keys := make(chan string)
+	wg := &sync.WaitGroup{}
+	for n := runtime.GOMAXPROCS(0); n > 0; n-- {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for k := range keys {
+				checkKey(k, lockInstructions[k])
+			}
+		}()
+	}
+	sortedKeys := make([]string, 0, len(lockInstructions))
+	acquisitions := 0
+	for k := range lockInstructions {
+		sortedKeys = append(sortedKeys, k)
+		acquisitions += len(lockInstructions[k])
+	}
+	sort.Strings(sortedKeys)
+	start := time.Now()
+	for _, k := range sortedKeys {
+		keys <- k
+	}
+	close(keys)
+	wg.Wait()
+	c.logf(start, "double lock analysis of %d acquisitions of %d locks", acquisitions, len(sortedKeys))
+
+	sort.Slice(found, func(i, k int) bool {
+		if found[i].second.call.Pos() != found[k].second.call.Pos() {
+			return found[i].second.call.Pos() < found[k].second.call.Pos()
+		}
+		return found[i].first.call.Pos() < found[k].first.call.Pos()
+	})
+	return found, skipped
+}
+
+// reportSkipped reports the functions doubleLocks skipped, as
+// warnings, so that they show up with the other problems rather than
+// passing as checked. what describes the problems that may have been
+// missed.
+func (c *Checker) reportSkipped(j *lint.Job, skipped map[*ssa.Function]*ssa.Call, what string) {
+	var fns []*ssa.Function
+	for fn := range skipped {
+		fns = append(fns, fn)
+	}
+	sort.Slice(fns, func(i, k int) bool {
+		return skipped[fns[i]].Pos() < skipped[fns[k]].Pos()
+	})
+	for _, fn := range fns {
+		var at lint.Positioner = fn
+		if !fn.Pos().IsValid() {
+			at = skipped[fn]
+		}
+		p := j.Errorf(at, "analysis of the locks acquired in %s took longer than %v and was skipped; its %s may go unreported",
+			fn.Name(), c.PerFuncTimeout, what)
+		p.Severity = lint.SeverityWarning
+	}
+}
+
+func (c *Checker) CheckDoubleLock(j *lint.Job) {
+	// Read locks are left to SA2049, which tells the ways they
+	// deadlock apart, and upgrades to SA2021. The kinds of two
+	// write acquisitions differ when one goes through a sync.Locker.
+	found, skipped := c.doubleLocks(j)
+	for _, f := range found {
+		if f.first.kind.read() || f.second.kind.read() {
+			continue
+		}
+		po := j.Program.DisplayPosition(f.first.call.Pos())
+		if f.uncertain {
+			j.Errorf(f.second.call, "mutex possibly re-acquired here (previously acquired at %v); it may have been released by a call through a function value that couldn't be resolved", po)
+		} else {
+			j.Errorf(f.second.call, "mutex re-acquired here (previously acquired at %v)", po)
+		}
+	}
+	what := "double locks"
+	if _, ok := c.Funcs()["SA2049"]; ok {
+		what = "double locks and recursive read locks"
+	}
+	c.reportSkipped(j, skipped, what)
+}
+
+// distinctLocks reports whether a and b, acquiring locks in fields of
+// the same type, operate on the fields of different variables of one
+// function, e.g. r.mu and other.mu, which getLockPrefix doesn't tell
+// apart.
+func distinctLocks(a, b *ssa.Call) bool {
+	if a.Parent() != b.Parent() {
+		return false
+	}
+	ra, rb := lockRoot(lockReceiver(a.Common())), lockRoot(lockReceiver(b.Common()))
+	return ra != nil && rb != nil && ra != rb
+}
+
+func (c *Checker) CheckRecursiveRLock(j *lint.Job) {
+	found, skipped := c.doubleLocks(j)
+	for _, f := range found {
+		if !f.second.kind.read() {
+			continue
+		}
+		po := j.Program.DisplayPosition(f.first.call.Pos())
+		unresolved := ""
+		if f.uncertain {
+			unresolved = ", unless a call through a function value that couldn't be resolved released it"
+		}
+		if !f.first.kind.read() {
+			j.Errorf(f.second.call, "RLock called while holding the write lock acquired at %v%s; an RWMutex can't be read locked by the holder of its write lock, so this always deadlocks",
+				po, unresolved)
+			continue
+		}
+		p := j.Errorf(f.second.call, "read lock re-acquired here (previously acquired at %v)%s; if another goroutine calls Lock in between, this RLock waits for it, while it waits for the first read lock to be released, and both deadlock",
+			po, unresolved)
+		if p.Severity > lint.SeverityWarning {
+			// writer starvation is only a deadlock if there is
+			// a writer
+			p.Severity = lint.SeverityWarning
+		}
+	}
+	if _, ok := c.Funcs()["SA2005"]; !ok {
+		// SA2005 reports the skipped functions for both checks
+		c.reportSkipped(j, skipped, "recursive read locks")
+	}
+}
+
+func (c *Checker) CheckAnonRace(j *lint.Job) {
+	// racy rules out accesses of the starting function that only
+	// happen after the goroutine was waited for, e.g. with wg.Wait()
+	// or a receive, and pairs of accesses made holding the same lock.
+	racy := func(g *ssa.Go, inGo, afterGo ssa.Instruction) bool {
+		if !pathAvoiding(g, afterGo, isJoin) {
+			return false
+		}
+		held := c.LockState(afterGo)
+		for k, st := range c.LockState(inGo) {
+			if st == MustHeld && held[k] == MustHeld {
+				return false
+			}
+		}
+		return true
+	}
+
+	for _, ssafn := range c.checkedFuncs(j) {
+
+		if strings.HasSuffix(ssafn.String(), ".init") {
+			continue
+		}
+
+		blockReachability := util.MapReachableBlocks(ssafn)
+
+		races, ok := util.HasAnonRace(ssafn.AnonFuncs, blockReachability, racy)
+		if !ok {
+			continue
+		}
+
+		for _, race := range races {
+			j.Errorf(race.Access1, "variable %s is shared with the goroutine started at %v: the %s here races with the %s at %v",
+				addressName(race.Shared), j.Program.DisplayPosition(race.Go.Pos()),
+				accessKind(race.Access1), accessKind(race.Access2),
+				j.Program.DisplayPosition(race.Access2.Pos()))
+		}
+	}
+
+}
+
+// accessKind describes a load or store of a variable.
+func accessKind(ins ssa.Instruction) string {
+	if _, ok := ins.(*ssa.Store); ok {
+		return "write"
+	}
+	return "read"
+}
+
+// goVersion returns the minor Go version of the checked code.
+func (c *Checker) goVersion(j *lint.Job) int {
+	if c.GoVersion != 0 {
+		return c.GoVersion
+	}
+	return j.Program.GoVersion
+}
+
+func (c *Checker) CheckLoopVarCapture(j *lint.Job) {
+	if c.goVersion(j) >= 22 {
+		// loop variables are per iteration since Go 1.22
+		return
+	}
+	for _, ssafn := range c.checkedFuncs(j) {
+		for _, block := range ssafn.Blocks {
+			for _, ins := range block.Instrs {
+				g, ok := ins.(*ssa.Go)
+				if !ok {
+					continue
+				}
+				mc, ok := g.Call.Value.(*ssa.MakeClosure)
+				if !ok {
+					continue
+				}
+				loop := c.loopBlocks(block)
+				if len(loop) == 0 {
+					continue
+				}
+				closure := mc.Fn.(*ssa.Function)
+				for i, binding := range mc.Bindings {
+					v, ok := binding.(*ssa.Alloc)
+					if !ok || loop[v.Block()] || !changedBeforeJoin(g, v, loop) {
+						continue
+					}
+					if !isLoaded(closure.FreeVars[i]) {
+						continue
+					}
+					j.Errorf(g, "goroutine captures the loop variable %s, which is shared by all iterations and may have changed by the time the goroutine reads it; pass it as an argument instead",
+						valueKey{v: v}.name())
+					break
+				}
+			}
+		}
+	}
+}
+
+// changedBeforeJoin reports whether the variable v, declared outside
+// of loop, is assigned in it, as loop variables are before Go 1.22,
+// and whether the assignment can happen after the go statement g
+// without waiting for the goroutine first.
+func changedBeforeJoin(g *ssa.Go, v *ssa.Alloc, loop functions.Loop) bool {
+	for _, ref := range *v.Referrers() {
+		st, ok := ref.(*ssa.Store)
+		if !ok || st.Addr != v || !loop[st.Block()] {
+			continue
+		}
+		if pathAvoiding(g, st, isJoin) {
+			return true
+		}
+	}
+	return false
+}
+
+// isLoaded reports whether the variable fv is read.
+func isLoaded(fv *ssa.FreeVar) bool {
+	for _, ref := range *fv.Referrers() {
+		if load, ok := ref.(*ssa.UnOp); ok && load.Op == token.MUL {
+			return true
+		}
+	}
+	return false
+}
+
+// waitGroupCall returns the WaitGroups ins calls method on, e.g.
+// "Done". It returns false if ins isn't such a call or its WaitGroup
+// can't be resolved.
+func (c *Checker) waitGroupCall(ins ssa.Instruction, method string) ([]valueKey, bool) {
+	call, ok := ins.(ssa.CallInstruction)
+	if !ok || !IsCallTo(call.Common(), "(*sync.WaitGroup)."+method) {
+		return nil, false
+	}
+	keys, ok := c.valueKeys(call.Common().Args[0])
+	return keys, ok && len(keys) > 0
+}
+
+func (c *Checker) CheckWaitgroupBlocking(j *lint.Job) {
+	// callsDone reports whether the goroutine started by g calls Done
+	// on one of wgs.
+	callsDone := func(g *ssa.Go, wgs []valueKey) bool {
+		fn := g.Common().StaticCallee()
+		if fn == nil {
+			return false
+		}
+		for _, block := range fn.Blocks {
+			for _, ins := range block.Instrs {
+				if keys, ok := c.waitGroupCall(ins, "Done"); ok && sharesObject(wgs, keys) {
+					return true
+				}
+			}
+		}
+		return false
+	}
+
+	for _, ssafn := range c.checkedFuncs(j) {
+		for _, loop := range c.funcDescs.Loops(ssafn) {
+			var waits []*ssa.Call
+			var gos []*ssa.Go
+			for block := range loop {
+				for _, ins := range block.Instrs {
+					switch ins := ins.(type) {
+					case *ssa.Call:
+						if _, ok := c.waitGroupCall(ins, "Wait"); ok {
+							waits = append(waits, ins)
+						}
+					case *ssa.Go:
+						gos = append(gos, ins)
+					}
+				}
+			}
+			if len(waits) == 0 || len(gos) == 0 {
+				continue
+			}
+
+		waitLoop:
+			for _, wait := range waits {
+				wgs, _ := c.waitGroupCall(wait, "Wait")
+				for block := range loop {
+					for _, ins := range block.Instrs {
+						if keys, ok := c.waitGroupCall(ins, "Add"); ok && sharesObject(wgs, keys) {
+							// the counter is incremented per
+							// iteration, for that iteration's
+							// goroutines only
+							continue waitLoop
+						}
+					}
+				}
+				for _, g := range gos {
+					// Wait and the go statement run in the same
+					// iterations, so Wait blocks before the
+					// goroutines of the following iterations are
+					// started.
+					if !wait.Block().Dominates(g.Block()) && !g.Block().Dominates(wait.Block()) {
+						continue
+					}
+					if !callsDone(g, wgs) {
+						continue
+					}
+					name := wgs[0].name()
+					if name == "" {
+						name = "WaitGroup"
+					}
+					j.Errorf(wait, "%s.Wait waits for the goroutines started at %v, but is called in the loop that starts them and blocks before the goroutines of later iterations exist; call Wait after the loop",
+						name, j.Program.DisplayPosition(g.Pos()))
+					break
+				}
+			}
+		}
+	}
+}
+
+func (c *Checker) CheckWaitgroupReuse(j *lint.Job) {
+	// waitIn returns the call of Wait on one of wgs that the
+	// goroutine started by g makes, if any.
+	waitIn := func(g *ssa.Go, wgs []valueKey) *ssa.Call {
+		fn := g.Common().StaticCallee()
+		if fn == nil {
+			return nil
+		}
+		for _, block := range fn.Blocks {
+			for _, ins := range block.Instrs {
+				if keys, ok := c.waitGroupCall(ins, "Wait"); ok && sharesObject(wgs, keys) {
+					return ins.(*ssa.Call)
+				}
+			}
+		}
+		return nil
+	}
+
+	for _, ssafn := range c.checkedFuncs(j) {
+		for _, loop := range c.funcDescs.Loops(ssafn) {
+			var adds []*ssa.Call
+			var gos []*ssa.Go
+			for block := range loop {
+				for _, ins := range block.Instrs {
+					switch ins := ins.(type) {
+					case *ssa.Call:
+						if _, ok := c.waitGroupCall(ins, "Add"); ok {
+							adds = append(adds, ins)
+						}
+					case *ssa.Go:
+						gos = append(gos, ins)
+					}
+				}
+			}
+
+			for _, add := range adds {
+				wgs, _ := c.waitGroupCall(add, "Add")
+				for _, g := range gos {
+					wait := waitIn(g, wgs)
+					if wait == nil {
+						continue
+					}
+					// Waiting for the goroutine, e.g. on a channel
+					// it closes once Wait returns, orders the
+					// next round's Add after the Wait.
+					if !pathAvoiding(g, add, isJoin) {
+						continue
+					}
+					name := wgs[0].name()
+					if name == "" {
+						name = "WaitGroup"
+					}
+					j.Errorf(add, "%s.Add for the next round may run before the Wait at %v, called by the goroutine started at %v in the previous iteration, has returned; a WaitGroup may only be reused once Wait has returned",
+						name, j.Program.DisplayPosition(wait.Pos()), j.Program.DisplayPosition(g.Pos()))
+					break
+				}
+			}
+		}
+	}
+}
+
+func _CallName(call *ssa.CallCommon) string {
+
+	if call.IsInvoke() {
+		return call.String()
+	}
+
+	switch v := call.Value.(type) {
+	case *ssa.Function:
+		fn, ok := v.Object().(*types.Func)
+		if !ok {
+			return ""
+		}
+		return fn.FullName()
+	case *ssa.Builtin:
+		return v.Name()
+	}
+	return ""
+}
+
+// isTestFunc reports whether f is declared in a _test.go file.
+func isTestFunc(j *lint.Job, f *ssa.Function) bool {
+	pos := f.Pos()
+	if !pos.IsValid() {
+		// synthetic functions, such as wrappers, take the position
+		// of their first positioned instruction
+		for _, bb := range f.Blocks {
+			for _, ins := range bb.Instrs {
+				if ins.Pos().IsValid() {
+					pos = ins.Pos()
+					break
+				}
+			}
+			if pos.IsValid() {
+				break
+			}
+		}
+	}
+	return pos.IsValid() && strings.HasSuffix(j.Program.DisplayPosition(pos).Filename, "_test.go")
+}
+
+// checkedFuncs returns the functions checks analyze: the initial
+// functions of the job's program, without those in test files unless
+// IncludeTests is set.
+func (c *Checker) checkedFuncs(j *lint.Job) []*ssa.Function {
+	if c.IncludeTests {
+		return j.Program.InitialFunctions
+	}
+	var fns []*ssa.Function
+	for _, fn := range j.Program.InitialFunctions {
+		if !isTestFunc(j, fn) {
+			fns = append(fns, fn)
+		}
+	}
+	return fns
+}
+
+// PrimitiveStats counts the uses of synchronization primitives in a
+// program: lock and unlock calls, channel operations and so on.
+type PrimitiveStats struct {
+	Mutex, RWMutex, Cond, Pool, Once, Atomic, Waitgroup, Channel int
+}
+
+func (s PrimitiveStats) String() string {
+	return fmt.Sprintf("Mutex: %d, RWMutex %d,Cond %d, Pool %d, Once %d, atomic %d, Waitgroup %d, Channel %d",
+		s.Mutex, s.RWMutex, s.Cond, s.Pool, s.Once, s.Atomic, s.Waitgroup, s.Channel)
+}
+
+func (c *Checker) CheckPrimitiveUsage(j *lint.Job) {
+	if c.ReportPrimitiveUsage == nil {
+		return
+	}
+	c.ReportPrimitiveUsage(c.PrimitiveUsage(j))
+}
+
+// PrimitiveUsage tallies the uses of synchronization primitives in the
+// functions of the job's program, see checkedFuncs.
+func (c *Checker) PrimitiveUsage(j *lint.Job) PrimitiveStats {
+	var stats PrimitiveStats
+
+	for _, ssafn := range c.checkedFuncs(j) {
+
+		for _, bb := range ssafn.Blocks {
+
+			instrs := FilterDebug(bb.Instrs)
+
+			for _, ins := range instrs {
+
+				// Send type
+				// send value to channel
+				_, ok := ins.(*ssa.Send)
+				if ok {
+					stats.Channel += 1
+					//fmt.Println(ins)
+					continue
+				}
+
+				// UnOp type
+				unop, ok := ins.(*ssa.UnOp)
+				if ok {
+					if unop.Op == token.ARROW {
+						stats.Channel += 1
+						//fmt.Println(ins)
+						continue
+					}
+				}
+
+				// channel in select
+				selector, ok := ins.(*ssa.Select)
+				if ok {
+					// if each case in select is related to a channel
+					for _, state := range selector.States {
+						if state.Chan != nil {
+							stats.Channel += 1
+						}
+					}
+					continue
+				}
+
+				// call
+				var call *ssa.CallCommon
+
+				call_, ok := ins.(*ssa.Call)
+
+				if ok {
+					call = call_.Common()
+				}
+
+				deferIns, ok := ins.(*ssa.Defer)
+				if ok {
+					call = deferIns.Common()
+				}
+
+				if call != nil {
+					callName := _CallName(call)
+					if callName == "(*sync.Mutex).Lock" || callName == "(*sync.Mutex).Unlock" {
+						stats.Mutex += 1
+						continue
+					}
+
+					if callName == "(*sync.RWMutex).Lock" || callName == "(*sync.RWMutex).Unlock" ||
+						callName == "(*sync.RWMutex).RLock" || callName == "(*sync.RWMutex).RUnlock" {
+						stats.RWMutex += 1
+						continue
+					}
+
+					if callName == "(*sync.WaitGroup).Add" || callName == "(*sync.WaitGroup).Done" ||
+						callName == "(*sync.WaitGroup).Wait" {
+						stats.Waitgroup += 1
+						continue
+					}
+
+					if callName == "(*sync.Once).Do" {
+						stats.Once += 1
+						continue
+					}
+
+					if callName == "(*sync.Cond).Broadcast" || callName == "(*sync.Cond).Signal" ||
+						callName == "(*sync.Cond).Wait" {
+						stats.Cond += 1
+						continue
+					}
+
+					if callName == "(*sync.Pool).Get" || callName == "(*sync.Pool).Put" {
+						stats.Pool += 1
+						continue
+					}
+
+					if strings.Contains(callName, "atomic") {
+						stats.Atomic += 1
+						continue
+					}
+				}
+			}
+		}
+	}
+
+	return stats
+}
+
+// fieldAccess is an access to a field of a method's receiver, together
+// with the field locks held at the time of access.
+type fieldAccess struct {
+	fn   *ssa.Function
+	addr *ssa.FieldAddr
+	held map[string]bool
+}
+
+// receiverFieldAccesses collects all accesses to non-lock fields of
+// method receivers in fns, keyed by field. It also returns the sorted
+// list of fields.
+func receiverFieldAccesses(fns []*ssa.Function, held *fieldLockSets) (map[string][]fieldAccess, []string) {
+	accesses := map[string][]fieldAccess{}
+	var fields []string
+	for _, ssafn := range fns {
+		if ssafn.Signature.Recv() == nil || len(ssafn.Params) == 0 {
+			continue
+		}
+		recv := ssafn.Params[0]
+		for _, block := range ssafn.Blocks {
+			for _, ins := range block.Instrs {
+				fa, ok := ins.(*ssa.FieldAddr)
+				if !ok || fa.X != recv {
+					continue
+				}
+				T := Dereference(fa.X.Type()).Underlying().(*types.Struct)
+				if isLockType(T.Field(fa.Field).Type()) {
+					continue
+				}
+				name := fieldName(fa)
+				if _, ok := accesses[name]; !ok {
+					fields = append(fields, name)
+				}
+				accesses[name] = append(accesses[name], fieldAccess{ssafn, fa, held.at(fa)})
+			}
+		}
+	}
+	sort.Strings(fields)
+	return accesses, fields
+}
+
+func (c *Checker) CheckUnlockedFieldAccess(j *lint.Job) {
+	held := newFieldLockSets(c.callLockOps(fieldLockKey))
+	accesses, fields := receiverFieldAccesses(c.checkedFuncs(j), held)
+
+	isExported := func(fn *ssa.Function) bool {
+		return fn.Object() != nil && fn.Object().Exported()
+	}
+
+	// unlockedCaller returns a function that reaches fn without
+	// holding lock, or nil if every call path holds it.
+	var unlockedCaller func(fn *ssa.Function, lock string, seen map[*ssa.Function]bool) *ssa.Function
+	unlockedCaller = func(fn *ssa.Function, lock string, seen map[*ssa.Function]bool) *ssa.Function {
+		if seen[fn] {
+			return nil
+		}
+		seen[fn] = true
+		node := c.funcDescs.CallGraph.Nodes[fn]
+		if node == nil {
+			return nil
+		}
+		for _, edge := range node.In {
+			caller := edge.Caller.Func
+			if caller == nil || edge.Site == nil {
+				continue
+			}
+			if _, ok := edge.Site.(*ssa.Go); ok {
+				// a new goroutine doesn't inherit any locks
+				return caller
+			}
+			if held.at(edge.Site)[lock] {
+				continue
+			}
+			if caller.Signature.Recv() != nil && !isExported(caller) {
+				// the caller may itself expect the lock to be held
+				if other := unlockedCaller(caller, lock, seen); other != nil {
+					return other
+				}
+				continue
+			}
+			return caller
+		}
+		return nil
+	}
+
+	for _, field := range fields {
+		// a field is guarded by the locks that exported methods hold
+		// while accessing it
+		guards := map[string]bool{}
+		for _, acc := range accesses[field] {
+			if !isExported(acc.fn) {
+				continue
+			}
+			for lock := range acc.held {
+				guards[lock] = true
+			}
+		}
+		var locks []string
+		for lock := range guards {
+			locks = append(locks, lock)
+		}
+		sort.Strings(locks)
+
+		reported := map[*ssa.Function]bool{}
+		for _, acc := range accesses[field] {
+			if isExported(acc.fn) || reported[acc.fn] {
+				continue
+			}
+			for _, lock := range locks {
+				if acc.held[lock] {
+					continue
+				}
+				caller := unlockedCaller(acc.fn, lock, map[*ssa.Function]bool{})
+				if caller == nil {
+					continue
+				}
+				reported[acc.fn] = true
+				j.Errorf(acc.addr, "field %s is accessed without holding %s, which guards it in exported methods; %s calls %s without holding the lock",
+					field, lock, caller.Name(), acc.fn.Name())
+				break
+			}
+		}
+	}
+}
+
+// minGuardAccesses and minGuardRatio control how confident
+// CheckWrongLockHeld must be that a lock guards a field: the lock has
+// to be held at no fewer than minGuardAccesses accesses, and at no less
+// than minGuardRatio of all accesses made while holding some lock of
+// the same struct.
+const (
+	minGuardAccesses = 2
+	minGuardRatio    = 0.75
+)
+
+func (c *Checker) CheckWrongLockHeld(j *lint.Job) {
+	held := newFieldLockSets(c.callLockOps(fieldLockKey))
+	accesses, fields := receiverFieldAccesses(c.checkedFuncs(j), held)
+
+	// structLocks returns the locks held in acc that are fields of
+	// the same struct as the accessed field.
+	structLocks := func(field string, acc fieldAccess) []string {
+		prefix := field[:strings.LastIndex(field, ".")+1]
+		var locks []string
+		for lock := range acc.held {
+			if strings.HasPrefix(lock, prefix) && !strings.Contains(lock[len(prefix):], ".") {
+				locks = append(locks, lock)
+			}
+		}
+		sort.Strings(locks)
+		return locks
+	}
+
+	// heldByCallers reports whether every caller of fn holds lock
+	// when calling it, in which case fn's accesses are guarded even
+	// though fn doesn't acquire the lock itself.
+	heldByCallers := func(fn *ssa.Function, lock string) bool {
+		node := c.funcDescs.CallGraph.Nodes[fn]
+		if node == nil || len(node.In) == 0 {
+			return false
+		}
+		for _, edge := range node.In {
+			if edge.Site == nil {
+				return false
+			}
+			if _, ok := edge.Site.(*ssa.Go); ok {
+				return false
+			}
+			if !held.at(edge.Site)[lock] {
+				return false
+			}
+		}
+		return true
+	}
+
+	for _, field := range fields {
+		// associate the field with the lock held most often while
+		// accessing it
+		counts := map[string]int{}
+		locked := 0
+		for _, acc := range accesses[field] {
+			locks := structLocks(field, acc)
+			if len(locks) == 0 {
+				continue
+			}
+			locked++
+			for _, lock := range locks {
+				counts[lock]++
+			}
+		}
+		guard := ""
+		for lock, n := range counts {
+			if n > counts[guard] || (n == counts[guard] && lock < guard) {
+				guard = lock
+			}
+		}
+		if guard == "" || counts[guard] < minGuardAccesses ||
+			float64(counts[guard]) < minGuardRatio*float64(locked) {
+			continue
+		}
+
+		reported := map[*ssa.Function]bool{}
+		for _, acc := range accesses[field] {
+			locks := structLocks(field, acc)
+			if len(locks) == 0 || acc.held[guard] || reported[acc.fn] {
+				continue
+			}
+			if heldByCallers(acc.fn, guard) {
+				continue
+			}
+			reported[acc.fn] = true
+			j.Errorf(acc.addr, "field %s is accessed holding %s, but it is guarded by %s, which is held at %d of %d locked accesses",
+				field, strings.Join(locks, ", "), guard, counts[guard], locked)
+		}
+	}
+}
+
+// isMethodCallOn reports whether common calls a method of the named
+// type, e.g. "time.Timer", with v as its receiver.
+func isMethodCallOn(common *ssa.CallCommon, v ssa.Value, typ string) bool {
+	callee := common.StaticCallee()
+	if callee == nil || callee.Signature.Recv() == nil || len(common.Args) == 0 {
+		return false
+	}
+	if common.Args[0] != v {
+		return false
+	}
+	return types.TypeString(Dereference(callee.Signature.Recv().Type()), nil) == typ
+}
+
+// receivesFromField reports whether v is a receive from the channel
+// stored in a field of x, such as <-t.C.
+func receivesFromField(v ssa.Value, x ssa.Value) bool {
+	recv, ok := v.(*ssa.UnOp)
+	if !ok || recv.Op != token.ARROW {
+		return false
+	}
+	return isFieldOf(recv.X, x)
+}
+
+// isFieldOf reports whether v is a load of a field of x.
+func isFieldOf(v ssa.Value, x ssa.Value) bool {
+	load, ok := v.(*ssa.UnOp)
+	if !ok || load.Op != token.MUL {
+		return false
+	}
+	fa, ok := load.X.(*ssa.FieldAddr)
+	return ok && fa.X == x
+}
+
+// selectCaseBlock returns the block that runs the body of the i'th
+// case of sel, or nil if it can't be found.
+func selectCaseBlock(sel *ssa.Select, i int) *ssa.BasicBlock {
+	for _, ref := range *sel.Referrers() {
+		ex, ok := ref.(*ssa.Extract)
+		if !ok || ex.Index != 0 {
+			continue
+		}
+		for _, ref := range *ex.Referrers() {
+			cmp, ok := ref.(*ssa.BinOp)
+			if !ok || cmp.Op != token.EQL {
+				continue
+			}
+			k, ok := cmp.Y.(*ssa.Const)
+			if !ok || k.Int64() != int64(i) {
+				continue
+			}
+			for _, ref := range *cmp.Referrers() {
+				if ifInstr, ok := ref.(*ssa.If); ok {
+					return ifInstr.Block().Succs[0]
+				}
+			}
+		}
+	}
+	return nil
+}
+
+func (c *Checker) CheckTimerStop(j *lint.Job) {
+	for _, ssafn := range c.checkedFuncs(j) {
+		for _, block := range ssafn.Blocks {
+			for _, ins := range block.Instrs {
+				call, ok := ins.(*ssa.Call)
+				if !ok {
+					continue
+				}
+				var kind, typ string
+				switch {
+				case IsCallTo(call.Common(), "time.NewTimer"):
+					kind, typ = "timer", "time.Timer"
+				case IsCallTo(call.Common(), "time.NewTicker"):
+					kind, typ = "ticker", "time.Ticker"
+				default:
+					continue
+				}
+
+				// A timer or ticker that is stored, returned or
+				// handed to another function may be stopped there.
+				if escapes(call, func(common *ssa.CallCommon) bool {
+					return isMethodCallOn(common, call, typ)
+				}) {
+					continue
+				}
+
+				// blocks of select cases receiving from the
+				// timer's channel
+				drained := map[*ssa.BasicBlock]bool{}
+				for _, b := range ssafn.Blocks {
+					for _, ins := range b.Instrs {
+						sel, ok := ins.(*ssa.Select)
+						if !ok || kind != "timer" {
+							continue
+						}
+						for i, st := range sel.States {
+							if st.Dir != types.RecvOnly || !isFieldOf(st.Chan, call) {
+								continue
+							}
+							if b := selectCaseBlock(sel, i); b != nil {
+								drained[b] = true
+							}
+						}
+					}
+				}
+
+				leak := leakPath(call, func(ins ssa.Instruction) bool {
+					if drained[ins.Block()] && ins == ins.Block().Instrs[0] {
+						return true
+					}
+					switch ins := ins.(type) {
+					case ssa.CallInstruction:
+						return isMethodCallOn(ins.Common(), call, typ) &&
+							ins.Common().StaticCallee().Name() == "Stop"
+					case *ssa.UnOp:
+						// a timer that has fired and whose
+						// channel was drained is released
+						return kind == "timer" && receivesFromField(ins, call)
+					}
+					return false
+				})
+				if leak == nil {
+					continue
+				}
+
+				name := kind
+				if v := varName(call); v != "" {
+					name = kind + " " + v
+				}
+				if leak == ssa.Instruction(call) {
+					j.Errorf(call, "%s is never stopped before it is created again in the next loop iteration", name)
+				} else if leak.Pos().IsValid() {
+					j.Errorf(call, "%s is never stopped on the path returning at %v", name, j.Program.DisplayPosition(leak.Pos()))
+				} else {
+					j.Errorf(call, "%s is never stopped on the path reaching the end of the function", name)
+				}
+			}
+		}
+	}
+}
+
+// isInitFunc reports whether fn is a package's init function, either
+// a declared one or the synthetic package initializer.
+func isInitFunc(fn *ssa.Function) bool {
+	if fn.Parent() != nil || fn.Signature.Recv() != nil || fn.Pkg == nil {
+		return false
+	}
+	return fn.Name() == "init" || strings.HasPrefix(fn.Name(), "init#")
+}
+
+func (c *Checker) CheckGoInInit(j *lint.Job) {
+	// waitGroupCalls returns the WaitGroups method is called on in
+	// fn, and whether all of them could be resolved.
+	waitGroupCalls := func(fn *ssa.Function, method string) ([]ssa.CallInstruction, []valueKey, bool) {
+		var calls []ssa.CallInstruction
+		var keys []valueKey
+		resolved := true
+		for _, block := range fn.Blocks {
+			for _, ins := range block.Instrs {
+				call, ok := ins.(ssa.CallInstruction)
+				if !ok || !IsCallTo(call.Common(), "(*sync.WaitGroup)."+method) {
+					continue
+				}
+				ks, ok := c.valueKeys(call.Common().Args[0])
+				resolved = resolved && ok
+				calls = append(calls, call)
+				keys = append(keys, ks...)
+			}
+		}
+		return calls, keys, resolved
+	}
+
+	for _, ssafn := range c.checkedFuncs(j) {
+		if !isInitFunc(ssafn) {
+			continue
+		}
+		for _, block := range ssafn.Blocks {
+			for _, ins := range block.Instrs {
+				g, ok := ins.(*ssa.Go)
+				if !ok {
+					continue
+				}
+
+				// The goroutine finishes before main if init
+				// waits for a WaitGroup it marks as done.
+				waited := false
+				if callee := g.Common().StaticCallee(); callee != nil {
+					_, done, resolved := waitGroupCalls(callee, "Done")
+					waits, _, _ := waitGroupCalls(ssafn, "Wait")
+					for _, wait := range waits {
+						if !pathAvoiding(g, wait, func(ssa.Instruction) bool { return false }) {
+							continue
+						}
+						keys, ok := c.valueKeys(wait.Common().Args[0])
+						if !resolved || !ok || sharesObject(keys, done) {
+							waited = true
+							break
+						}
+					}
+				}
+				if waited {
+					continue
+				}
+				j.Errorf(g, "goroutine started in init may still be running when main starts; consider starting it from an explicit initialization function")
+			}
+		}
+	}
+}
+
+// onlyLoaded reports whether every referrer of the address v loads
+// from it, i.e. v is never written to or taken elsewhere.
+func onlyLoaded(v ssa.Value) bool {
+	refs := v.Referrers()
+	if refs == nil {
+		return false
+	}
+	for _, ref := range *refs {
+		if _, ok := ref.(*ssa.DebugRef); ok {
+			continue
+		}
+		load, ok := ref.(*ssa.UnOp)
+		if !ok || load.Op != token.MUL {
+			return false
+		}
+	}
+	return true
+}
+
+func (c *Checker) CheckNilSelect(j *lint.Job) {
+	// fields and globals that may be assigned somewhere in the
+	// program. Globals don't track their referrers, so their uses
+	// are found through the operands of all instructions.
+	assigned := map[*types.Var]bool{}
+	assignedGlobals := map[*ssa.Global]bool{}
+	var ops []*ssa.Value
+	for _, fn := range j.Program.AllFunctions {
+		for _, block := range fn.Blocks {
+			for _, ins := range block.Instrs {
+				if fa, ok := ins.(*ssa.FieldAddr); ok && !onlyLoaded(fa) {
+					field := Dereference(fa.X.Type()).Underlying().(*types.Struct).Field(fa.Field)
+					assigned[field.Origin()] = true
+				}
+				switch ins := ins.(type) {
+				case *ssa.DebugRef:
+					continue
+				case *ssa.UnOp:
+					if ins.Op == token.MUL {
+						continue
+					}
+				}
+				ops = ins.Operands(ops[:0])
+				for _, op := range ops {
+					if g, ok := (*op).(*ssa.Global); ok {
+						assignedGlobals[g] = true
+					}
+				}
+			}
+		}
+	}
+
+	// nilChan returns why ch is always nil, or false if it may not
+	// be.
+	nilChan := func(ch ssa.Value) (string, bool) {
+		if ks, ok := consts(ch, nil, nil); ok {
+			for _, k := range ks {
+				if !k.IsNil() {
+					return "", false
+				}
+			}
+			return "nil", true
+		}
+		load, ok := ch.(*ssa.UnOp)
+		if !ok || load.Op != token.MUL {
+			return "", false
+		}
+		switch addr := load.X.(type) {
+		case *ssa.FieldAddr:
+			field := Dereference(addr.X.Type()).Underlying().(*types.Struct).Field(addr.Field)
+			if assigned[field.Origin()] {
+				return "", false
+			}
+			return fmt.Sprintf("field %s is never assigned", fieldName(addr)), true
+		case *ssa.Global:
+			if assignedGlobals[addr] {
+				return "", false
+			}
+			return fmt.Sprintf("variable %s is never assigned", addr.Name()), true
+		}
+		return "", false
+	}
+
+	for _, ssafn := range c.checkedFuncs(j) {
+		for _, block := range ssafn.Blocks {
+			for _, ins := range block.Instrs {
+				sel, ok := ins.(*ssa.Select)
+				if !ok || !sel.Blocking || len(sel.States) == 0 {
+					continue
+				}
+				var reasons []string
+				seen := map[string]bool{}
+				for _, st := range sel.States {
+					why, ok := nilChan(st.Chan)
+					if !ok {
+						reasons = nil
+						break
+					}
+					if !seen[why] {
+						seen[why] = true
+						reasons = append(reasons, why)
+					}
+				}
+				if reasons == nil {
+					continue
+				}
+				j.Errorf(sel, "select blocks forever: all of its channels are nil (%s)", strings.Join(reasons, ", "))
+			}
+		}
+	}
+}
+
+func (c *Checker) CheckWaitgroupWithoutWait(j *lint.Job) {
+	type usage struct {
+		first    *ssa.Call // first Add, or Done if there is no Add
+		hasAdd   bool
+		waits    []*ssa.Function // functions calling Wait
+		resolved bool
+	}
+	usages := map[valueKey]*usage{}
+	var keys []valueKey
+
+	// Wait may be called in any package, but we only report
+	// WaitGroups used in the packages being checked.
+	initial := map[*ssa.Function]bool{}
+	for _, fn := range c.checkedFuncs(j) {
+		initial[fn] = true
+	}
+	for _, fn := range j.Program.AllFunctions {
+		for _, block := range fn.Blocks {
+			for _, ins := range block.Instrs {
+				call, ok := ins.(ssa.CallInstruction)
+				if !ok {
+					continue
+				}
+				var method string
+				switch {
+				case IsCallTo(call.Common(), "(*sync.WaitGroup).Add"):
+					method = "Add"
+				case IsCallTo(call.Common(), "(*sync.WaitGroup).Done"):
+					method = "Done"
+				case IsCallTo(call.Common(), "(*sync.WaitGroup).Wait"):
+					method = "Wait"
+				default:
+					continue
+				}
+				wgs, resolved := c.valueKeys(call.Common().Args[0])
+				for _, k := range wgs {
+					u, ok := usages[k]
+					if !ok {
+						u = &usage{resolved: true}
+						usages[k] = u
+						keys = append(keys, k)
+					}
+					u.resolved = u.resolved && resolved
+					switch method {
+					case "Wait":
+						u.waits = append(u.waits, fn)
+					case "Add", "Done":
+						site, ok := call.(*ssa.Call)
+						if !ok || !initial[fn] {
+							continue
+						}
+						if u.first == nil || (method == "Add" && !u.hasAdd) {
+							u.first = site
+						}
+						if method == "Add" {
+							u.hasAdd = true
+						}
+					}
+				}
+			}
+		}
+	}
+
+	// waitReached reports whether Wait is called on the WaitGroup
+	// identified by k somewhere it can be reached. A local WaitGroup
+	// has to be waited on in the function declaring it or in its
+	// callees; a Wait in a closure that is never called doesn't
+	// count.
+	waitReached := func(k valueKey, u *usage) bool {
+		alloc, ok := k.v.(*ssa.Alloc)
+		if !ok || k.field != "" {
+			return len(u.waits) > 0
+		}
+		reachable := c.reachableFuncs([]*ssa.Function{alloc.Parent()}, true)
+		for _, fn := range u.waits {
+			if reachable[fn] {
+				return true
+			}
+		}
+		return false
+	}
+
+	for _, k := range keys {
+		u := usages[k]
+		if !u.resolved || u.first == nil || waitReached(k, u) {
+			continue
+		}
+		what := "WaitGroup"
+		if name := k.name(); name != "" {
+			what += " " + name
+		}
+		if k.pos().IsValid() {
+			what += fmt.Sprintf(" (declared at %v)", j.Program.DisplayPosition(k.pos()))
+		}
+		if len(u.waits) > 0 {
+			j.Errorf(u.first, "%s is used with Add and Done but Wait is only called in %s, which is never reached", what, u.waits[0].Name())
+			continue
+		}
+		j.Errorf(u.first, "%s is used with Add and Done but Wait is never called on it", what)
+	}
+}
+
+// CheckErrgroupWithoutWait flags errgroup.Groups whose goroutines are
+// started with Go but that are never waited on, or not waited on
+// before some return, and calls of Wait whose error is dropped.
+func (c *Checker) CheckErrgroupWithoutWait(j *lint.Job) {
+	const (
+		goMethod   = "(*golang.org/x/sync/errgroup.Group).Go"
+		waitMethod = "(*golang.org/x/sync/errgroup.Group).Wait"
+	)
+	type usage struct {
+		gos      []*ssa.Call
+		waits    []ssa.CallInstruction
+		resolved bool
+	}
+	usages := map[valueKey]*usage{}
+	var keys []valueKey
+
+	initial := map[*ssa.Function]bool{}
+	for _, fn := range c.checkedFuncs(j) {
+		initial[fn] = true
+	}
+	for _, fn := range j.Program.AllFunctions {
+		for _, block := range fn.Blocks {
+			for _, ins := range block.Instrs {
+				call, ok := ins.(ssa.CallInstruction)
+				if !ok {
+					continue
+				}
+				isGo := IsCallTo(call.Common(), goMethod)
+				if !isGo && !IsCallTo(call.Common(), waitMethod) {
+					continue
+				}
+				if site, ok := call.(*ssa.Call); ok && !isGo && initial[fn] {
+					if refs := site.Referrers(); refs != nil && len(FilterDebug(*refs)) == 0 {
+						j.Errorf(site, "the error returned by Wait is discarded; it is the first error returned by the group's goroutines")
+					}
+				}
+				groups, resolved := c.valueKeys(call.Common().Args[0])
+				for _, k := range groups {
+					u, ok := usages[k]
+					if !ok {
+						u = &usage{resolved: true}
+						usages[k] = u
+						keys = append(keys, k)
+					}
+					u.resolved = u.resolved && resolved
+					if !isGo {
+						u.waits = append(u.waits, call)
+						continue
+					}
+					if site, ok := call.(*ssa.Call); ok && initial[fn] {
+						u.gos = append(u.gos, site)
+					}
+				}
+			}
+		}
+	}
+
+	for _, k := range keys {
+		u := usages[k]
+		if !u.resolved || len(u.gos) == 0 {
+			continue
+		}
+		what := "errgroup.Group"
+		if name := k.name(); name != "" {
+			what += " " + name
+		}
+		if k.pos().IsValid() {
+			what += fmt.Sprintf(" (declared at %v)", j.Program.DisplayPosition(k.pos()))
+		}
+
+		var waitFns []*ssa.Function
+		for _, w := range u.waits {
+			waitFns = append(waitFns, w.Parent())
+		}
+		reached := len(waitFns) > 0
+		if alloc, ok := k.v.(*ssa.Alloc); ok && k.field == "" {
+			// A local group has to be waited on by the function
+			// declaring it or its callees.
+			reachable := c.reachableFuncs([]*ssa.Function{alloc.Parent()}, true)
+			reached = false
+			for _, fn := range waitFns {
+				if reachable[fn] {
+					reached = true
+					break
+				}
+			}
+		}
+		if !reached {
+			j.Errorf(u.gos[0], "%s is used with Go but Wait is never called on it; its goroutines leak and their errors are lost", what)
+			continue
+		}
+
+		// Where the group is only waited on by the function starting
+		// its goroutines, every return after Go has to pass through
+		// Wait. A deferred Wait covers all of them.
+		for _, site := range u.gos {
+			fn := site.Parent()
+			local := true
+			for _, w := range u.waits {
+				if _, ok := w.(*ssa.Defer); ok || w.Parent() != fn {
+					local = false
+					break
+				}
+			}
+			if !local {
+				continue
+			}
+			isWait := func(ins ssa.Instruction) bool {
+				for _, w := range u.waits {
+					if ins == w {
+						return true
+					}
+				}
+				return false
+			}
+		exits:
+			for _, b := range fn.Blocks {
+				for _, ins := range b.Instrs {
+					ret, ok := ins.(*ssa.Return)
+					if !ok || !pathAvoiding(site, ret, isWait) {
+						continue
+					}
+					pos := ret.Pos()
+					if !pos.IsValid() {
+						// the implicit return at the end of the
+						// function
+						pos = fn.Syntax().End()
+					}
+					j.Errorf(site, "%s is not waited on before returning at %v", what, j.Program.DisplayPosition(pos))
+					break exits
+				}
+			}
+		}
+	}
+}
+
+// CheckNonTerminatingGoroutine flags goroutines that run a loop with
+// no way out: no break or return leaves it, and it doesn't operate on
+// channels, so nothing can tell it to stop.
+func (c *Checker) CheckNonTerminatingGoroutine(j *lint.Job) {
+	// communicates reports whether ins operates on a channel or
+	// otherwise may end the goroutine.
+	communicates := func(ins ssa.Instruction) bool {
+		switch ins := ins.(type) {
+		case *ssa.Send, *ssa.Select:
+			return true
+		case *ssa.UnOp:
+			return ins.Op == token.ARROW
+		case ssa.CallInstruction:
+			common := ins.Common()
+			if common.IsInvoke() {
+				return common.Method.Name() == "Done" && IsType(common.Value.Type(), "context.Context")
+			}
+			return IsCallTo(common, "runtime.Goexit") || IsCallTo(common, "(context.Context).Done")
+		}
+		return false
+	}
+
+	for _, ssafn := range c.checkedFuncs(j) {
+		for _, block := range ssafn.Blocks {
+			for _, ins := range block.Instrs {
+				g, ok := ins.(*ssa.Go)
+				if !ok {
+					continue
+				}
+				fn := g.Common().StaticCallee()
+				if fn == nil || len(fn.Blocks) == 0 {
+					continue
+				}
+			loops:
+				for _, loop := range c.funcDescs.Loops(fn) {
+					for b := range loop {
+						// a block without successors returns or
+						// panics
+						if len(b.Succs) == 0 {
+							continue loops
+						}
+						for _, succ := range b.Succs {
+							if !loop[succ] {
+								continue loops
+							}
+						}
+						for _, ins := range b.Instrs {
+							if communicates(ins) {
+								continue loops
+							}
+						}
+					}
+					j.Errorf(g, "the goroutine started here may never terminate: it runs a loop that neither exits nor operates on a channel, so it can't be stopped")
+					break
+				}
+			}
+		}
+	}
+}
+
+// chanFieldKey identifies a channel loaded from a struct field, as in
+// s.sem <- struct{}{}, by the field.
+func chanFieldKey(v ssa.Value) (string, bool) {
+	load, ok := v.(*ssa.UnOp)
+	if !ok || load.Op != token.MUL {
+		return "", false
+	}
+	fa, ok := load.X.(*ssa.FieldAddr)
+	if !ok {
+		return "", false
+	}
+	return fieldName(fa), true
+}
+
+// semaphoreOps returns a lockOpFunc for channels in sems used as
+// semaphores: sending acquires them, receiving releases them.
+func semaphoreOps(sems map[string]bool) lockOpFunc {
+	return func(ins ssa.Instruction) (string, lockEvent) {
+		switch ins := ins.(type) {
+		case *ssa.Send:
+			if k, ok := chanFieldKey(ins.Chan); ok && sems[k] {
+				return k, acquire
+			}
+		case *ssa.UnOp:
+			if ins.Op != token.ARROW {
+				break
+			}
+			if k, ok := chanFieldKey(ins.X); ok && sems[k] {
+				return k, release
+			}
+		}
+		return "", noLockEvent
+	}
+}
+
+// findSemaphores returns the channel fields used as semaphores: those
+// of type chan struct{} that a function both sends to and, directly
+// or in one of its closures, receives from.
+func findSemaphores(fns []*ssa.Function) map[string]bool {
+	sems := map[string]bool{}
+	for _, fn := range fns {
+		sends := map[string]bool{}
+		recvs := map[string]bool{}
+		for _, f := range append([]*ssa.Function{fn}, fn.AnonFuncs...) {
+			for _, block := range f.Blocks {
+				for _, ins := range block.Instrs {
+					switch ins := ins.(type) {
+					case *ssa.Send:
+						k, ok := chanFieldKey(ins.Chan)
+						if !ok {
+							continue
+						}
+						elem := ins.Chan.Type().Underlying().(*types.Chan).Elem()
+						if st, ok := elem.Underlying().(*types.Struct); ok && st.NumFields() == 0 {
+							sends[k] = true
+						}
+					case *ssa.UnOp:
+						if k, ok := chanFieldKey(ins.X); ok && ins.Op == token.ARROW {
+							recvs[k] = true
+						}
+					}
+				}
+			}
+		}
+		for k := range sends {
+			if recvs[k] {
+				sems[k] = true
+			}
+		}
+	}
+	return sems
+}
+
+func sortedKeys(m map[string]bool) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func (c *Checker) CheckSemaphoreAndMutex(j *lint.Job) {
+	sems := findSemaphores(c.checkedFuncs(j))
+	if len(sems) == 0 {
+		return
+	}
+	semOps := semaphoreOps(sems)
+	mutexOps := c.callLockOps(fieldLockKey)
+	ops := func(ins ssa.Instruction) (string, lockEvent) {
+		if k, ev := semOps(ins); ev != noLockEvent {
+			return k, ev
+		}
+		return mutexOps(ins)
+	}
+
+	type pair struct{ sem, mutex string }
+	type order struct {
+		pair
+		semFirst bool
+	}
+	// the first site, by position, acquiring the second primitive of
+	// a pair while holding the first, for either order
+	orders := map[order]ssa.Instruction{}
+	var pairs []pair
+	// the first access to a field, by position, while holding both
+	// primitives
+	dual := map[string]ssa.Instruction{}
+	dualPair := map[string]pair{}
+	var fields []string
+
+	for _, ssafn := range c.checkedFuncs(j) {
+		ls := computeLockSets(ssafn, ops)
+		for _, block := range ssafn.Blocks {
+			for _, ins := range block.Instrs {
+				if k, ev := ops(ins); ev == acquire {
+					for _, held := range sortedKeys(ls.at(ins).must) {
+						if sems[held] == sems[k] {
+							continue
+						}
+						o := order{pair{held, k}, true}
+						if sems[k] {
+							o = order{pair{k, held}, false}
+						}
+						if prev, ok := orders[o]; ok {
+							if ins.Pos() < prev.Pos() {
+								orders[o] = ins
+							}
+							continue
+						}
+						if _, ok := orders[order{o.pair, !o.semFirst}]; !ok {
+							pairs = append(pairs, o.pair)
+						}
+						orders[o] = ins
+					}
+					continue
+				}
+
+				fa, ok := ins.(*ssa.FieldAddr)
+				if !ok {
+					continue
+				}
+				T := Dereference(fa.X.Type()).Underlying().(*types.Struct)
+				name := fieldName(fa)
+				if isLockType(T.Field(fa.Field).Type()) || sems[name] {
+					continue
+				}
+				if prev, ok := dual[name]; ok && prev.Pos() < ins.Pos() {
+					continue
+				}
+				var p pair
+				for _, held := range sortedKeys(ls.at(ins).must) {
+					if sems[held] {
+						p.sem = held
+					} else {
+						p.mutex = held
+					}
+				}
+				if p.sem != "" && p.mutex != "" {
+					if _, ok := dual[name]; !ok {
+						fields = append(fields, name)
+					}
+					dual[name] = ins
+					dualPair[name] = p
+				}
+			}
+		}
+	}
+
+	for _, p := range pairs {
+		semFirst, ok1 := orders[order{p, true}]
+		mutexFirst, ok2 := orders[order{p, false}]
+		if !ok1 || !ok2 {
+			continue
+		}
+		j.Errorf(semFirst, "mutex %s is acquired while holding semaphore %s, but the opposite order is used at %v; this can deadlock",
+			p.mutex, p.sem, j.Program.DisplayPosition(mutexFirst.Pos()))
+		j.Errorf(mutexFirst, "semaphore %s is acquired while holding mutex %s, but the opposite order is used at %v; this can deadlock",
+			p.sem, p.mutex, j.Program.DisplayPosition(semFirst.Pos()))
+	}
+	for _, name := range fields {
+		p := dualPair[name]
+		j.Errorf(dual[name], "field %s is guarded by both semaphore %s and mutex %s; one of them is redundant", name, p.sem, p.mutex)
+	}
+}
+
+// errorEscapes reports whether the error v is passed on in any way:
+// sent on a channel, stored, handed to a function, captured by a
+// closure or panicked with. Comparing it against nil isn't enough.
+func errorEscapes(v ssa.Value, seen map[ssa.Value]bool) bool {
+	if seen[v] {
+		return false
+	}
+	seen[v] = true
+	refs := v.Referrers()
+	if refs == nil {
+		return false
+	}
+	for _, ref := range *refs {
+		switch ref := ref.(type) {
+		case *ssa.Send, *ssa.Store, *ssa.MapUpdate, *ssa.Panic,
+			*ssa.MakeClosure, ssa.CallInstruction:
+			return true
+		case *ssa.MakeInterface, *ssa.ChangeInterface, *ssa.TypeAssert, *ssa.Phi:
+			if errorEscapes(ref.(ssa.Value), seen) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// ignoredErrorCall reports whether common calls a function whose
+// error is conventionally ignored.
+func ignoredErrorCall(common *ssa.CallCommon) bool {
+	name := CallName(common)
+	for _, prefix := range []string{"fmt.Print", "fmt.Fprint", "(*bytes.Buffer).", "(*strings.Builder)."} {
+		if strings.HasPrefix(name, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+func (c *Checker) CheckGoroutineDroppedError(j *lint.Job) {
+	isError := func(T types.Type) bool { return IsType(T, "error") }
+
+	checkBody := func(fn *ssa.Function) {
+		for _, block := range fn.Blocks {
+			for _, ins := range block.Instrs {
+				call, ok := ins.(*ssa.Call)
+				if !ok || ignoredErrorCall(call.Common()) {
+					continue
+				}
+				// find the error result, if any
+				var err ssa.Value
+				switch T := call.Type().(type) {
+				case *types.Tuple:
+					if T.Len() == 0 || !isError(T.At(T.Len()-1).Type()) {
+						continue
+					}
+					for _, ref := range *call.Referrers() {
+						if ex, ok := ref.(*ssa.Extract); ok && ex.Index == T.Len()-1 {
+							err = ex
+						}
+					}
+				default:
+					if !isError(T) {
+						continue
+					}
+					err = call
+				}
+				if err != nil && errorEscapes(err, map[ssa.Value]bool{}) {
+					continue
+				}
+				name := CallName(call.Common())
+				if name == "" {
+					name = "the call"
+				}
+				j.Errorf(call, "error returned by %s in a goroutine is dropped; send it on a channel, store it or log it if that isn't intended", name)
+			}
+		}
+	}
+
+	for _, ssafn := range c.checkedFuncs(j) {
+		for _, block := range ssafn.Blocks {
+			for _, ins := range block.Instrs {
+				g, ok := ins.(*ssa.Go)
+				if !ok {
+					continue
+				}
+				res := g.Common().Signature().Results()
+				if res.Len() > 0 && isError(res.At(res.Len()-1).Type()) {
+					name := CallName(g.Common())
+					if name == "" {
+						name = "the function"
+					}
+					j.Errorf(g, "error returned by %s is dropped by the go statement", name)
+					continue
+				}
+				// only look into function literals; named
+				// functions may be called synchronously, too
+				if fn := g.Common().StaticCallee(); fn != nil && fn.Parent() != nil {
+					checkBody(fn)
+				}
+			}
+		}
+	}
+}
+
+// sharesObject reports whether the resolved objects a and b, such as
+// WaitGroups, overlap.
+func sharesObject(a, b []valueKey) bool {
+	for _, ka := range a {
+		for _, kb := range b {
+			if ka == kb {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (c *Checker) CheckWaitgroupAccounting(j *lint.Job) {
+	for _, ssafn := range c.checkedFuncs(j) {
+		var adds []*ssa.Call
+		var gos []*ssa.Go
+		for _, block := range ssafn.Blocks {
+			for _, ins := range block.Instrs {
+				switch ins := ins.(type) {
+				case *ssa.Call:
+					if _, ok := c.waitGroupCall(ins, "Add"); ok {
+						adds = append(adds, ins)
+					}
+				case *ssa.Go:
+					gos = append(gos, ins)
+				}
+			}
+		}
+		if len(adds) == 0 || len(gos) == 0 {
+			continue
+		}
+
+		// Only straight-line fan-out can be accounted for: constant
+		// deltas and no loops around the Adds or go statements.
+		var wgs []valueKey
+		var delta int64
+		accountable := true
+		for _, add := range adds {
+			k, ok := add.Common().Args[1].(*ssa.Const)
+			keys, _ := c.waitGroupCall(add, "Add")
+			if !ok || c.isInLoop(add.Block()) || (wgs != nil && !sharesObject(wgs, keys)) {
+				accountable = false
+				break
+			}
+			wgs = keys
+			delta += k.Int64()
+		}
+		if !accountable {
+			continue
+		}
+
+		var dones int64
+		for _, g := range gos {
+			fn := g.Common().StaticCallee()
+			if fn == nil {
+				continue
+			}
+			n := 0
+			for _, block := range fn.Blocks {
+				for _, ins := range block.Instrs {
+					if keys, ok := c.waitGroupCall(ins, "Done"); ok && sharesObject(wgs, keys) {
+						n++
+						if c.isInLoop(block) {
+							accountable = false
+						}
+					}
+				}
+			}
+			if n == 0 {
+				continue
+			}
+			if c.isInLoop(g.Block()) {
+				accountable = false
+			}
+			exit := exitWithout(fn, func(ins ssa.Instruction) bool {
+				keys, ok := c.waitGroupCall(ins, "Done")
+				return ok && sharesObject(wgs, keys)
+			})
+			if exit != nil {
+				pos := fn.Pos()
+				if exit.Pos().IsValid() {
+					pos = exit.Pos()
+				}
+				j.Errorf(g, "goroutine may return at %v without calling Done; Wait will block forever",
+					j.Program.DisplayPosition(pos))
+				accountable = false
+			}
+			dones += int64(n)
+		}
+		if !accountable || dones == 0 || dones == delta {
+			continue
+		}
+		j.Errorf(adds[0], "WaitGroup counter is incremented by %d but the goroutines started here decrement it by %d; the counter can't reach zero",
+			delta, dones)
+	}
+}
+
+// CheckNegativeWaitgroup flags calls of WaitGroup.Done that, on some
+// path, decrement the counter more often than constant calls of Add
+// incremented it. The paths of goroutines calling Done are followed
+// from their go statements.
+func (c *Checker) CheckNegativeWaitgroup(j *lint.Job) {
+	type keyed struct {
+		keys []valueKey
+		ok   bool
+	}
+	resolved := map[ssa.Value]keyed{}
+	// wgOp returns the change ins makes to the counter of the
+	// WaitGroup k. known is false if it may change it by an amount
+	// that isn't known.
+	wgOp := func(ins ssa.Instruction, k valueKey) (delta int64, isOp, known bool) {
+		call, ok := ins.(ssa.CallInstruction)
+		if !ok {
+			return 0, false, true
+		}
+		common := call.Common()
+		isDone := IsCallTo(common, "(*sync.WaitGroup).Done")
+		if !isDone && !IsCallTo(common, "(*sync.WaitGroup).Add") {
+			return 0, false, true
+		}
+		r, ok := resolved[common.Args[0]]
+		if !ok {
+			r.keys, r.ok = c.valueKeys(common.Args[0])
+			resolved[common.Args[0]] = r
+		}
+		if !r.ok || len(r.keys) != 1 {
+			// may or may not be k
+			return 0, false, !sharesObject(r.keys, []valueKey{k})
+		}
+		if r.keys[0] != k {
+			return 0, false, true
+		}
+		if isDone {
+			return -1, true, true
+		}
+		n, ok := common.Args[1].(*ssa.Const)
+		if !ok {
+			return 0, true, false
+		}
+		return n.Int64(), true, true
+	}
+
+	for _, ssafn := range c.checkedFuncs(j) {
+		var keys []valueKey
+		for _, block := range ssafn.Blocks {
+			for _, ins := range block.Instrs {
+				call, ok := ins.(*ssa.Call)
+				if !ok || !IsCallTo(call.Common(), "(*sync.WaitGroup).Add") {
+					continue
+				}
+				if ks, ok := c.valueKeys(call.Common().Args[0]); ok && len(ks) == 1 && !sharesObject(keys, ks) {
+					keys = append(keys, ks[0])
+				}
+			}
+		}
+
+		for _, k := range keys {
+			type report struct {
+				done  ssa.Instruction
+				added int64
+			}
+			var reports []report
+			reported := map[ssa.Instruction]bool{}
+			known := true
+			// walk follows the paths of fn starting with the counter
+			// at balance, after Add added added to it, and returns
+			// the lowest balance at any of its returns.
+			walking := map[*ssa.Function]bool{}
+			var walk func(fn *ssa.Function, balance, added int64) int64
+			walk = func(fn *ssa.Function, balance, added int64) int64 {
+				if walking[fn] {
+					known = false
+					return balance
+				}
+				walking[fn] = true
+				defer delete(walking, fn)
+				type state struct {
+					b              *ssa.BasicBlock
+					balance, added int64
+					deferred       int
+				}
+				seen := map[state]bool{}
+				lowest, returns := balance, false
+				var visit func(b *ssa.BasicBlock, balance, added int64, deferred []*ssa.Defer)
+				visit = func(b *ssa.BasicBlock, balance, added int64, deferred []*ssa.Defer) {
+					s := state{b, balance, added, len(deferred)}
+					if seen[s] || !known {
+						return
+					}
+					seen[s] = true
+					for _, ins := range b.Instrs {
+						if g, ok := ins.(*ssa.Go); ok {
+							callee := g.Common().StaticCallee()
+							if callee == nil {
+								continue
+							}
+							after := walk(callee, balance, added)
+							if after != balance && c.isInLoop(b) {
+								// started an unknown number of times
+								known = false
+								return
+							}
+							balance = after
+							continue
+						}
+						delta, isOp, ok := wgOp(ins, k)
+						if !ok || isOp && c.isInLoop(b) {
+							// not statically determinable
+							known = false
+							return
+						}
+						if _, ok := ins.(*ssa.Return); ok {
+							for i := len(deferred) - 1; i >= 0; i-- {
+								balance--
+								if balance < 0 {
+									if !reported[deferred[i]] {
+										reported[deferred[i]] = true
+										reports = append(reports, report{deferred[i], added})
+									}
+									return
+								}
+							}
+							if !returns || balance < lowest {
+								lowest, returns = balance, true
+							}
+							return
+						}
+						if !isOp {
+							continue
+						}
+						if d, ok := ins.(*ssa.Defer); ok {
+							if delta != -1 {
+								known = false
+								return
+							}
+							deferred = append(deferred[:len(deferred):len(deferred)], d)
+							continue
+						}
+						balance += delta
+						if delta > 0 {
+							added += delta
+						}
+						if balance < 0 {
+							if !reported[ins] {
+								reported[ins] = true
+								reports = append(reports, report{ins, added})
+							}
+							return
+						}
+					}
+					for _, succ := range b.Succs {
+						visit(succ, balance, added, deferred)
+					}
+				}
+				if len(fn.Blocks) > 0 {
+					visit(fn.Blocks[0], balance, added, nil)
+				}
+				return lowest
+			}
+			walk(ssafn, 0, 0)
+			if !known {
+				continue
+			}
+			name := k.name()
+			if name == "" {
+				name = "WaitGroup"
+			}
+			for _, r := range reports {
+				j.Errorf(r.done, "%s.Done is called more often than Add incremented the counter (by %d) on some path; Done panics when the counter goes negative", name, r.added)
+			}
+		}
+	}
+}
+
+// isClose reports whether ins is a call, deferred call or go
+// statement closing a channel, and returns the channel.
+func isClose(ins ssa.Instruction) (ssa.Value, bool) {
+	call, ok := ins.(ssa.CallInstruction)
+	if !ok {
+		return nil, false
+	}
+	if b, ok := call.Common().Value.(*ssa.Builtin); !ok || b.Name() != "close" {
+		return nil, false
+	}
+	return call.Common().Args[0], true
+}
+
+func (c *Checker) CheckDoubleClose(j *lint.Job) {
+	// Closes in different functions aren't compared, which also keeps
+	// the sync.Once idiom, once.Do(func() { close(ch) }), quiet.
+	for _, ssafn := range c.checkedFuncs(j) {
+		type closeSite struct {
+			ins ssa.Instruction
+			key valueKey
+		}
+		var closes []closeSite
+		for _, block := range ssafn.Blocks {
+			for _, ins := range block.Instrs {
+				ch, ok := isClose(ins)
+				if !ok {
+					continue
+				}
+				if _, ok := ins.(*ssa.Go); ok {
+					continue
+				}
+				keys, ok := c.valueKeys(ch)
+				if !ok || len(keys) != 1 {
+					continue
+				}
+				closes = append(closes, closeSite{ins, keys[0]})
+			}
+		}
+
+		name := func(k valueKey) string {
+			if n := k.name(); n != "" {
+				return "channel " + n
+			}
+			return "the channel"
+		}
+		for _, second := range closes {
+			for _, first := range closes {
+				if first.key != second.key {
+					continue
+				}
+				avoid := second.key.redefinedBy
+				_, firstDeferred := first.ins.(*ssa.Defer)
+				_, secondDeferred := second.ins.(*ssa.Defer)
+				if secondDeferred && !firstDeferred {
+					// the deferred close runs when the function
+					// returns, after the other close regardless of
+					// their order
+					if pathAvoiding(first.ins, second.ins, avoid) || pathAvoiding(second.ins, first.ins, avoid) {
+						j.Errorf(second.ins, "%s is closed at %v and closed again by this deferred close when the function returns; closing a closed channel panics",
+							name(second.key), j.Program.DisplayPosition(first.ins.Pos()))
+						break
+					}
+					continue
+				}
+				if firstDeferred && !secondDeferred {
+					continue
+				}
+				if !pathAvoiding(first.ins, second.ins, avoid) {
+					continue
+				}
+				if first.ins == second.ins {
+					j.Errorf(second.ins, "%s is closed in a loop and may be closed again in a later iteration; closing a closed channel panics", name(second.key))
+				} else {
+					j.Errorf(second.ins, "%s may already have been closed at %v; closing a closed channel panics",
+						name(second.key), j.Program.DisplayPosition(first.ins.Pos()))
+				}
+				break
+			}
+		}
+	}
+}
+
+func (c *Checker) CheckSendOnClosed(j *lint.Job) {
+	// Only closes and sends in the same function are compared; the
+	// order of operations in different goroutines can't be known.
+	for _, ssafn := range c.checkedFuncs(j) {
+		type chanOp struct {
+			ins ssa.Instruction
+			key valueKey
+		}
+		keyOf := func(ch ssa.Value) (valueKey, bool) {
+			keys, ok := c.valueKeys(ch)
+			if !ok || len(keys) != 1 {
+				return valueKey{}, false
+			}
+			return keys[0], true
+		}
+		var closes, sends []chanOp
+		for _, block := range ssafn.Blocks {
+			for _, ins := range block.Instrs {
+				switch ins := ins.(type) {
+				case *ssa.Call:
+					ch, ok := isClose(ins)
+					if !ok {
+						continue
+					}
+					if k, ok := keyOf(ch); ok {
+						closes = append(closes, chanOp{ins, k})
+					}
+				case *ssa.Send:
+					if k, ok := keyOf(ins.Chan); ok {
+						sends = append(sends, chanOp{ins, k})
+					}
+				case *ssa.Select:
+					for _, st := range ins.States {
+						if st.Dir != types.SendOnly {
+							continue
+						}
+						if k, ok := keyOf(st.Chan); ok {
+							sends = append(sends, chanOp{ins, k})
+						}
+					}
+				}
+			}
+		}
+
+		for _, send := range sends {
+			for _, cl := range closes {
+				if cl.key != send.key || !pathAvoiding(cl.ins, send.ins, send.key.redefinedBy) {
+					continue
+				}
+				name := "the channel"
+				if n := send.key.name(); n != "" {
+					name = "channel " + n
+				}
+				j.Errorf(send.ins, "sending on %s, which may have been closed at %v; sending on a closed channel panics",
+					name, j.Program.DisplayPosition(cl.ins.Pos()))
+				break
+			}
+		}
+	}
+}
+
+func (c *Checker) CheckReceiveFromClosed(j *lint.Job) {
+	for _, ssafn := range c.checkedFuncs(j) {
+		var closes []*ssa.Call
+		var gos []*ssa.Go
+		for _, block := range ssafn.Blocks {
+			for _, ins := range block.Instrs {
+				switch ins := ins.(type) {
+				case *ssa.Call:
+					if _, ok := isClose(ins); ok {
+						closes = append(closes, ins)
+					}
+				case *ssa.Go:
+					gos = append(gos, ins)
+				}
+			}
+		}
+		if len(closes) == 0 || len(gos) == 0 {
+			continue
+		}
+
+		for _, cl := range closes {
+			ch, _ := isClose(cl)
+			keys, ok := c.valueKeys(ch)
+			if !ok || len(keys) != 1 {
+				continue
+			}
+			key := keys[0]
+			if _, ok := key.v.(*ssa.MakeChan); !ok {
+				// a channel in a field may be another instance's
+				continue
+			}
+			elem := ch.Type().Underlying().(*types.Chan).Elem()
+			if st, ok := elem.Underlying().(*types.Struct); ok && st.NumFields() == 0 {
+				// a signal; receiving from it once it's closed is
+				// what closing it is for
+				continue
+			}
+			for _, g := range gos {
+				if !cl.Block().Dominates(g.Block()) || !pathAvoiding(cl, g, key.redefinedBy) {
+					continue
+				}
+				fn := g.Call.StaticCallee()
+				if fn == nil {
+					continue
+				}
+				for _, block := range fn.Blocks {
+					for _, ins := range block.Instrs {
+						recv, ok := ins.(*ssa.UnOp)
+						if !ok || recv.Op != token.ARROW {
+							continue
+						}
+						// a parameter is the channel passed by g, not
+						// by other callers
+						ch := recv.X
+						for i, p := range fn.Params {
+							if ch == p && i < len(g.Call.Args) {
+								ch = g.Call.Args[i]
+							}
+						}
+						keys, ok := c.valueKeys(ch)
+						if !ok || len(keys) != 1 || keys[0] != key {
+							continue
+						}
+						name := "the channel"
+						if n := key.name(); n != "" {
+							name = "channel " + n
+						}
+						j.Errorf(recv, "receiving from %s, which was closed at %v before the goroutine started at %v; the receive yields the zero value immediately instead of waiting for data",
+							name, j.Program.DisplayPosition(cl.Pos()), j.Program.DisplayPosition(g.Pos()))
+					}
+				}
+			}
+		}
+	}
+}
+
+// chanUses describes how the channel made by a MakeChan is used.
+type chanUses struct {
+	// sends are the sends executed by goroutines.
+	sends []*ssa.Send
+	// spawns are the go statements in the function making the
+	// channel that start goroutines using it.
+	spawns []*ssa.Go
+	// recvs are the receives in the function making the channel,
+	// outside of goroutines, that always receive from it.
+	recvs []ssa.Instruction
+	// maybeRecv is set if the channel is a case of a select
+	// statement with other cases in the function making it.
+	maybeRecv bool
+	// otherSends is set if the channel is sent on outside of
+	// goroutines.
+	otherSends bool
+}
+
+// channelUses follows the channel made by mc through local variables
+// assigned only once and into closures and statically called
+// functions. ok is false if the channel is used in any other way,
+// including receives other than in the function making it, that might
+// lead to it being received from.
+func channelUses(mc *ssa.MakeChan) (uses chanUses, ok bool) {
+	seen := map[ssa.Value]bool{}
+	// visit returns false if v may be received from by an unknown
+	// party. If addr is true, v is the address of a variable
+	// holding the channel.
+	var visit func(v ssa.Value, inGo, addr bool) bool
+
+	// inParent reports whether ref is a use by the function making
+	// the channel, outside of goroutines.
+	inParent := func(ref ssa.Instruction, inGo bool) bool {
+		return !inGo && ref.Parent() == mc.Parent()
+	}
+	// addrUse handles a use of the address of a variable holding
+	// the channel.
+	addrUse := func(ref ssa.Instruction, v ssa.Value, inGo bool) bool {
+		switch ref := ref.(type) {
+		case *ssa.Store:
+			return ref.Addr == v
+		case *ssa.UnOp:
+			return ref.Op == token.MUL && visit(ref, inGo, false)
+		}
+		return false
+	}
+	// chanUse handles a use of the channel itself.
+	chanUse := func(ref ssa.Instruction, v ssa.Value, inGo bool) bool {
+		switch ref := ref.(type) {
+		case *ssa.Send:
+			if ref.X == v {
+				return false
+			}
+			if inGo {
+				uses.sends = append(uses.sends, ref)
+			} else {
+				uses.otherSends = true
+			}
+			return true
+		case *ssa.UnOp:
+			if ref.Op != token.ARROW || !inParent(ref, inGo) {
+				return false
+			}
+			uses.recvs = append(uses.recvs, ref)
+			return true
+		case *ssa.Select:
+			only := true
+			for _, st := range ref.States {
+				if st.Send == v {
+					return false
+				}
+				if st.Chan == v && st.Dir == types.RecvOnly {
+					if !inParent(ref, inGo) {
+						return false
+					}
+				} else {
+					only = false
+				}
+			}
+			if only && ref.Blocking {
+				uses.recvs = append(uses.recvs, ref)
+			} else {
+				uses.maybeRecv = true
+			}
+			return true
+		case *ssa.Store:
+			addr, ok := ref.Addr.(*ssa.Alloc)
+			if !ok || ref.Val != v {
+				return false
+			}
+			if stored, ok := storedOnce(addr); !ok || stored != v {
+				return false
+			}
+			return visit(addr, inGo, true)
+		case ssa.CallInstruction:
+			common := ref.Common()
+			if b, ok := common.Value.(*ssa.Builtin); ok {
+				switch b.Name() {
+				case "close", "len", "cap":
+					return true
+				}
+				return false
+			}
+			callee := common.StaticCallee()
+			if callee == nil || callee.Blocks == nil || common.Value == v {
+				return false
+			}
+			g, isGo := ref.(*ssa.Go)
+			if isGo && inParent(ref, inGo) {
+				uses.spawns = append(uses.spawns, g)
+			}
+			for i, arg := range common.Args {
+				if arg == v && !visit(callee.Params[i], inGo || isGo, false) {
+					return false
+				}
+			}
+			return true
+		}
+		// returned, converted...
+		return false
+	}
+
+	visit = func(v ssa.Value, inGo, addr bool) bool {
+		if seen[v] {
+			return true
+		}
+		seen[v] = true
+		refs := v.Referrers()
+		if refs == nil {
+			return true
+		}
+		for _, ref := range *refs {
+			switch ref := ref.(type) {
+			case *ssa.DebugRef:
+			case *ssa.MakeClosure:
+				fn := ref.Fn.(*ssa.Function)
+				goClosure := false
+				for _, cref := range *ref.Referrers() {
+					if g, ok := cref.(*ssa.Go); ok && g.Call.Value == ref {
+						goClosure = true
+						if inParent(g, inGo) {
+							uses.spawns = append(uses.spawns, g)
+						}
+					}
+				}
+				for i, b := range ref.Bindings {
+					if b == v && !visit(fn.FreeVars[i], inGo || goClosure, addr) {
+						return false
+					}
+				}
+			default:
+				use := chanUse
+				if addr {
+					use = addrUse
+				}
+				if !use(ref, v, inGo) {
+					return false
+				}
+			}
+		}
+		return true
+	}
+	if !visit(mc, false, false) {
+		return chanUses{}, false
+	}
+	return uses, true
+}
+
+// unreceivedSends returns the sends on the channel made by mc that are
+// executed by goroutines, if the channel is provably never received
+// from.
+func unreceivedSends(mc *ssa.MakeChan) []*ssa.Send {
+	uses, ok := channelUses(mc)
+	if !ok || len(uses.recvs) > 0 || uses.maybeRecv {
+		return nil
+	}
+	return uses.sends
+}
+
+// chanDisplayName returns a description of the channel made by mc.
+func chanDisplayName(mc *ssa.MakeChan) string {
+	if n := varName(mc); n != "" {
+		return "channel " + n
+	}
+	if n := storedName(mc); n != "" {
+		return "channel " + n
+	}
+	return "the channel"
+}
+
+func (c *Checker) CheckUnreceivedSend(j *lint.Job) {
+	for _, ssafn := range c.checkedFuncs(j) {
+		for _, block := range ssafn.Blocks {
+			for _, ins := range block.Instrs {
+				mc, ok := ins.(*ssa.MakeChan)
+				if !ok {
+					continue
+				}
+				if k, ok := mc.Size.(*ssa.Const); !ok || k.Int64() != 0 {
+					continue
+				}
+				name := chanDisplayName(mc)
+				for _, send := range unreceivedSends(mc) {
+					j.Errorf(send, "sending on unbuffered %s, which is never received from, blocks the goroutine forever", name)
+				}
+			}
+		}
+	}
+}
+
+func (c *Checker) CheckAbandonedSend(j *lint.Job) {
+	for _, ssafn := range c.checkedFuncs(j) {
+		var rets []*ssa.Return
+		for _, block := range ssafn.Blocks {
+			if n := len(block.Instrs); n > 0 {
+				if ret, ok := block.Instrs[n-1].(*ssa.Return); ok {
+					rets = append(rets, ret)
+				}
+			}
+		}
+		for _, block := range ssafn.Blocks {
+			for _, ins := range block.Instrs {
+				mc, ok := ins.(*ssa.MakeChan)
+				if !ok {
+					continue
+				}
+				if k, ok := mc.Size.(*ssa.Const); !ok || k.Int64() != 0 {
+					continue
+				}
+				uses, ok := channelUses(mc)
+				if !ok || uses.otherSends || len(uses.sends) == 0 || len(uses.spawns) == 0 {
+					continue
+				}
+				if len(uses.recvs) == 0 && !uses.maybeRecv {
+					// never received from; see SA2023
+					continue
+				}
+				recv := func(ins ssa.Instruction) bool {
+					for _, r := range uses.recvs {
+						if ins == r {
+							return true
+						}
+					}
+					return false
+				}
+				// the function may return without receiving the
+				// goroutine's value
+				var spawn *ssa.Go
+				var ret *ssa.Return
+			spawns:
+				for _, g := range uses.spawns {
+					for _, r := range rets {
+						if pathAvoiding(g, r, recv) {
+							spawn, ret = g, r
+							break spawns
+						}
+					}
+				}
+				if spawn == nil {
+					continue
+				}
+				pos := ret.Pos()
+				if !pos.IsValid() {
+					// the implicit return at the end of the
+					// function
+					pos = ssafn.Syntax().End()
+				}
+				at := j.Program.DisplayPosition(pos)
+				name := chanDisplayName(mc)
+				for _, send := range uses.sends {
+					j.Errorf(send, "sending on unbuffered %s blocks the goroutine started at %v forever if %s returns at %v without receiving from it",
+						name, j.Program.DisplayPosition(spawn.Pos()), ssafn.Name(), at)
+				}
+			}
+		}
+	}
+}
+
+// mapAccess returns the map ins reads or writes, if any.
+func mapAccess(ins ssa.Instruction) (m ssa.Value, write bool) {
+	isMap := func(v ssa.Value) bool {
+		_, ok := v.Type().Underlying().(*types.Map)
+		return ok
+	}
+	switch ins := ins.(type) {
+	case *ssa.MapUpdate:
+		return ins.Map, true
+	case *ssa.Lookup:
+		if isMap(ins.X) {
+			return ins.X, false
+		}
+	case *ssa.Range:
+		if isMap(ins.X) {
+			return ins.X, false
+		}
+	case *ssa.Call:
+		if b, ok := ins.Common().Value.(*ssa.Builtin); ok && b.Name() == "delete" {
+			return ins.Common().Args[0], true
+		}
+	}
+	return nil, false
+}
+
+func (c *Checker) CheckConcurrentMap(j *lint.Job) {
+	type access struct {
+		ins   ssa.Instruction
+		write bool
+		maps  []valueKey
+	}
+	// accesses returns the map accesses in fn made without holding
+	// a lock.
+	accesses := func(fn *ssa.Function) []access {
+		var out []access
+		for _, block := range fn.Blocks {
+			for _, ins := range block.Instrs {
+				m, write := mapAccess(ins)
+				if m == nil || len(c.LockState(ins)) > 0 {
+					continue
+				}
+				keys, ok := c.valueKeys(m)
+				if !ok || len(keys) == 0 {
+					continue
+				}
+				out = append(out, access{ins, write, keys})
+			}
+		}
+		return out
+	}
+	describe := func(a access) string {
+		if a.write {
+			return "written"
+		}
+		return "read"
+	}
+
+	for _, ssafn := range c.checkedFuncs(j) {
+		var gos []*ssa.Go
+		for _, block := range ssafn.Blocks {
+			for _, ins := range block.Instrs {
+				if g, ok := ins.(*ssa.Go); ok {
+					gos = append(gos, g)
+				}
+			}
+		}
+		if len(gos) == 0 {
+			continue
+		}
+		parent := accesses(ssafn)
+
+		for _, g := range gos {
+			fn := g.Call.StaticCallee()
+			if fn == nil || fn.Blocks == nil {
+				continue
+			}
+			for _, w := range accesses(fn) {
+				if !w.write {
+					continue
+				}
+				var other *access
+				if c.isInLoop(g.Block()) {
+					// another instance of the same goroutine
+					other = &w
+				}
+				// the spawning function, while the goroutine runs
+				for i := 0; other == nil && i < len(parent); i++ {
+					a := parent[i]
+					if sharesObject(w.maps, a.maps) && pathAvoiding(g, a.ins, isJoin) {
+						other = &a
+					}
+				}
+				// other goroutines running at the same time
+				for _, g2 := range gos {
+					if other != nil {
+						break
+					}
+					fn2 := g2.Call.StaticCallee()
+					if g2 == g || fn2 == nil || fn2 == fn || fn2.Blocks == nil {
+						continue
+					}
+					if !pathAvoiding(g, g2, isJoin) && !pathAvoiding(g2, g, isJoin) {
+						continue
+					}
+					for _, a := range accesses(fn2) {
+						if sharesObject(w.maps, a.maps) {
+							a := a
+							other = &a
+							break
+						}
+					}
+				}
+				if other == nil {
+					continue
+				}
+				name := w.maps[0].name()
+				if name == "" {
+					name = "the map"
+				} else {
+					name = "map " + name
+				}
+				if other.ins == w.ins {
+					j.Errorf(w.ins, "%s is written without holding a lock by every goroutine started at %v; concurrent map writes panic",
+						name, j.Program.DisplayPosition(g.Pos()))
+					continue
+				}
+				j.Errorf(w.ins, "%s is written by the goroutine started at %v and %s at %v without holding a lock; concurrent map access races and may panic",
+					name, j.Program.DisplayPosition(g.Pos()), describe(*other), j.Program.DisplayPosition(other.ins.Pos()))
+			}
+		}
+	}
+}
+
+// variableAccess returns the address of the variable ins reads or
+// writes, if any.
+func variableAccess(ins ssa.Instruction) (addr ssa.Value, write bool) {
+	switch ins := ins.(type) {
+	case *ssa.UnOp:
+		if ins.Op == token.MUL {
+			return ins.X, false
+		}
+	case *ssa.Store:
+		return ins.Addr, true
+	}
+	return nil, false
+}
+
+func (c *Checker) CheckGoroutineRace(j *lint.Job) {
+	type access struct {
+		ins   ssa.Instruction
+		write bool
+		vars  []valueKey
+	}
+	// accesses returns the accesses in fn, made without holding a
+	// lock, of variables that may be shared with other goroutines.
+	// Fields and elements are left out, as are variables that can't
+	// be resolved to the objects they denote.
+	accesses := func(fn *ssa.Function) []access {
+		var out []access
+		for _, block := range fn.Blocks {
+			for _, ins := range block.Instrs {
+				addr, write := variableAccess(ins)
+				switch addr := addr.(type) {
+				case *ssa.Alloc:
+					if !addr.Heap {
+						// not shared
+						continue
+					}
+				case *ssa.Global, *ssa.FreeVar, *ssa.Parameter:
+				default:
+					continue
+				}
+				if len(c.LockState(ins)) > 0 {
+					continue
+				}
+				keys, ok := c.valueKeys(addr)
+				if !ok || len(keys) == 0 {
+					continue
+				}
+				for _, k := range keys {
+					switch k.v.(type) {
+					case *ssa.Alloc, *ssa.Global:
+					default:
+						ok = false
+					}
+				}
+				if ok {
+					out = append(out, access{ins, write, keys})
+				}
+			}
+		}
+		return out
+	}
+	// name names the variable both a and b access, the one declared
+	// first if there are several, so that the message doesn't depend
+	// on the order callers are found in.
+	name := func(a, b access) string {
+		var shared *valueKey
+		for _, ka := range a.vars {
+			for _, kb := range b.vars {
+				if ka == kb && (shared == nil || ka.pos() < shared.pos()) {
+					ka := ka
+					shared = &ka
+				}
+			}
+		}
+		if shared != nil {
+			if name := shared.name(); name != "" {
+				return "variable " + name
+			}
+		}
+		return "the variable"
+	}
+	describe := func(a access) string {
+		if a.write {
+			return "written"
+		}
+		return "read"
+	}
+
+	for _, ssafn := range c.checkedFuncs(j) {
+		if ssafn.Synthetic != "" {
+			// package initializers run before any goroutine
+			continue
+		}
+		var gos []*ssa.Go
+		for _, block := range ssafn.Blocks {
+			for _, ins := range block.Instrs {
+				if g, ok := ins.(*ssa.Go); ok {
+					gos = append(gos, g)
+				}
+			}
+		}
+		if len(gos) == 0 {
+			continue
+		}
+		reach := util.MapReachableBlocks(ssafn)
+		parent := accesses(ssafn)
+		// afterGo returns the accesses in the spawning function that
+		// may happen while the goroutine started by g runs.
+		afterGo := func(g *ssa.Go) []access {
+			var out []access
+			for _, a := range parent {
+				if after, ok := util.IsPotentiallyReachableInst(g, a.ins, reach); ok && after && pathAvoiding(g, a.ins, isJoin) {
+					out = append(out, a)
+				}
+			}
+			return out
+		}
+		reported := map[ssa.Instruction]bool{}
+
+		for _, g := range gos {
+			fn := g.Call.StaticCallee()
+			if fn == nil || fn.Blocks == nil {
+				continue
+			}
+			// variables captured by an anonymous goroutine are
+			// checked against the spawning function by SA2006
+			captured := map[valueKey]bool{}
+			if mc, ok := g.Call.Value.(*ssa.MakeClosure); ok {
+				for _, b := range mc.Bindings {
+					captured[valueKey{v: b}] = true
+				}
+			}
+			var uncaptured []valueKey
+			shared := func(a, b access) bool {
+				uncaptured = uncaptured[:0]
+				for _, k := range a.vars {
+					if !captured[k] {
+						uncaptured = append(uncaptured, k)
+					}
+				}
+				return sharesObject(uncaptured, b.vars)
+			}
+			loop := c.loopBlocks(g.Block())
+			running := afterGo(g)
+
+			for _, w := range accesses(fn) {
+				// the spawning function, while the goroutine runs
+				for _, a := range running {
+					if (!w.write && !a.write) || !shared(w, a) {
+						continue
+					}
+					if w.write {
+						if !reported[w.ins] {
+							reported[w.ins] = true
+							j.Errorf(w.ins, "%s is written by the goroutine started at %v and %s at %v without holding a lock; the accesses race",
+								name(w, a), j.Program.DisplayPosition(g.Pos()), describe(a), j.Program.DisplayPosition(a.ins.Pos()))
+						}
+						break
+					}
+					if !reported[a.ins] {
+						reported[a.ins] = true
+						j.Errorf(a.ins, "%s is written here without holding a lock while the goroutine started at %v reads it at %v; the accesses race",
+							name(a, w), j.Program.DisplayPosition(g.Pos()), j.Program.DisplayPosition(w.ins.Pos()))
+					}
+				}
+				if !w.write || reported[w.ins] {
+					continue
+				}
+
+				// another instance of the same goroutine, unless
+				// each instance has a variable of its own
+				if len(loop) > 0 {
+					instances := true
+					for _, k := range w.vars {
+						if alloc, ok := k.v.(*ssa.Alloc); ok && loop[alloc.Block()] {
+							instances = false
+						}
+					}
+					if instances {
+						reported[w.ins] = true
+						j.Errorf(w.ins, "%s is written without holding a lock by every goroutine started at %v; the writes race",
+							name(w, w), j.Program.DisplayPosition(g.Pos()))
+						continue
+					}
+				}
+
+				// other goroutines running at the same time
+			others:
+				for _, g2 := range gos {
+					fn2 := g2.Call.StaticCallee()
+					if g2 == g || fn2 == nil || fn2 == fn || fn2.Blocks == nil {
+						continue
+					}
+					if !pathAvoiding(g, g2, isJoin) && !pathAvoiding(g2, g, isJoin) {
+						continue
+					}
+					for _, a := range accesses(fn2) {
+						if sharesObject(w.vars, a.vars) {
+							reported[w.ins] = true
+							j.Errorf(w.ins, "%s is written by the goroutine started at %v and %s by the one started at %v, at %v, without holding a lock; the accesses race",
+								name(w, a), j.Program.DisplayPosition(g.Pos()), describe(a), j.Program.DisplayPosition(g2.Pos()), j.Program.DisplayPosition(a.ins.Pos()))
+							break others
+						}
+					}
+				}
+			}
+		}
+	}
+}
+
+// buffered reports whether ch is only ever a buffered channel, on
+// which a send doesn't necessarily block.
+func (c *Checker) buffered(ch ssa.Value) bool {
+	keys, ok := c.valueKeys(ch)
+	if !ok || len(keys) == 0 {
+		return false
+	}
+	for _, k := range keys {
+		mc, ok := k.v.(*ssa.MakeChan)
+		if !ok {
+			return false
+		}
+		if size, ok := mc.Size.(*ssa.Const); ok && size.Int64() == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+func (c *Checker) CheckGoroutineIgnoresContext(j *lint.Job) {
+	for _, ssafn := range c.checkedFuncs(j) {
+		var ctx *ssa.Parameter
+		for _, p := range ssafn.Params {
+			if IsType(p.Type(), "context.Context") {
+				ctx = p
+				break
+			}
 		}
-		 */
+		if ctx == nil {
+			continue
+		}
+		for _, block := range ssafn.Blocks {
+			for _, ins := range block.Instrs {
+				g, ok := ins.(*ssa.Go)
+				if !ok {
+					continue
+				}
+				fn := g.Call.StaticCallee()
+				if fn == nil || fn.Blocks == nil {
+					continue
+				}
 
-		fFuncNode := c.funcDescs.CallGraph.CreateNode(fFunc)
-		//fmt.Println(fFunc.Name() + "---->" + sFunc.Name())
+				// the channels returned by ctx.Done()
+				dones := map[ssa.Value]bool{}
+				for _, b := range fn.Blocks {
+					for _, ins := range b.Instrs {
+						call, ok := ins.(*ssa.Call)
+						if ok && call.Common().IsInvoke() && call.Common().Method.Name() == "Done" &&
+							IsType(call.Common().Value.Type(), "context.Context") {
+							dones[call] = true
+						}
+					}
+				}
 
-		pathResult := callgraph.PathSearchIgnoreGoCall(
-			fFuncNode, func(other *callgraph.Node) bool {
-				return other.Func == sFunc
-			})
+				var blocking ssa.Instruction
+			search:
+				for _, b := range fn.Blocks {
+					for _, ins := range b.Instrs {
+						switch ins := ins.(type) {
+						case *ssa.UnOp:
+							if ins.Op == token.ARROW && !dones[ins.X] {
+								blocking = ins
+								break search
+							}
+						case *ssa.Send:
+							if !c.buffered(ins.Chan) {
+								blocking = ins
+								break search
+							}
+						case *ssa.Select:
+							if !ins.Blocking {
+								continue
+							}
+							aware := false
+							for _, st := range ins.States {
+								if st.Dir == types.RecvOnly && dones[st.Chan] {
+									aware = true
+								}
+							}
+							if !aware {
+								blocking = ins
+								break search
+							}
+						}
+					}
+				}
+				if blocking == nil {
+					continue
+				}
+				what := "a channel"
+				if _, ok := blocking.(*ssa.Select); ok {
+					what = "a select"
+				}
+				j.Errorf(g, "the goroutine blocks on %s at %v without observing the cancellation of %s; select on %s.Done() as well so that it doesn't leak",
+					what, j.Program.DisplayPosition(blocking.Pos()), ctx.Name(), ctx.Name())
+			}
+		}
+	}
+}
 
-		// Careful pathResult != nil is not equal len(pathResult) > 0
-		if len(pathResult) > 0 {
+// lockTypes are the types in package sync that must not be copied
+// after first use.
+var lockTypes = map[string]bool{
+	"Mutex":     true,
+	"RWMutex":   true,
+	"WaitGroup": true,
+	"Cond":      true,
+	"Once":      true,
+}
 
-			// TODO: optimize it!!!
-			sNode := bg.CreateBBNode(sInstr.Block())
-			if isUnlockBeforeLock(sNode, lockKey) {
-				// if there is an unlock before second lock, we should ignore it?
-				return false
+// containsLock returns the name of a lock type T is or contains by
+// value, e.g. sync.Mutex, or the empty string if there is none.
+func containsLock(T types.Type) string {
+	return containsLockSeen(T, map[types.Type]bool{})
+}
+
+func containsLockSeen(T types.Type, seen map[types.Type]bool) string {
+	if seen[T] {
+		return ""
+	}
+	seen[T] = true
+	if named, ok := T.(*types.Named); ok {
+		obj := named.Obj()
+		if obj.Pkg() != nil && obj.Pkg().Path() == "sync" && lockTypes[obj.Name()] {
+			return "sync." + obj.Name()
+		}
+	}
+	switch T := T.Underlying().(type) {
+	case *types.Struct:
+		for i := 0; i < T.NumFields(); i++ {
+			if lock := containsLockSeen(T.Field(i).Type(), seen); lock != "" {
+				return lock
 			}
+		}
+	case *types.Array:
+		return containsLockSeen(T.Elem(), seen)
+	}
+	return ""
+}
 
-			firstEdge := pathResult[0]
-			callInstruction := firstEdge.Site
-			sInstr, ok := callInstruction.(*ssa.Call)
+func (c *Checker) CheckCopyLock(j *lint.Job) {
+	// describe returns a description of the lock copied by copying
+	// a value of expr's type, or the empty string if no lock is
+	// copied. Composite literals and call results are fresh values
+	// and can't have been used yet.
+	var qf types.Qualifier
+	describe := func(expr ast.Expr) string {
+		x := expr
+		for {
+			p, ok := x.(*ast.ParenExpr)
 			if !ok {
-				return false
+				break
 			}
-			// no unlock from lockInstruction to callInstruction
-			// no unlock before second locking, see line#977
-			if fInstr.Block() == sInstr.Block() {
-				if isLockToLockInSameBlock(fInstr, sInstr) {
-					return true
-				}
-			} else {
+			x = p.X
+		}
+		switch x.(type) {
+		case *ast.CompositeLit, *ast.CallExpr, *ast.FuncLit:
+			return ""
+		}
+		T := TypeOf(j, expr)
+		if T == nil {
+			return ""
+		}
+		lock := containsLock(T)
+		if lock == "" {
+			return ""
+		}
+		name := types.TypeString(T, qf)
+		if name == lock {
+			return lock
+		}
+		return name + " contains " + lock
+	}
 
-				fNode := bg.CreateBBNode(fInstr.Block())
-				sNode := bg.CreateBBNode(sInstr.Block())
+	fn := func(node ast.Node) bool {
+		switch node := node.(type) {
+		case *ast.FuncDecl:
+			if node.Recv == nil || len(node.Recv.List) == 0 {
+				return true
+			}
+			recv := node.Recv.List[0]
+			if _, ok := TypeOf(j, recv.Type).(*types.Pointer); ok {
+				return true
+			}
+			if lock := containsLock(TypeOf(j, recv.Type)); lock != "" {
+				j.Errorf(recv.Type, "%s has a value receiver, so every call copies the %s in %s; use a pointer receiver",
+					node.Name.Name, lock, Render(j, recv.Type))
+			}
+		case *ast.CallExpr:
+			if tv, ok := j.Program.Info.Types[node.Fun]; ok && (tv.IsType() || tv.IsBuiltin()) {
+				// conversions and builtins like len
+				return true
+			}
+			for _, arg := range node.Args {
+				if desc := describe(arg); desc != "" {
+					j.Errorf(arg, "call of %s copies lock value: %s", Render(j, node.Fun), desc)
+				}
+			}
+		case *ast.AssignStmt:
+			for i, rhs := range node.Rhs {
+				if len(node.Lhs) != len(node.Rhs) {
+					break
+				}
+				if desc := describe(rhs); desc != "" {
+					j.Errorf(rhs, "assignment copies lock value to %s: %s", Render(j, node.Lhs[i]), desc)
+				}
+			}
+		case *ast.ValueSpec:
+			for i, v := range node.Values {
+				if len(node.Names) != len(node.Values) {
+					break
+				}
+				if desc := describe(v); desc != "" {
+					j.Errorf(v, "variable declaration copies lock value to %s: %s", node.Names[i].Name, desc)
+				}
+			}
+		case *ast.ReturnStmt:
+			for _, res := range node.Results {
+				if desc := describe(res); desc != "" {
+					j.Errorf(res, "return copies lock value: %s", desc)
+				}
+			}
+		case *ast.RangeStmt:
+			if node.Value == nil || IsBlank(node.Value) {
+				return true
+			}
+			T := TypeOf(j, node.Value)
+			if T == nil {
+				return true
+			}
+			if lock := containsLock(T); lock != "" {
+				j.Errorf(node.Value, "range variable %s copies lock value: each element contains %s; range over the indices instead",
+					Render(j, node.Value), lock)
+			}
+		}
+		return true
+	}
+	for _, pkg := range j.Program.Packages {
+		qf = types.RelativeTo(pkg.Info.Pkg)
+		for _, f := range pkg.Info.Files {
+			ast.Inspect(f, fn)
+		}
+	}
+}
 
-				finded := findPath(fNode, sNode, lockKey)
+func (c *Checker) CheckGoValueReceiver(j *lint.Job) {
+	for _, ssafn := range c.checkedFuncs(j) {
+		qf := types.RelativeTo(ssafn.Package().Pkg)
+		for _, block := range ssafn.Blocks {
+			for _, ins := range block.Instrs {
+				g, ok := ins.(*ssa.Go)
+				if !ok {
+					continue
+				}
+				callee := g.Call.StaticCallee()
+				if callee == nil || callee.Signature.Recv() == nil {
+					continue
+				}
+				T := callee.Signature.Recv().Type()
+				if _, ok := T.(*types.Pointer); ok {
+					continue
+				}
+				lock := containsLock(T)
+				if lock == "" {
+					continue
+				}
+				j.Errorf(g, "%s has a value receiver, so the goroutine runs on a copy of the %s in %s and doesn't synchronize with its caller",
+					callee.Name(), lock, types.TypeString(T, qf))
+			}
+		}
+	}
+}
 
-				//if finded {
-				//	fmt.Println(pathResult)
-				//}
-				return finded
+func (c *Checker) CheckLoadOrStoreLoaded(j *lint.Job) {
+	// used reports whether v has users other than debug info and
+	// assignments to the blank identifier.
+	used := func(v ssa.Value) bool {
+		refs := v.Referrers()
+		if refs == nil {
+			return false
+		}
+		for _, ref := range FilterDebug(*refs) {
+			if _, ok := ref.(*ssa.BlankStore); !ok {
+				return true
+			}
+		}
+		return false
+	}
+	for _, ssafn := range c.checkedFuncs(j) {
+		for _, block := range ssafn.Blocks {
+			for _, ins := range block.Instrs {
+				call, ok := ins.(*ssa.Call)
+				if !ok || !IsCallTo(call.Common(), "(*sync.Map).LoadOrStore") {
+					continue
+				}
+				loaded := false
+				for _, ref := range FilterDebug(*call.Referrers()) {
+					if ex, ok := ref.(*ssa.Extract); ok && ex.Index == 1 && used(ex) {
+						loaded = true
+					}
+				}
+				if !loaded {
+					j.Errorf(call, "the loaded result of LoadOrStore is never used, so the case of the key already being present isn't handled; the value passed in may have been created for nothing")
+				}
 			}
 		}
 	}
-	return isNotNeedFindPathSearch
 }
 
-func (c *Checker) CheckDoubleLock(j *lint.Job) {
+// addressKeys returns the keys identifying the variable at addr, as
+// by lockValueKey. Fields are also identified by their struct type,
+// so that accesses through different pointers to the same type match.
+func addressKeys(addr ssa.Value) []string {
+	keys := []string{lockValueKey(addr)}
+	if fa, ok := addr.(*ssa.FieldAddr); ok {
+		if k := fieldName(fa); k != keys[0] {
+			keys = append(keys, k)
+		}
+	}
+	return keys
+}
 
-	lockInstructions := make(map[string][]ssa.Instruction)
+// addressName returns the source name of the variable at addr.
+func addressName(addr ssa.Value) string {
+	switch addr := addr.(type) {
+	case *ssa.Global:
+		return addr.Name()
+	case *ssa.Alloc:
+		if addr.Comment != "" {
+			return addr.Comment
+		}
+	case *ssa.FieldAddr:
+		return "field " + Dereference(addr.X.Type()).Underlying().(*types.Struct).Field(addr.Field).Name()
+	case *ssa.FreeVar:
+		return addr.Name()
+	}
+	return "the variable"
+}
 
-	for _, ssafn := range j.Program.InitialFunctions {
+func (c *Checker) CheckMixedAtomic(j *lint.Job) {
+	isAtomic := func(common *ssa.CallCommon) bool {
+		fn := common.StaticCallee()
+		return fn != nil && fn.Pkg != nil && fn.Pkg.Pkg.Path() == "sync/atomic" &&
+			fn.Signature.Recv() == nil && len(common.Args) > 0
+	}
 
-		//if !(ssafn.Name() == "checkGrowBaseDeviceFS" || ssafn.Name() == "removeDevice") {
-		//	continue
-		//}
+	atomics := map[string]*ssa.Call{}
+	for _, ssafn := range c.checkedFuncs(j) {
+		for _, block := range ssafn.Blocks {
+			for _, ins := range block.Instrs {
+				call, ok := ins.(*ssa.Call)
+				if !ok || !isAtomic(call.Common()) {
+					continue
+				}
+				for _, k := range addressKeys(call.Common().Args[0]) {
+					if prev, ok := atomics[k]; !ok || call.Pos() < prev.Pos() {
+						atomics[k] = call
+					}
+				}
+			}
+		}
+	}
+	if len(atomics) == 0 {
+		return
+	}
 
-		lockResultBB := collectLockInstrs(ssafn)
+	for _, ssafn := range c.checkedFuncs(j) {
+		if ssafn.Synthetic != "" {
+			// package initializers run before any goroutine
+			continue
+		}
+		for _, block := range ssafn.Blocks {
+			for _, ins := range block.Instrs {
+				var addr ssa.Value
+				kind := ""
+				switch ins := ins.(type) {
+				case *ssa.UnOp:
+					if ins.Op != token.MUL {
+						continue
+					}
+					addr, kind = ins.X, "read"
+				case *ssa.Store:
+					addr, kind = ins.Addr, "write"
+				default:
+					continue
+				}
+				keys := addressKeys(addr)
+				if fa, ok := addr.(*ssa.FieldAddr); ok {
+					if _, ok := fa.X.(*ssa.Alloc); ok {
+						// initializing a new value, before it is
+						// shared; only match the variable itself
+						keys = keys[:1]
+					}
+				}
+				for _, k := range keys {
+					atomic, ok := atomics[k]
+					if !ok {
+						continue
+					}
+					j.Errorf(ins, "%s is accessed atomically at %v, but this %s isn't atomic; mixing atomic and plain accesses is a data race",
+						addressName(addr), j.Program.DisplayPosition(atomic.Pos()), kind)
+					break
+				}
+			}
+		}
+	}
+}
 
-		for lockKey, lockInstrs := range lockResultBB {
-			// collect all lock acquiring
-			lockInstructions[lockKey] = append(lockInstructions[lockKey], lockInstrs...)
+// sizes32 are the sizes of the gc compiler on a 32-bit platform, on
+// which 64-bit words are only 32-bit aligned.
+var sizes32 = types.SizesFor("gc", "386")
+
+// fieldOffset32 returns the offset, on a 32-bit platform, of the field
+// addressed by fa from the start of the outermost struct reached
+// through fields, e.g. of x in &t.inner.x from the start of t, and the
+// type of that struct.
+func fieldOffset32(fa *ssa.FieldAddr) (int64, types.Type) {
+	var offset int64
+	for {
+		T := Dereference(fa.X.Type())
+		st := T.Underlying().(*types.Struct)
+		fields := make([]*types.Var, st.NumFields())
+		for i := range fields {
+			fields[i] = st.Field(i)
+		}
+		offset += sizes32.Offsetsof(fields)[fa.Field]
+		outer, ok := fa.X.(*ssa.FieldAddr)
+		if !ok {
+			return offset, T
 		}
+		fa = outer
 	}
+}
 
-	for lockKey, lockInstrs := range lockInstructions {
+func (c *Checker) CheckMisalignedAtomic(j *lint.Job) {
+	for _, ssafn := range c.checkedFuncs(j) {
+		for _, block := range ssafn.Blocks {
+			for _, ins := range block.Instrs {
+				call, ok := ins.(*ssa.Call)
+				if !ok || len(call.Common().Args) == 0 {
+					continue
+				}
+				fn := call.Common().StaticCallee()
+				if fn == nil || fn.Pkg == nil || fn.Pkg.Pkg.Path() != "sync/atomic" || fn.Signature.Recv() != nil {
+					continue
+				}
+				typ := ""
+				switch {
+				case strings.HasSuffix(fn.Name(), "Uint64"):
+					typ = "Uint64"
+				case strings.HasSuffix(fn.Name(), "Int64"):
+					typ = "Int64"
+				default:
+					continue
+				}
+				// The first word of a variable or of an allocated
+				// struct is 64-bit aligned, so only fields can be
+				// misaligned.
+				fa, ok := call.Common().Args[0].(*ssa.FieldAddr)
+				if !ok {
+					continue
+				}
+				offset, T := fieldOffset32(fa)
+				if offset%8 == 0 {
+					continue
+				}
+				var qf types.Qualifier
+				if ssafn.Pkg != nil {
+					qf = types.RelativeTo(ssafn.Pkg.Pkg)
+				}
+				j.Errorf(call, "atomic.%s on %s, which is at offset %d of %s on 32-bit platforms and so isn't 64-bit aligned there, panics on those platforms; move the field to the start of the struct or use atomic.%s",
+					fn.Name(), addressName(fa), offset, types.TypeString(T, qf), typ)
+			}
+		}
+	}
+}
 
-		for i := 0; i < len(lockInstrs); i++ {
+// mayBlock reports whether ins may block or yield the processor. Any
+// call other than to a builtin is assumed to, so that loops calling
+// time.Sleep, waiting on a lock or doing any other work aren't
+// mistaken for busy waits.
+func mayBlock(ins ssa.Instruction) bool {
+	switch ins := ins.(type) {
+	case *ssa.UnOp:
+		return ins.Op == token.ARROW
+	case *ssa.Send:
+		return true
+	case *ssa.Select:
+		return ins.Blocking
+	case ssa.CallInstruction:
+		_, ok := ins.Common().Value.(*ssa.Builtin)
+		return !ok
+	}
+	return false
+}
 
-			for t := i; t < len(lockInstrs); t++ {
+// exitsLoop reports whether b, a block of loop, leaves the loop.
+func exitsLoop(b *ssa.BasicBlock, loop functions.Loop) bool {
+	for _, succ := range b.Succs {
+		if !loop[succ] {
+			return true
+		}
+	}
+	switch b.Instrs[len(b.Instrs)-1].(type) {
+	case *ssa.Return, *ssa.Panic:
+		return true
+	}
+	return false
+}
 
-				fInstr, _ := lockInstrs[i].(*ssa.Call)
-				sInstr, _ := lockInstrs[t].(*ssa.Call)
+func (c *Checker) CheckSelectDefaultSpin(j *lint.Job) {
+	for _, ssafn := range c.checkedFuncs(j) {
+		for _, block := range ssafn.Blocks {
+			for _, ins := range block.Instrs {
+				sel, ok := ins.(*ssa.Select)
+				if !ok || sel.Blocking {
+					continue
+				}
+				loop := c.loopBlocks(block)
+				if len(loop) == 0 {
+					continue
+				}
+				spins := true
+				for b := range loop {
+					for _, other := range b.Instrs {
+						if other != sel && mayBlock(other) {
+							spins = false
+						}
+					}
+					// a loop that can be left without going
+					// through the select, such as a bounded
+					// for loop, ends by itself
+					if exitsLoop(b, loop) && !block.Dominates(b) {
+						spins = false
+					}
+				}
+				if !spins {
+					continue
+				}
+				j.Errorf(sel, "select with a default case in a loop that never blocks is a busy wait; remove the default case or wait with time.Sleep or a time.Ticker")
+			}
+		}
+	}
+}
 
-				if c._isDoubleLock(fInstr, sInstr, lockKey) {
+func (c *Checker) CheckSpinOnClosedChannel(j *lint.Job) {
+	// the first close of every channel, anywhere in the program
+	closed := map[valueKey]ssa.Instruction{}
+	for _, fn := range j.Program.AllFunctions {
+		for _, block := range fn.Blocks {
+			for _, ins := range block.Instrs {
+				// close(ch), defer close(ch) or go close(ch)
+				call, ok := ins.(ssa.CallInstruction)
+				if !ok {
+					continue
+				}
+				if b, ok := call.Common().Value.(*ssa.Builtin); !ok || b.Name() != "close" {
+					continue
+				}
+				keys, _ := c.valueKeys(call.Common().Args[0])
+				for _, k := range keys {
+					if _, ok := closed[k]; !ok {
+						closed[k] = call
+					}
+				}
+			}
+		}
+	}
+	if len(closed) == 0 {
+		return
+	}
 
-					po1 := j.Program.DisplayPosition(fInstr.Pos())
-					po := j.Program.DisplayPosition(sInstr.Pos())
-					name := shortCallName(fInstr.Common())
-					j.Errorf(fInstr, "Acquiring the %s again at %v, %v", name, po, po1)
+	for _, ssafn := range c.checkedFuncs(j) {
+		for _, block := range ssafn.Blocks {
+			for _, ins := range block.Instrs {
+				recv, ok := ins.(*ssa.UnOp)
+				if !ok || recv.Op != token.ARROW || recv.CommaOk {
+					continue
 				}
-
-				if fInstr != sInstr && c._isDoubleLock(sInstr, fInstr, lockKey) {
-
-					po := j.Program.DisplayPosition(fInstr.Pos())
-					name := shortCallName(sInstr.Common())
-					j.Errorf(sInstr, "Acquiring the %s again at %v ", name, po)
+				if !c.isInLoop(block) {
+					continue
+				}
+				// the loop must not have any way out; a loop that
+				// can exit presumably checks for the zero value
+				loop := c.loopBlocks(block)
+				exits := false
+				for b := range loop {
+					for _, succ := range b.Succs {
+						if !loop[succ] {
+							exits = true
+						}
+					}
+				}
+				if exits {
+					continue
+				}
+				keys, _ := c.valueKeys(recv.X)
+				for _, k := range keys {
+					cl, ok := closed[k]
+					if !ok {
+						continue
+					}
+					j.Errorf(recv, "receiving from a channel that is closed at %v in a loop that never exits spins forever once it is closed; use v, ok := <-ch and stop when ok is false",
+						j.Program.DisplayPosition(cl.Pos()))
+					break
 				}
 			}
 		}
 	}
 }
 
-func (c *Checker) CheckAnonRace(j *lint.Job) {
-
-	for _, ssafn := range j.Program.InitialFunctions {
+// reachableFuncs returns the functions reachable from roots in the
+// static call graph. Calls in go statements are only followed if
+// followGo is set.
+func (c *Checker) reachableFuncs(roots []*ssa.Function, followGo bool) map[*ssa.Function]bool {
+	seen := map[*ssa.Function]bool{}
+	var visit func(fn *ssa.Function)
+	visit = func(fn *ssa.Function) {
+		if fn == nil || seen[fn] {
+			return
+		}
+		seen[fn] = true
+		node := c.funcDescs.CallGraph.Nodes[fn]
+		if node == nil {
+			return
+		}
+		for _, edge := range node.Out {
+			if _, ok := edge.Site.(*ssa.Go); ok && !followGo {
+				continue
+			}
+			visit(edge.Callee.Func)
+		}
+	}
+	for _, fn := range roots {
+		visit(fn)
+	}
+	return seen
+}
 
-		if strings.HasSuffix(ssafn.String(), ".init") {
+// isCalledOnly reports whether the only uses of v are calls of it.
+func isCalledOnly(v ssa.Value) bool {
+	refs := v.Referrers()
+	if refs == nil {
+		return false
+	}
+	for _, ref := range *refs {
+		if _, ok := ref.(*ssa.DebugRef); ok {
 			continue
 		}
-
-		blockReachability := util.MapReachableBlocks(ssafn)
-
-		if result, ok := util.HasAnonRace(ssafn.AnonFuncs, blockReachability); ok {
-			fmt.Println(result)
+		call, ok := ref.(ssa.CallInstruction)
+		if !ok || call.Common().IsInvoke() || call.Common().Value != v {
+			return false
 		}
 	}
-
+	return true
 }
 
-func (c *Checker) CheckWaitgroupBlocking(j *lint.Job) {
-
-	for _, ssafn := range j.Program.InitialFunctions {
-
-		// for loop in a func and create goroutines in the loop
-		loopSets := c.funcDescs.Get(ssafn).Loops
-
-		for _, loop := range loopSets {
-
-			isCallDoneInGoroutine := false
-			isCallWait := false
-
-			for bb := range loop {
-
-				for _, ins := range bb.Instrs {
-
-					// new goroutine
-					gostmt, ok := ins.(*ssa.Go)
-
-					if ok {
-
-						var fn *ssa.Function
-						switch val := gostmt.Call.Value.(type) {
-						case *ssa.Function:
-							fn = val
-						case *ssa.MakeClosure:
-							fn = val.Fn.(*ssa.Function)
-						default:
-							continue
-						}
-						if fn.Blocks == nil {
-							continue
-						}
-
-						for _, block := range fn.Blocks {
-							for _, ins := range block.Instrs {
-								call, ok := ins.(*ssa.Call)
-								if !ok {
-									continue
-								}
+func (c *Checker) CheckUnnecessaryLock(j *lint.Job) {
+	// Only whole programs can be reasoned about; in a library, any
+	// function may be called from any goroutine.
+	for _, fn := range c.checkedFuncs(j) {
+		if fn.Name() == "main" && fn.Parent() == nil && fn.Signature.Recv() == nil &&
+			fn.Pkg.Pkg.Name() == "main" {
+			c.checkUnnecessaryLock(j, fn)
+		}
+	}
+}
 
-								callStr := strings.ToLower(call.Common().String())
-								if strings.Contains(callStr, ".done(") {
-									isCallDoneInGoroutine = true
-								}
-							}
-						}
-					}
+func (c *Checker) checkUnnecessaryLock(j *lint.Job, mainFn *ssa.Function) {
+	pkg := mainFn.Pkg
+	var pkgFuncs []*ssa.Function
+	for _, fn := range c.checkedFuncs(j) {
+		if fn.Pkg == pkg {
+			pkgFuncs = append(pkgFuncs, fn)
+		}
+	}
 
-					// call Wait()
-					call, ok := ins.(*ssa.Call)
-					if ok {
-						callStr := strings.ToLower(call.Common().String())
-						if strings.Contains(callStr, ".wait(") {
-							isCallWait = true
-						}
+	// Functions that may be called in ways the static call graph
+	// doesn't see, and so from any goroutine: those whose value is
+	// used other than by calling it, and methods of types converted
+	// to interfaces.
+	var unknown []*ssa.Function
+	ifaces := map[string]bool{}
+	for _, fn := range pkgFuncs {
+		for _, block := range fn.Blocks {
+			for _, ins := range block.Instrs {
+				switch ins := ins.(type) {
+				case *ssa.DebugRef:
+					continue
+				case *ssa.MakeInterface:
+					ifaces[types.TypeString(Dereference(ins.X.Type()), nil)] = true
+				case *ssa.MakeClosure:
+					if !isCalledOnly(ins) {
+						unknown = append(unknown, ins.Fn.(*ssa.Function))
 					}
+					continue
 				}
-			}
-
-			if isCallWait && isCallDoneInGoroutine {
-
-				for bb, ok := range loop {
-
-					if ok {
-
-						for _, ins := range bb.Instrs {
-							if ins.Pos() > 0 {
-								j.Errorf(ins, "There is a potential blocking bug,"+
-									"which caused by misusing Wait() and Done()!")
-								break
-							}
-						}
+				var rands []*ssa.Value
+				for _, rand := range ins.Operands(rands) {
+					f, ok := (*rand).(*ssa.Function)
+					if !ok {
+						continue
+					}
+					if call, ok := ins.(ssa.CallInstruction); ok && call.Common().Value == f {
+						continue
 					}
+					unknown = append(unknown, f)
 				}
 			}
 		}
 	}
-}
-
-func _CallName(call *ssa.CallCommon) string {
-
-	if call.IsInvoke() {
-		return call.String()
+	var roots []*ssa.Function
+	for _, fn := range pkgFuncs {
+		if recv := fn.Signature.Recv(); recv != nil && ifaces[types.TypeString(Dereference(recv.Type()), nil)] {
+			unknown = append(unknown, fn)
+		}
+		if fn.Name() == "init" || fn == mainFn {
+			roots = append(roots, fn)
+		}
 	}
 
-	switch v := call.Value.(type) {
-	case *ssa.Function:
-		fn, ok := v.Object().(*types.Func)
-		if !ok {
-			return ""
+	// functions that may run concurrently: those started by go
+	// statements and the unknown ones, and everything they call
+	var concurrentRoots []*ssa.Function
+	concurrentRoots = append(concurrentRoots, unknown...)
+	for _, fn := range pkgFuncs {
+		for _, block := range fn.Blocks {
+			for _, ins := range block.Instrs {
+				if g, ok := ins.(*ssa.Go); ok {
+					concurrentRoots = append(concurrentRoots, g.Common().StaticCallee())
+				}
+			}
 		}
-		return fn.FullName()
-	case *ssa.Builtin:
-		return v.Name()
 	}
-	return ""
-}
+	onMain := c.reachableFuncs(roots, false)
+	concurrent := c.reachableFuncs(concurrentRoots, true)
 
-func ignoreFunc(j *lint.Job, f *ssa.Function) bool {
-
-	for _, bb := range f.Blocks {
-
-		for _, ins := range bb.Instrs {
-			if ins.Pos() > 0 {
-				po := j.Program.DisplayPosition(ins.Pos()).String()
-
-				if strings.Contains(po, "_test.go") {
-					return true
-				} else {
-					return false
+	type lockUse struct {
+		first  *ssa.Call
+		serial bool
+	}
+	uses := map[string]*lockUse{}
+	var keys []string
+	for _, fn := range pkgFuncs {
+		for _, block := range fn.Blocks {
+			for _, ins := range block.Instrs {
+				call, ok := ins.(*ssa.Call)
+				if !ok || !c.isCallToLock(call.Common()) {
+					continue
+				}
+				// only locks with an identity beyond a single
+				// function: struct fields and globals
+				k, ok := fieldLockKey(call)
+				if !ok {
+					if _, isGlobal := lockReceiver(call.Common()).(*ssa.Global); !isGlobal {
+						continue
+					}
+					k = getLockPrefix(call)
+				}
+				u, ok := uses[k]
+				if !ok {
+					u = &lockUse{first: call, serial: true}
+					uses[k] = u
+					keys = append(keys, k)
+				}
+				if call.Pos() < u.first.Pos() {
+					u.first = call
+				}
+				if !onMain[fn] || concurrent[fn] {
+					u.serial = false
 				}
 			}
 		}
 	}
-	return false
-}
-
-func (c *Checker) CheckPrimitiveUsage(j *lint.Job) {
-	isMutex := 0
-	isRWMutex := 0
-	isCond := 0
-	isPool := 0
-	isWaitgroup := 0
-	isAtomic := 0
-	isOnce := 0
-	isChannel := 0
-
-	for _, ssafn := range j.Program.InitialFunctions {
-
-		// ignore test file
-		if ignoreFunc(j, ssafn) {
+	sort.Strings(keys)
+	for _, k := range keys {
+		u := uses[k]
+		if !u.serial {
 			continue
 		}
+		j.Errorf(u.first, "%s is only ever locked on the main goroutine, so the lock appears to be unnecessary", k)
+	}
+}
 
-		for _, bb := range ssafn.Blocks {
-
-			instrs := FilterDebug(bb.Instrs)
-
-			for _, ins := range instrs {
-
-				// Send type
-				// send value to channel
-				_, ok := ins.(*ssa.Send)
-				if ok {
-					isChannel += 1
-					//fmt.Println(ins)
+func (c *Checker) CheckLostCancel(j *lint.Job) {
+	for _, ssafn := range c.checkedFuncs(j) {
+		for _, block := range ssafn.Blocks {
+			for _, ins := range block.Instrs {
+				call, ok := ins.(*ssa.Call)
+				if !ok {
 					continue
 				}
-
-				// UnOp type
-				unop, ok := ins.(*ssa.UnOp)
-				if ok {
-					if unop.Op == token.ARROW {
-						isChannel += 1
-						//fmt.Println(ins)
-						continue
+				name := CallName(call.Common())
+				switch name {
+				case "context.WithCancel", "context.WithTimeout", "context.WithDeadline":
+				default:
+					continue
+				}
+				var cancel ssa.Value
+				for _, ref := range *call.Referrers() {
+					if ex, ok := ref.(*ssa.Extract); ok && ex.Index == 1 {
+						cancel = ex
 					}
 				}
-
-				// channel in select
-				selector, ok := ins.(*ssa.Select)
-				if ok {
-					// if each case in select is related to a channel
-					for _, state := range selector.States {
-						if state.Chan != nil {
-							isChannel += 1
+				used := false
+				if cancel != nil {
+					for _, ref := range *cancel.Referrers() {
+						switch ref.(type) {
+						case *ssa.DebugRef, *ssa.BlankStore:
+						default:
+							used = true
 						}
 					}
+				}
+				if !used {
+					j.Errorf(call, "the cancel function returned by %s is discarded; the context leaks until its parent is canceled", name)
 					continue
 				}
-
-				// call
-				var call *ssa.CallCommon
-
-				call_, ok := ins.(*ssa.Call)
-
-				if ok {
-					call = call_.Common()
+				// a cancel function that is stored, returned or
+				// passed on may be called elsewhere
+				if escapes(cancel, func(common *ssa.CallCommon) bool { return common.Value == cancel }) {
+					continue
 				}
-
-				deferIns, ok := ins.(*ssa.Defer)
-				if ok {
-					call = deferIns.Common()
+				done := func(ins ssa.Instruction) bool {
+					call, ok := ins.(ssa.CallInstruction)
+					return ok && call.Common().Value == cancel
 				}
+				leak := leakPath(call, done)
+				if _, ok := leak.(*ssa.Return); !ok {
+					continue
+				}
+				if leak.Pos().IsValid() {
+					j.Errorf(call, "the cancel function returned by %s is not called on the path returning at %v", name, j.Program.DisplayPosition(leak.Pos()))
+				} else {
+					j.Errorf(call, "the cancel function returned by %s is not called on the path reaching the end of the function", name)
+				}
+			}
+		}
+	}
+}
 
-				if call != nil {
-					callName := _CallName(call)
-					if callName == "(*sync.Mutex).Lock" || callName == "(*sync.Mutex).Unlock" {
-						isMutex += 1
-						continue
-					}
-
-					if callName == "(*sync.RWMutex).Lock" || callName == "(*sync.RWMutex).Unlock" ||
-						callName == "(*sync.RWMutex).RLock" || callName == "(*sync.RWMutex).RUnlock" {
-						isRWMutex += 1
-						continue
-					}
-
-					if callName == "(*sync.WaitGroup).Add" || callName == "(*sync.WaitGroup).Done" ||
-						callName == "(*sync.WaitGroup).Wait" {
-						isWaitgroup += 1
-						continue
-					}
-
-					if callName == "(*sync.Once).Do" {
-						isOnce += 1
-						continue
-					}
+// goroutineUsing returns a go statement whose goroutine uses v, either
+// as an argument or captured by a closure, possibly after deriving a
+// new context from it.
+func goroutineUsing(v ssa.Value) *ssa.Go {
+	seen := map[ssa.Value]bool{}
+	var visit func(v ssa.Value) *ssa.Go
+	visit = func(v ssa.Value) *ssa.Go {
+		if seen[v] {
+			return nil
+		}
+		seen[v] = true
+		refs := v.Referrers()
+		if refs == nil {
+			return nil
+		}
+		for _, ref := range *refs {
+			switch ref := ref.(type) {
+			case *ssa.Go:
+				return ref
+			case *ssa.Store:
+				if ref.Val != v {
+					continue
+				}
+				if g := visit(ref.Addr); g != nil {
+					return g
+				}
+			case *ssa.MakeClosure:
+				if g := visit(ref); g != nil {
+					return g
+				}
+			case *ssa.Call:
+				// ctx2 := context.WithValue(ctx, ...)
+				if !strings.HasPrefix(CallName(ref.Common()), "context.With") {
+					continue
+				}
+				if g := visit(ref); g != nil {
+					return g
+				}
+			case *ssa.Extract:
+				if ref.Index != 0 {
+					continue
+				}
+				if g := visit(ref); g != nil {
+					return g
+				}
+			case *ssa.MakeInterface, *ssa.ChangeInterface, *ssa.Phi, *ssa.UnOp:
+				if g := visit(ref.(ssa.Value)); g != nil {
+					return g
+				}
+			}
+		}
+		return nil
+	}
+	return visit(v)
+}
 
-					if callName == "(*sync.Cond).Broadcast" || callName == "(*sync.Cond).Signal" ||
-						callName == "(*sync.Cond).Wait" {
-						isCond += 1
-						continue
-					}
+// isJoin reports whether ins may wait for a goroutine to finish: a
+// receive, a blocking select or a call to a Wait method.
+func isJoin(ins ssa.Instruction) bool {
+	switch ins := ins.(type) {
+	case *ssa.UnOp:
+		return ins.Op == token.ARROW
+	case *ssa.Select:
+		return ins.Blocking
+	case ssa.CallInstruction:
+		if _, ok := ins.(*ssa.Go); ok {
+			return false
+		}
+		if ins.Common().IsInvoke() {
+			return ins.Common().Method.Name() == "Wait"
+		}
+		callee := ins.Common().StaticCallee()
+		return callee != nil && callee.Name() == "Wait" && callee.Signature.Recv() != nil
+	}
+	return false
+}
 
-					if callName == "(*sync.Pool).Get" || callName == "(*sync.Pool).Put" {
-						isPool += 1
-						continue
+func (c *Checker) CheckEarlyCancel(j *lint.Job) {
+	for _, ssafn := range c.checkedFuncs(j) {
+		for _, block := range ssafn.Blocks {
+			for _, ins := range block.Instrs {
+				call, ok := ins.(*ssa.Call)
+				if !ok {
+					continue
+				}
+				name := CallName(call.Common())
+				switch name {
+				case "context.WithCancel", "context.WithTimeout", "context.WithDeadline":
+				default:
+					continue
+				}
+				var ctx, cancel ssa.Value
+				for _, ref := range *call.Referrers() {
+					if ex, ok := ref.(*ssa.Extract); ok {
+						switch ex.Index {
+						case 0:
+							ctx = ex
+						case 1:
+							cancel = ex
+						}
 					}
-
-					if strings.Contains(callName, "atomic") {
-						isAtomic += 1
-						continue
+				}
+				if ctx == nil || cancel == nil {
+					continue
+				}
+				var deferred *ssa.Defer
+				for _, ref := range *cancel.Referrers() {
+					if d, ok := ref.(*ssa.Defer); ok && d.Call.Value == cancel {
+						deferred = d
 					}
 				}
+				if deferred == nil {
+					continue
+				}
+				g := goroutineUsing(ctx)
+				if g == nil || g.Parent() != ssafn {
+					continue
+				}
+				if leakPath(g, isJoin) == nil {
+					// every path waits for something before
+					// returning
+					continue
+				}
+				j.Errorf(deferred, "deferred cancel cancels the context from %s as soon as %s returns, but the goroutine started at %v, which uses it, isn't waited for",
+					name, ssafn.Name(), j.Program.DisplayPosition(g.Pos()))
 			}
 		}
 	}
-
-	fmt.Printf("Mutex: %d, RWMutex %d,Cond %d, Pool %d, Once %d, atomic %d, Waitgroup %d, Channel %d\n",
-		isMutex, isRWMutex, isCond, isPool, isOnce, isAtomic, isWaitgroup, isChannel)
 }
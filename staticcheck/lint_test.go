@@ -8,16 +8,760 @@
 package staticcheck
 
 import (
+	"fmt"
+	"go/parser"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/Tengfei1010/GCBDetector/lint"
 	"github.com/Tengfei1010/GCBDetector/lint/lintutil"
 	"github.com/Tengfei1010/GCBDetector/lint/testutil"
+	"golang.org/x/tools/go/buildutil"
+	"golang.org/x/tools/go/loader"
 )
 
+// testdataDir holds the fixtures of the checks, one CheckXxx.go file
+// per check, annotated with the problems it is expected to report.
+var testdataDir = filepath.Join("..", "testdata")
+
 func TestAll(t *testing.T) {
 	c := NewChecker()
-	testutil.TestAll(t, c, "")
+	c.Experimental = true
+	for code := range optInChecks {
+		c.EnabledChecks = append(c.EnabledChecks, code)
+	}
+	testutil.TestDir(t, c, testdataDir)
+}
+
+// fakeSync stands in for the sync package in fake build contexts,
+// which don't include the standard library.
+const fakeSync = `package sync
+
+type Mutex struct{ state int32 }
+
+func (m *Mutex) Lock()   {}
+func (m *Mutex) Unlock() {}
+`
+
+// loadFake loads pkgs, which maps import paths to the files of the
+// package and their sources, from a fake build context.
+func loadFake(t *testing.T, pkgs map[string]map[string]string) *lint.Program {
+	conf := &loader.Config{Build: buildutil.FakeContext(pkgs), ParserMode: parser.ParseComments}
+	for path := range pkgs {
+		conf.ImportWithTests(path)
+	}
+	lprog, err := conf.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return lint.NewProgram(lprog, conf, 0)
+}
+
+// loadFixture loads the file name in testdataDir as a package of its
+// own.
+func loadFixture(t *testing.T, name string) *lint.Program {
+	conf := &loader.Config{ParserMode: parser.ParseComments}
+	conf.CreateFromFilenames("adhoc", filepath.Join(testdataDir, name))
+	lprog, err := conf.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return lint.NewProgram(lprog, conf, 0)
+}
+
+// lintFake runs c on the packages loaded by loadFake and returns the
+// problems of check.
+func lintFake(t *testing.T, pkgs map[string]map[string]string, c *Checker, check string) []lint.Problem {
+	return lintCheck(loadFake(t, pkgs), c, check)
+}
+
+// lintFixture runs c on the fixture loaded by loadFixture and returns
+// the problems of check.
+func lintFixture(t *testing.T, name string, c *Checker, check string) []lint.Problem {
+	return lintCheck(loadFixture(t, name), c, check)
+}
+
+func lintCheck(prog *lint.Program, c *Checker, check string) []lint.Problem {
+	l := &lint.Linter{Checker: c}
+	var ps []lint.Problem
+	for _, p := range l.LintProgram(prog) {
+		if p.Check == check {
+			ps = append(ps, p)
+		}
+	}
+	return ps
+}
+
+// problemLines returns the lines of ps.
+func problemLines(ps []lint.Problem) []int {
+	var lines []int
+	for _, p := range ps {
+		lines = append(lines, p.Position.Line)
+	}
+	return lines
+}
+
+func TestAnalyze(t *testing.T) {
+	prog := loadFixture(t, "CheckDoubleClose.go")
+
+	// Analyze must not print anything
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	stdout := os.Stdout
+	os.Stdout = w
+	ps, err := Analyze(prog)
+	os.Stdout = stdout
+	w.Close()
+	out, _ := ioutil.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(out) != 0 {
+		t.Errorf("Analyze printed %q", out)
+	}
+
+	found := false
+	for _, p := range ps {
+		if p.Check == "SA2015" {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("no SA2015 problem in %v", ps)
+	}
+
+	if _, err := Analyze(nil); err == nil {
+		t.Error("Analyze(nil) didn't fail")
+	}
+}
+
+func TestPerFuncTimeout(t *testing.T) {
+	c := NewChecker()
+	c.PerFuncTimeout = time.Nanosecond
+	skipped := 0
+	for _, p := range lintFixture(t, "CheckDoubleLock.go", c, "SA2005") {
+		if !strings.Contains(p.Text, "was skipped") {
+			t.Errorf("function wasn't skipped: %s", p.Text)
+			continue
+		}
+		if p.Severity != lint.SeverityWarning {
+			t.Errorf("skipped function reported as %v", p.Severity)
+		}
+		skipped++
+	}
+	if skipped == 0 {
+		t.Error("no function was skipped")
+	}
+	// SA2049 shares the analysis, and the skipped functions are
+	// reported once, by SA2005
+	for _, p := range lintFixture(t, "CheckDoubleLock.go", c, "SA2049") {
+		t.Errorf("SA2049 reported %s", p.Text)
+	}
+}
+
+func TestDeferInLoopClosersOnly(t *testing.T) {
+	c := NewChecker()
+	c.DeferInLoopClosersOnly = true
+	got := problemLines(lintFixture(t, "CheckDeferInLoop.go", c, "SA2051"))
+	sort.Ints(got)
+	// the Close and the function literal calling it, not println
+	if want := []int{14, 31}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got SA2051 on lines %v, want %v", got, want)
+	}
+}
+
+func TestAnalyzeAcrossPackages(t *testing.T) {
+	prog := loadFake(t, map[string]map[string]string{
+		"sync": {"sync.go": fakeSync},
+		"a": {"a.go": `package a
+
+import "sync"
+
+var Mu sync.Mutex
+
+func Do() {
+	Mu.Lock()
+	Mu.Unlock()
+}
+`},
+		"b": {"b.go": `package b
+
+import "a"
+
+func F() {
+	a.Mu.Lock()
+	a.Do()
+	a.Mu.Unlock()
+}
+`},
+	})
+	ps, err := Analyze(prog)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, p := range ps {
+		if p.Check == "SA2005" && p.Position.Filename == "/go/src/a/a.go" && p.Position.Line == 8 &&
+			strings.Contains(p.Text, "/go/src/b/b.go:6:") {
+			return
+		}
+	}
+	t.Errorf("lock in package b re-acquired in package a not found in %v", ps)
+}
+
+// The default lock heuristic is covered by
+// testdata/CheckDoubleLockHeuristic.go.
+func TestLockTypes(t *testing.T) {
+	pkgs := map[string]map[string]string{
+		"spin": {"spin.go": `package spin
+
+type SpinLock struct{ state int32 }
+
+func (l *SpinLock) Lock()   {}
+func (l *SpinLock) Unlock() {}
+
+type Sem struct{ n int32 }
+
+func (s *Sem) Acquire() {}
+func (s *Sem) Release() {}
+
+var (
+	L SpinLock
+	S Sem
+)
+
+func F() {
+	L.Lock()
+	L.Lock()
+	L.Unlock()
+}
+
+func G() {
+	S.Acquire()
+	S.Acquire()
+	S.Release()
+}
+`},
+	}
+
+	tests := []struct {
+		lockTypes   []string
+		noHeuristic bool
+		want        []int // lines of SA2005 problems
+	}{
+		{nil, true, nil},
+		{[]string{"(*spin.Sem).Acquire", "(*spin.Sem).Release"}, false, []int{20, 26}},
+		{[]string{"(*spin.SpinLock).Lock", "(*spin.SpinLock).Unlock"}, true, []int{20}},
+	}
+	for _, tt := range tests {
+		c := NewChecker()
+		c.LockTypes = tt.lockTypes
+		c.NoLockHeuristic = tt.noHeuristic
+		if got := problemLines(lintFake(t, pkgs, c, "SA2005")); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("LockTypes %q, NoLockHeuristic %t: got SA2005 at lines %v, want %v",
+				tt.lockTypes, tt.noHeuristic, got, tt.want)
+		}
+	}
+}
+
+// Without UnlockHelpers, see testdata/CheckMissingUnlockHelper.go.
+func TestUnlockHelpers(t *testing.T) {
+	c := NewChecker()
+	c.UnlockHelpers = []string{"store.release"}
+	ps := lintFake(t, map[string]map[string]string{
+		"sync": {"sync.go": fakeSync},
+		"store": {"store.go": `package store
+
+import "sync"
+
+var (
+	mu   sync.Mutex
+	data map[string]int
+)
+
+func Get(k string) int {
+	mu.Lock()
+	v, ok := data[k]
+	if !ok {
+		return release(&mu, -1)
+	}
+	mu.Unlock()
+	return v
+}
+`, "release.go": `package store
+
+import "sync"
+
+// release is implemented elsewhere.
+func release(mu *sync.Mutex, v int) int
+`},
+	}, c, "SA2010")
+	if len(ps) != 0 {
+		t.Errorf("SA2010 reported with UnlockHelpers %q: %v", c.UnlockHelpers, ps)
+	}
+}
+
+func TestErrgroupWithoutWait(t *testing.T) {
+	ps := lintFake(t, map[string]map[string]string{
+		"golang.org/x/sync/errgroup": {"errgroup.go": `package errgroup
+
+type Group struct{ err error }
+
+func (g *Group) Go(f func() error) {}
+func (g *Group) Wait() error       { return g.err }
+`},
+		"work": {"work.go": `package work
+
+import "golang.org/x/sync/errgroup"
+
+func task() error { return nil }
+
+func Leak() {
+	var g errgroup.Group
+	g.Go(task)
+}
+
+func Early(skip bool) error {
+	var g errgroup.Group
+	g.Go(task)
+	if skip {
+		return nil
+	}
+	return g.Wait()
+}
+
+func Discard() {
+	var g errgroup.Group
+	g.Go(task)
+	g.Wait()
+}
+
+func Loop(n int) error {
+	var g errgroup.Group
+	for i := 0; i < n; i++ {
+		g.Go(task)
+	}
+	return g.Wait()
+}
+
+func Deferred() {
+	var g errgroup.Group
+	defer g.Wait()
+	g.Go(task)
+}
+
+type Server struct{ g errgroup.Group }
+
+func (s *Server) Start() { s.g.Go(task) }
+
+func (s *Server) Stop() error { return s.g.Wait() }
+`},
+	}, NewChecker(), "SA2041")
+	// Go in Leak and Early, Wait in Discard
+	if got, want := problemLines(ps), []int{9, 14, 24}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got SA2041 at lines %v, want %v", got, want)
+	}
+}
+
+func TestSemaphoreRelease(t *testing.T) {
+	ps := lintFake(t, map[string]map[string]string{
+		"context": {"context.go": `package context
+
+type Context interface{ Err() error }
+`},
+		"golang.org/x/sync/semaphore": {"semaphore.go": `package semaphore
+
+import "context"
+
+type Weighted struct{ size, cur int64 }
+
+func (s *Weighted) Acquire(ctx context.Context, n int64) error { return ctx.Err() }
+func (s *Weighted) TryAcquire(n int64) bool                    { return s.cur+n <= s.size }
+func (s *Weighted) Release(n int64)                            { s.cur -= n }
+`},
+		"pool": {"pool.go": `package pool
+
+import (
+	"context"
+
+	"golang.org/x/sync/semaphore"
+)
+
+var (
+	sem    semaphore.Weighted
+	leaked semaphore.Weighted
+)
+
+func work() bool { return false }
+
+func Leak(ctx context.Context) {
+	leaked.Acquire(ctx, 1)
+}
+
+func Early(ctx context.Context) error {
+	if err := sem.Acquire(ctx, 1); err != nil {
+		return err
+	}
+	if work() {
+		return nil
+	}
+	sem.Release(1)
+	return nil
+}
+
+func Deferred(ctx context.Context) error {
+	if err := sem.Acquire(ctx, 1); err != nil {
+		return err
+	}
+	defer sem.Release(1)
+	work()
+	return nil
+}
+
+func Try() {
+	if !sem.TryAcquire(1) {
+		return
+	}
+	work()
+	sem.Release(1)
+}
+
+func Async(ctx context.Context) {
+	sem.Acquire(ctx, 1)
+	go func() {
+		defer sem.Release(1)
+		work()
+	}()
+}
+
+func Weight(ctx context.Context) {
+	sem.Acquire(ctx, 2)
+	work()
+	sem.Release(1)
+}
+`},
+	}, NewChecker(), "SA2042")
+	// Acquire in Leak, Early and Weight
+	if got, want := problemLines(ps), []int{17, 21, 57}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got SA2042 at lines %v, want %v", got, want)
+	}
+}
+
+func TestConfig(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gcbd")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	sub := filepath.Join(dir, "pkg")
+	if err := os.Mkdir(sub, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if cfg, err := LoadConfig(sub); cfg != nil || err != nil {
+		t.Fatalf("LoadConfig without a configuration file = %v, %v", cfg, err)
+	}
+
+	files := map[string]string{
+		"go.mod": "module example.com/m\n",
+		ConfigFile: `{
+	"checkGenerated": true,
+	"lockTypes": ["(*example.com/m/spin.SpinLock).Lock"],
+	"unlockHelpers": ["example.com/m/store.release"],
+	"severity": {"SA2043": "warning"},
+	"disabledChecks": ["SA2008"],
+	"enabledChecks": ["SA2054"],
+	"experimental": true,
+	"includeTests": true,
+	"deferInLoopClosersOnly": true,
+	"perFuncTimeout": "30s"
+}`,
+	}
+	for name, src := range files {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(src), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	cfg, err := LoadConfig(sub)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := NewChecker()
+	if err := cfg.Apply(c); err != nil {
+		t.Fatal(err)
+	}
+	if !c.CheckGenerated {
+		t.Error("CheckGenerated wasn't set")
+	}
+	if !c.Experimental {
+		t.Error("Experimental wasn't set")
+	}
+	if !c.IncludeTests {
+		t.Error("IncludeTests wasn't set")
+	}
+	if !c.DeferInLoopClosersOnly {
+		t.Error("DeferInLoopClosersOnly wasn't set")
+	}
+	if c.PerFuncTimeout != 30*time.Second {
+		t.Errorf("PerFuncTimeout = %v, want 30s", c.PerFuncTimeout)
+	}
+	if want := []string{"(*example.com/m/spin.SpinLock).Lock"}; !reflect.DeepEqual(c.LockTypes, want) {
+		t.Errorf("LockTypes = %q, want %q", c.LockTypes, want)
+	}
+	if want := []string{"example.com/m/store.release"}; !reflect.DeepEqual(c.UnlockHelpers, want) {
+		t.Errorf("UnlockHelpers = %q, want %q", c.UnlockHelpers, want)
+	}
+	if c.Severity["SA2043"] != lint.SeverityWarning {
+		t.Errorf("severity of SA2043 = %v, want warning", c.Severity["SA2043"])
+	}
+	if _, ok := c.Funcs()["SA2054"]; !ok {
+		t.Error("SA2054 wasn't enabled")
+	}
+	if _, ok := c.Funcs()["SA2008"]; ok {
+		t.Error("SA2008 wasn't disabled")
+	}
+
+	for _, bad := range []*Config{
+		{Severity: map[string]string{"SA9999": "error"}},
+		{Severity: map[string]string{"SA2043": "fatal"}},
+		{DisabledChecks: []string{"SA9999"}},
+		{PerFuncTimeout: "30"},
+	} {
+		if err := bad.Apply(NewChecker()); err == nil {
+			t.Errorf("Apply(%+v) didn't fail", bad)
+		}
+	}
+}
+
+// Without CheckGenerated and the options, see
+// testdata/CheckEmptyCriticalSectionGenerated.go.
+func TestGenerated(t *testing.T) {
+	pkgs := map[string]map[string]string{
+		"sync": {"sync.go": fakeSync},
+		"gen": {
+			"a.go": `package gen
+
+import "sync"
+
+var mu sync.Mutex
+
+func A() {
+	mu.Lock()
+	mu.Unlock()
+}
+`,
+			"b.go": `// Code generated by stringer. DO NOT EDIT.
+
+package gen
+
+func B() {
+	mu.Lock()
+	mu.Unlock()
+}
+`,
+			"c.go": `// Autogenerated by mytool from c.proto.
+
+package gen
+
+func C() {
+	mu.Lock()
+	mu.Unlock()
+}
+`,
+			"d.pb.go": `package gen
+
+func D() {
+	mu.Lock()
+	mu.Unlock()
+}
+`,
+		},
+	}
+
+	tests := []struct {
+		checkGenerated bool
+		markers        []string
+		files          []string
+		want           []string // files with SA2001 problems
+	}{
+		{true, nil, nil, []string{"a.go", "b.go", "c.go", "d.pb.go"}},
+		{false, []string{`^// Autogenerated by`}, nil, []string{"a.go", "b.go", "d.pb.go"}},
+		{false, nil, []string{"*.pb.go"}, []string{"a.go", "c.go"}},
+	}
+	for _, tt := range tests {
+		c := NewChecker()
+		c.CheckGenerated = tt.checkGenerated
+		c.GeneratedMarkers = tt.markers
+		c.GeneratedFiles = tt.files
+		var got []string
+		for _, p := range lintFake(t, pkgs, c, "SA2001") {
+			got = append(got, filepath.Base(p.Position.Filename))
+		}
+		sort.Strings(got)
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("CheckGenerated %t, GeneratedMarkers %q, GeneratedFiles %q: got SA2001 in %q, want %q",
+				tt.checkGenerated, tt.markers, tt.files, got, tt.want)
+		}
+	}
+}
+
+// Without IncludeTests, see testdata/CheckDoubleLock_test.go.
+func TestIncludeTests(t *testing.T) {
+	c := NewChecker()
+	c.IncludeTests = true
+	if got, want := problemLines(lintFixture(t, "CheckDoubleLock_test.go", c, "SA2005")), []int{17}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got SA2005 at lines %v, want %v", got, want)
+	}
+}
+
+func TestChecks(t *testing.T) {
+	c := NewChecker()
+	checks := c.Checks()
+	if len(checks) != len(c.Funcs()) {
+		t.Fatalf("got %d checks, want %d", len(checks), len(c.Funcs()))
+	}
+	for i, check := range checks {
+		if check.Title == "" || check.Description == "" {
+			t.Errorf("%s isn't documented", check.Code)
+		}
+		if _, err := lint.ParseSeverity(check.DefaultSeverity); err != nil {
+			t.Errorf("%s: %v", check.Code, err)
+		}
+		if i > 0 && checks[i-1].Code >= check.Code {
+			t.Errorf("%s listed after %s", check.Code, checks[i-1].Code)
+		}
+	}
+
+	c.DisabledChecks = []string{"SA2008"}
+	for _, check := range c.Checks() {
+		switch check.Code {
+		case "SA2008":
+			t.Error("disabled check is listed")
+		case "SA2048":
+			t.Error("experimental check is listed")
+		case "SA2054":
+			t.Error("opt-in check is listed")
+		}
+	}
+	c.Experimental = true
+	if _, ok := c.Funcs()["SA2048"]; !ok {
+		t.Error("experimental check isn't run with Experimental set")
+	}
+	c.EnabledChecks = []string{"SA2054"}
+	if _, ok := c.Funcs()["SA2054"]; !ok {
+		t.Error("opt-in check isn't run when enabled")
+	}
+}
+
+func TestLoadProgramBuildConstraints(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gcbd")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	files := map[string]string{
+		"p.go":         "package p\n",
+		"p_linux.go":   "package p\n\nfunc linux() {}\n",
+		"p_windows.go": "package p\n\nfunc windows() {}\n",
+		"tagged.go":    "//go:build race\n\npackage p\n\nfunc race() {}\n",
+	}
+	var paths []string
+	for name, src := range files {
+		path := filepath.Join(dir, name)
+		if err := ioutil.WriteFile(path, []byte(src), 0644); err != nil {
+			t.Fatal(err)
+		}
+		paths = append(paths, path)
+	}
+
+	for _, tt := range []struct {
+		opt  lintutil.Options
+		want []string
+	}{
+		{lintutil.Options{GOOS: "linux"}, []string{"linux"}},
+		{lintutil.Options{GOOS: "windows"}, []string{"windows"}},
+		{lintutil.Options{GOOS: "darwin", Tags: []string{"race"}}, []string{"race"}},
+	} {
+		prog, err := lintutil.LoadProgram(paths, &tt.opt)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got []string
+		for _, fn := range prog.InitialFunctions {
+			if fn.Name() != "init" {
+				got = append(got, fn.Name())
+			}
+		}
+		sort.Strings(got)
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("with %+v, got functions %v, want %v", tt.opt, got, tt.want)
+		}
+	}
+}
+
+func TestReset(t *testing.T) {
+	prog := loadFixture(t, "CheckDoubleLock.go")
+	c := NewChecker()
+	l := &lint.Linter{Checker: c}
+	cold := l.LintProgram(prog)
+	if len(cold) == 0 {
+		t.Fatal("no problems found")
+	}
+	if warm := l.LintProgram(prog); !reflect.DeepEqual(warm, cold) {
+		t.Errorf("running again: got %v, want %v", warm, cold)
+	}
+	c.Reset()
+	if warm := l.LintProgram(prog); !reflect.DeepEqual(warm, cold) {
+		t.Errorf("after Reset: got %v, want %v", warm, cold)
+	}
+}
+
+func TestCache(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gcbd")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "p.go")
+	write := func(src string) {
+		if err := ioutil.WriteFile(path, []byte(src), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("package p\n\nfunc a() {}\n")
+
+	cache := lintutil.NewCache([]string{path}, nil)
+	prog, err := cache.Program()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if again, err := cache.Program(); err != nil || again != prog {
+		t.Fatalf("unchanged sources loaded again: %v", err)
+	}
+
+	write("package p\n\nfunc b() {}\n")
+	changed, err := cache.Program()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if changed == prog {
+		t.Fatal("changed sources weren't loaded again")
+	}
+	var got []string
+	for _, fn := range changed.InitialFunctions {
+		if fn.Name() != "init" {
+			got = append(got, fn.Name())
+		}
+	}
+	if want := []string{"b"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got functions %v, want %v", got, want)
+	}
 }
 
 func BenchmarkStdlib(b *testing.B) {
@@ -39,3 +783,61 @@ func BenchmarkNetHttp(b *testing.B) {
 		}
 	}
 }
+
+// BenchmarkNetHttpWarm measures checking net/http again while its
+// sources don't change, which reuses the program kept by a Cache and
+// what the checker computed on the previous run, to compare with
+// BenchmarkNetHttp, which loads and checks it from scratch.
+func BenchmarkNetHttpWarm(b *testing.B) {
+	cache := lintutil.NewCache([]string{"net/http"}, nil)
+	prog, err := cache.Program()
+	if err != nil {
+		b.Fatal(err)
+	}
+	l := &lint.Linter{Checker: NewChecker(), GoVersion: prog.GoVersion}
+	l.LintProgram(prog)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		prog, err := cache.Program()
+		if err != nil {
+			b.Fatal(err)
+		}
+		l.LintProgram(prog)
+	}
+}
+
+// BenchmarkNetHttpChanged measures checking a package importing
+// net/http again after each change to it. The Cache loads the whole
+// program again, net/http included, so this costs about as much as the
+// first run: nothing computed for the unchanged packages is reused.
+func BenchmarkNetHttpChanged(b *testing.B) {
+	dir, err := ioutil.TempDir("", "gcbd")
+	if err != nil {
+		b.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "p.go")
+	write := func(i int) {
+		src := fmt.Sprintf("package p\n\nimport \"net/http\"\n\nvar c%d = http.DefaultClient\n", i)
+		if err := ioutil.WriteFile(path, []byte(src), 0644); err != nil {
+			b.Fatal(err)
+		}
+	}
+	write(0)
+	cache := lintutil.NewCache([]string{path}, nil)
+	prog, err := cache.Program()
+	if err != nil {
+		b.Fatal(err)
+	}
+	l := &lint.Linter{Checker: NewChecker(), GoVersion: prog.GoVersion}
+	l.LintProgram(prog)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		write(i + 1)
+		prog, err := cache.Program()
+		if err != nil {
+			b.Fatal(err)
+		}
+		l.LintProgram(prog)
+	}
+}
@@ -0,0 +1,298 @@
+package staticcheck
+
+import (
+	"github.com/Tengfei1010/GCBDetector/ssa"
+)
+
+// lockKeyFunc maps a lock or unlock call to the identity of the lock
+// it operates on. It returns false if the lock can't be identified.
+type lockKeyFunc func(call ssa.CallInstruction) (string, bool)
+
+// lockEvent describes the effect of an instruction on a lock.
+type lockEvent int
+
+const (
+	noLockEvent lockEvent = iota
+	acquire
+	release
+)
+
+// lockOpFunc classifies an instruction as acquiring or releasing the
+// lock identified by key.
+type lockOpFunc func(ins ssa.Instruction) (key string, ev lockEvent)
+
+// callLockOps returns a lockOpFunc recognizing calls to Lock and
+// Unlock, identifying the locks with key.
+func (c *Checker) callLockOps(key lockKeyFunc) lockOpFunc {
+	return func(ins ssa.Instruction) (string, lockEvent) {
+		call, ok := ins.(*ssa.Call)
+		if !ok {
+			return "", noLockEvent
+		}
+		var ev lockEvent
+		switch {
+		case c.isCallToUnlock(call.Common()):
+			ev = release
+		case c.isCallToLock(call.Common()):
+			ev = acquire
+		default:
+			return "", noLockEvent
+		}
+		k, ok := key(call)
+		if !ok {
+			return "", noLockEvent
+		}
+		return k, ev
+	}
+}
+
+// lockSet is the set of locks held at a program point. must contains
+// the locks held on every path reaching the point, may those held on
+// at least one path.
+type lockSet struct {
+	may  map[string]bool
+	must map[string]bool
+}
+
+func newLockSet() lockSet {
+	return lockSet{may: map[string]bool{}, must: map[string]bool{}}
+}
+
+func (ls lockSet) copy() lockSet {
+	out := newLockSet()
+	for k := range ls.may {
+		out.may[k] = true
+	}
+	for k := range ls.must {
+		out.must[k] = true
+	}
+	return out
+}
+
+func (ls lockSet) equal(other lockSet) bool {
+	return sameKeys(ls.may, other.may) && sameKeys(ls.must, other.must)
+}
+
+func sameKeys(a, b map[string]bool) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for k := range a {
+		if !b[k] {
+			return false
+		}
+	}
+	return true
+}
+
+// transfer applies the effect of a single instruction to ls. Deferred
+// calls don't run until the function returns and are ignored.
+func (ls lockSet) transfer(ins ssa.Instruction, ops lockOpFunc) {
+	k, ev := ops(ins)
+	switch ev {
+	case release:
+		delete(ls.may, k)
+		delete(ls.must, k)
+	case acquire:
+		ls.may[k] = true
+		ls.must[k] = true
+	}
+}
+
+// lockSets holds the result of the held-lock dataflow analysis of a
+// single function.
+type lockSets struct {
+	ops lockOpFunc
+	in  map[*ssa.BasicBlock]lockSet
+}
+
+// computeLockSets runs a forward dataflow analysis over the blocks of
+// fn, computing which locks are held on entry to every block. Locks
+// held by the caller aren't known and are assumed not to be held.
+func computeLockSets(fn *ssa.Function, ops lockOpFunc) *lockSets {
+	ls := &lockSets{ops: ops, in: map[*ssa.BasicBlock]lockSet{}}
+	if len(fn.Blocks) == 0 {
+		return ls
+	}
+	out := map[*ssa.BasicBlock]lockSet{}
+	entries := []*ssa.BasicBlock{fn.Blocks[0]}
+	if fn.Recover != nil {
+		entries = append(entries, fn.Recover)
+	}
+	for _, b := range entries {
+		ls.in[b] = newLockSet()
+	}
+
+	for changed := true; changed; {
+		changed = false
+		for _, b := range fn.Blocks {
+			in := ls.in[b]
+			if !isEntry(b, entries) {
+				var ok bool
+				in, ok = meetPreds(b, out)
+				if !ok {
+					// no predecessor has been visited yet
+					continue
+				}
+				ls.in[b] = in
+			}
+			state := in.copy()
+			for _, ins := range b.Instrs {
+				state.transfer(ins, ops)
+			}
+			if old, ok := out[b]; !ok || !old.equal(state) {
+				out[b] = state
+				changed = true
+			}
+		}
+	}
+	return ls
+}
+
+func isEntry(b *ssa.BasicBlock, entries []*ssa.BasicBlock) bool {
+	for _, e := range entries {
+		if b == e {
+			return true
+		}
+	}
+	return false
+}
+
+// meetPreds combines the out states of all visited predecessors of b:
+// may is their union, must their intersection.
+func meetPreds(b *ssa.BasicBlock, out map[*ssa.BasicBlock]lockSet) (lockSet, bool) {
+	var res lockSet
+	first := true
+	for _, pred := range b.Preds {
+		state, ok := out[pred]
+		if !ok {
+			continue
+		}
+		if first {
+			res = state.copy()
+			first = false
+			continue
+		}
+		for k := range state.may {
+			res.may[k] = true
+		}
+		for k := range res.must {
+			if !state.must[k] {
+				delete(res.must, k)
+			}
+		}
+	}
+	return res, !first
+}
+
+// at returns the locks held immediately before ins is executed.
+func (ls *lockSets) at(ins ssa.Instruction) lockSet {
+	b := ins.Block()
+	in, ok := ls.in[b]
+	if !ok {
+		// unreachable block
+		return newLockSet()
+	}
+	state := in.copy()
+	for _, other := range b.Instrs {
+		if other == ins {
+			break
+		}
+		state.transfer(other, ls.ops)
+	}
+	return state
+}
+
+// fieldLockSets caches the held-lock analysis of functions, with locks
+// identified by the struct field they are stored in, see fieldLockKey.
+type fieldLockSets struct {
+	ops  lockOpFunc
+	sets map[*ssa.Function]*lockSets
+}
+
+// newFieldLockSets returns an empty cache of the analysis with the
+// lock operations ops, which should be keyed by fieldLockKey.
+func newFieldLockSets(ops lockOpFunc) *fieldLockSets {
+	return &fieldLockSets{ops: ops, sets: map[*ssa.Function]*lockSets{}}
+}
+
+// at returns the field locks held on every path reaching ins.
+func (fls *fieldLockSets) at(ins ssa.Instruction) map[string]bool {
+	fn := ins.Parent()
+	ls, ok := fls.sets[fn]
+	if !ok {
+		ls = computeLockSets(fn, fls.ops)
+		fls.sets[fn] = ls
+	}
+	return ls.at(ins).must
+}
+
+// HeldState describes whether a lock is held at a program point.
+type HeldState int
+
+const (
+	// MayHeld means the lock is held on some, but not all, paths
+	// reaching the point.
+	MayHeld HeldState = iota + 1
+	// MustHeld means the lock is held on every path reaching the
+	// point.
+	MustHeld
+)
+
+func (s HeldState) String() string {
+	switch s {
+	case MayHeld:
+		return "may be held"
+	case MustHeld:
+		return "must be held"
+	}
+	return "not held"
+}
+
+// lockKey identifies the lock operated on by a lock or unlock call,
+// see lockIdentity. Locks in struct fields are identified by the
+// field, as by fieldLockKey; other locks as by getLockPrefix.
+func lockKey(call ssa.CallInstruction) string {
+	recv := lockReceiver(call.Common())
+	if recv == nil {
+		return call.Common().String()
+	}
+	key, field := lockIdentity(recv)
+	if field != "" {
+		return field
+	}
+	return key
+}
+
+// LockState returns the locks held immediately before instr is
+// executed, keyed as by lockKey. Locks that aren't held on any path
+// are omitted. Locks acquired by callers of instr's function aren't
+// taken into account.
+//
+// The analysis runs once per function; its result is cached.
+func (c *Checker) LockState(instr ssa.Instruction) map[string]HeldState {
+	fn := instr.Parent()
+	c.lockStatesMu.Lock()
+	ls, ok := c.lockStates[fn]
+	if !ok {
+		ls = computeLockSets(fn, c.callLockOps(func(call ssa.CallInstruction) (string, bool) {
+			return lockKey(call), true
+		}))
+		if c.lockStates == nil {
+			c.lockStates = map[*ssa.Function]*lockSets{}
+		}
+		c.lockStates[fn] = ls
+	}
+	c.lockStatesMu.Unlock()
+
+	set := ls.at(instr)
+	out := make(map[string]HeldState, len(set.may))
+	for k := range set.may {
+		if set.must[k] {
+			out[k] = MustHeld
+		} else {
+			out[k] = MayHeld
+		}
+	}
+	return out
+}
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"github.com/Tengfei1010/GCBDetector/ssa"
 	"go/token"
+	"sort"
 )
 
 // check if called by go
@@ -116,42 +117,59 @@ type ResultInfo struct {
 	AFreeVar *ssa.FreeVar
 	FreeVarHasLoadStore int
 	BindingHasLoadStoreAfterGo int
+	// Go is the go statement starting the anonymous function.
+	Go *ssa.Go
+	// FreeVarAccesses are the accesses to AFreeVar in the goroutine,
+	// stores first.
+	FreeVarAccesses []ssa.Instruction
+	// BindingAccessesAfterGo are the accesses to the binding that may
+	// follow Go, stores first.
+	BindingAccessesAfterGo []ssa.Instruction
 }
 
-func HasBindingLoadStoreAfterGo(bindingLoadStore map[ssa.Value] SharedVarReferrer, instGo *ssa.Go, blockReachability BlockReachability) map[ssa.Value] int {
+// IsRace reports whether the goroutine and its starter both access
+// the shared variable, at least one of them writing to it.
+func (info ResultInfo) IsRace() bool {
+	return info.BindingHasLoadStoreAfterGo >= 2 || info.FreeVarHasLoadStore >= 2
+}
+
+func HasBindingLoadStoreAfterGo(bindingLoadStore map[ssa.Value] SharedVarReferrer, instGo *ssa.Go, blockReachability BlockReachability) (map[ssa.Value] int, map[ssa.Value] []ssa.Instruction) {
 	// 0: Nope; 1: Load; 2: Store; 3: Load&Store
 	result := make(map[ssa.Value] int)
+	accesses := make(map[ssa.Value] []ssa.Instruction)
 
 	for binding, loadAndStore := range bindingLoadStore {
 
-		for _, instLoad := range loadAndStore.LoadInsts {
+		var loads, stores []ssa.Instruction
 
-			if loadAfterGo, ok := IsPotentiallyReachableInst(instGo, instLoad, blockReachability); ok {
-				//fmt.Println("LOAD", instGo, instLoad, loadAfterGo)
-				if loadAfterGo {
-					result[binding] = 1
-					break
-				}
+		for _, instLoad := range loadAndStore.LoadInsts {
+			if loadAfterGo, ok := IsPotentiallyReachableInst(instGo, instLoad, blockReachability); ok && loadAfterGo {
+				loads = append(loads, instLoad)
 			}
 		}
 
 		for _, instStore := range loadAndStore.StoreInsts {
-			if storeAfterGo, ok := IsPotentiallyReachableInst(instGo, instStore, blockReachability); ok {
-				//fmt.Println("STORE", instGo, instStore, storeAfterGo)
-				if storeAfterGo {
-					result[binding] += 2
-					break
-				}
+			if storeAfterGo, ok := IsPotentiallyReachableInst(instGo, instStore, blockReachability); ok && storeAfterGo {
+				stores = append(stores, instStore)
 			}
 		}
+
+		if len(loads) > 0 {
+			result[binding] = 1
+		}
+		if len(stores) > 0 {
+			result[binding] += 2
+		}
+		accesses[binding] = append(stores, loads...)
 	}
 
-	return result
+	return result, accesses
 }
 
-func HasFreeVarLoadStore(freeVarLoadStore map[*ssa.FreeVar] SharedVarReferrer) map[*ssa.FreeVar] int {
+func HasFreeVarLoadStore(freeVarLoadStore map[*ssa.FreeVar] SharedVarReferrer) (map[*ssa.FreeVar] int, map[*ssa.FreeVar] []ssa.Instruction) {
 	// 0: Nope; 1: Load; 2: Store; 3: Load&Store
 	result := make(map[*ssa.FreeVar] int)
+	accesses := make(map[*ssa.FreeVar] []ssa.Instruction)
 
 	for freeVar, loadAndStore := range freeVarLoadStore {
 
@@ -162,9 +180,16 @@ func HasFreeVarLoadStore(freeVarLoadStore map[*ssa.FreeVar] SharedVarReferrer) m
 		if len(loadAndStore.StoreInsts) > 0 {
 			result[freeVar] += 2
 		}
+
+		for _, instStore := range loadAndStore.StoreInsts {
+			accesses[freeVar] = append(accesses[freeVar], instStore)
+		}
+		for _, instLoad := range loadAndStore.LoadInsts {
+			accesses[freeVar] = append(accesses[freeVar], instLoad)
+		}
 	}
 
-	return result
+	return result, accesses
 }
 
 func GetLoadStoreInfo(anonFuncs []*ssa.Function, blockReachability BlockReachability) map[ssa.Value] map[*ssa.Function] ResultInfo {
@@ -184,8 +209,8 @@ func GetLoadStoreInfo(anonFuncs []*ssa.Function, blockReachability BlockReachabi
 
 				bindingLoadStore := GetBindingLoadStore(instMakeClosure.Bindings)
 				freeVarLoadStore := GetFreeVarLoadStore(anonFunc.FreeVars)
-				hbls := HasBindingLoadStoreAfterGo(bindingLoadStore, instGo, blockReachability)
-				hfvls := HasFreeVarLoadStore(freeVarLoadStore)
+				hbls, bindingAccesses := HasBindingLoadStoreAfterGo(bindingLoadStore, instGo, blockReachability)
+				hfvls, freeVarAccesses := HasFreeVarLoadStore(freeVarLoadStore)
 
 				for binding, bindingLoadStore := range hbls {
 
@@ -196,7 +221,8 @@ func GetLoadStoreInfo(anonFuncs []*ssa.Function, blockReachability BlockReachabi
 					freeVar := binding2FreeVars[binding]
 					freeVarLoadStore := hfvls[freeVar]
 
-					resultInfo := ResultInfo{AFreeVar: freeVar, FreeVarHasLoadStore: freeVarLoadStore, BindingHasLoadStoreAfterGo: bindingLoadStore}
+					resultInfo := ResultInfo{AFreeVar: freeVar, FreeVarHasLoadStore: freeVarLoadStore, BindingHasLoadStoreAfterGo: bindingLoadStore,
+						Go: instGo, FreeVarAccesses: freeVarAccesses[freeVar], BindingAccessesAfterGo: bindingAccesses[binding]}
 					results[binding][anonFunc] = resultInfo
 				}
 			}
@@ -206,44 +232,83 @@ func GetLoadStoreInfo(anonFuncs []*ssa.Function, blockReachability BlockReachabi
 	return results
 }
 
-func HasAnonRace(anonFuncs []*ssa.Function, blockReachability BlockReachability) (map[ssa.Value] map[*ssa.Function] ResultInfo, bool) {
+// AnonRaceReport describes a race on a variable shared between a
+// function and an anonymous function it starts as a goroutine.
+type AnonRaceReport struct {
+	// Shared is the variable, as bound in the starting function.
+	Shared ssa.Value
+	// Go is the go statement starting the goroutine.
+	Go *ssa.Go
+	// Access1 is the goroutine's access to the variable, a store if
+	// there is one, and Access2 an access in the starting function
+	// that may follow Go. At least one of them is a store.
+	Access1, Access2 ssa.Instruction
+}
 
-	raceVars := make(map[ssa.Value] map[*ssa.Function] ResultInfo)
+// HasAnonRace returns the races on variables shared between a function
+// and the anonymous functions it starts as goroutines, one per
+// variable, ordered by the position of the variable. For a variable
+// shared with several goroutines, the race with the earliest started
+// one is reported.
+//
+// racy, if not nil, filters the pairs of accesses: it is called with
+// the go statement, the goroutine's access and the starting function's
+// access, and returns false if they can't race, e.g. because the
+// goroutine is waited for in between.
+func HasAnonRace(anonFuncs []*ssa.Function, blockReachability BlockReachability, racy func(g *ssa.Go, inGo, afterGo ssa.Instruction) bool) ([]AnonRaceReport, bool) {
 
-	hasAnonRace := false
+	var reports []AnonRaceReport
 
 	loadStoreInfo := GetLoadStoreInfo(anonFuncs, blockReachability)
 
 	for binding, bindingInfo := range loadStoreInfo {
 
-		// More than 1 go routine shares a var and at least one stores it
-		if len(bindingInfo) > 1 {
-
-			for _, funcInfo := range bindingInfo {
-
-				if funcInfo.FreeVarHasLoadStore >= 2 {
-
-					raceVars[binding] = bindingInfo
+		var race *AnonRaceReport
+		for _, funcInfo := range bindingInfo {
 
-					hasAnonRace = true
+			// As long as there is one Store in either Main or Go routine
+			if !funcInfo.IsRace() {
+				continue
+			}
+			if race != nil && funcInfo.Go.Pos() >= race.Go.Pos() {
+				continue
+			}
+			if access1, access2, ok := racingPair(funcInfo, racy); ok {
+				race = &AnonRaceReport{
+					Shared:  binding,
+					Go:      funcInfo.Go,
+					Access1: access1,
+					Access2: access2,
 				}
 			}
 		}
+		if race != nil {
+			reports = append(reports, *race)
+		}
+	}
 
-		for _, funcInfo := range bindingInfo {
-
-			// As long as there is one Store in either Main or Go routine
-			if funcInfo.BindingHasLoadStoreAfterGo >= 2 || funcInfo.FreeVarHasLoadStore >= 2 {
+	sort.Slice(reports, func(i, k int) bool {
+		return reports[i].Shared.Pos() < reports[k].Shared.Pos()
+	})
 
-				raceVars[binding] = bindingInfo
+	return reports, len(reports) > 0
+}
 
-				hasAnonRace = true
+// racingPair returns the first pair of accesses of info, one in the
+// goroutine and one following the go statement, of which at least one
+// is a store and which racy doesn't rule out.
+func racingPair(info ResultInfo, racy func(g *ssa.Go, inGo, afterGo ssa.Instruction) bool) (ssa.Instruction, ssa.Instruction, bool) {
+	for _, access1 := range info.FreeVarAccesses {
+		_, store1 := access1.(*ssa.Store)
+		for _, access2 := range info.BindingAccessesAfterGo {
+			_, store2 := access2.(*ssa.Store)
+			if !store1 && !store2 {
+				continue
+			}
+			if racy == nil || racy(info.Go, access1, access2) {
+				return access1, access2, true
 			}
 		}
 	}
-
-	return loadStoreInfo, hasAnonRace
+	return nil, nil, false
 }
-
-
-
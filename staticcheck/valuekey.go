@@ -0,0 +1,220 @@
+package staticcheck
+
+import (
+	"go/token"
+
+	"github.com/Tengfei1010/GCBDetector/ssa"
+)
+
+// valueKey identifies an object shared by reference, such as a
+// sync.WaitGroup, a channel or a map: either the value creating it
+// (an *ssa.Alloc, *ssa.Global, *ssa.MakeChan or *ssa.MakeMap) or, for
+// objects stored in struct fields, the field, qualified by its struct
+// type.
+type valueKey struct {
+	v     ssa.Value
+	field string
+}
+
+// name returns the name of the variable or field k denotes.
+func (k valueKey) name() string {
+	switch v := k.v.(type) {
+	case *ssa.Alloc:
+		if name := varName(v); name != "" {
+			// wg := &sync.WaitGroup{}
+			return name
+		}
+		switch v.Comment {
+		case "new", "complit":
+			return ""
+		}
+		return v.Comment
+	case *ssa.Global:
+		return v.Name()
+	case *ssa.MakeChan:
+		return varName(v)
+	case *ssa.MakeMap:
+		if name := varName(v); name != "" {
+			return name
+		}
+		return storedName(v)
+	}
+	return k.field
+}
+
+// storedName returns the name of a variable v is stored in, if any.
+// Variables captured by closures hold their values this way.
+func storedName(v ssa.Value) string {
+	for _, ref := range *v.Referrers() {
+		if st, ok := ref.(*ssa.Store); ok && st.Val == v {
+			if addr, ok := st.Addr.(*ssa.Alloc); ok && addr.Comment != "" {
+				return addr.Comment
+			}
+		}
+	}
+	return ""
+}
+
+// pos returns the position at which the object was declared, if
+// known.
+func (k valueKey) pos() token.Pos {
+	if k.v != nil {
+		return k.v.Pos()
+	}
+	return token.NoPos
+}
+
+// redefinedBy reports whether ins creates or assigns a new channel for
+// the object identified by k, so that closes before and after it
+// operate on different channels.
+func (k valueKey) redefinedBy(ins ssa.Instruction) bool {
+	if mc, ok := ins.(*ssa.MakeChan); ok && ssa.Value(mc) == k.v {
+		// make(chan T) in a loop
+		return true
+	}
+	store, ok := ins.(*ssa.Store)
+	if !ok {
+		return false
+	}
+	switch addr := store.Addr.(type) {
+	case *ssa.FieldAddr:
+		return k.field != "" && fieldName(addr) == k.field
+	default:
+		return k.v != nil && store.Addr == k.v
+	}
+}
+
+// valueKeys resolves v, a pointer such as a *sync.WaitGroup or a
+// channel, to the objects it may refer to. Values passed to closures
+// and parameters are followed back to their callers. ok is false if
+// some of the objects can't be determined.
+func (c *Checker) valueKeys(v ssa.Value) (keys []valueKey, ok bool) {
+	ok = true
+	seen := map[ssa.Value]bool{}
+	var resolve, resolveLoad func(v ssa.Value)
+	// resolveLoad resolves the values stored at addr.
+	resolveLoad = func(addr ssa.Value) {
+		switch addr := addr.(type) {
+		case *ssa.FieldAddr:
+			keys = append(keys, valueKey{field: fieldName(addr)})
+		case *ssa.Global:
+			keys = append(keys, valueKey{v: addr})
+		case *ssa.Alloc:
+			// a local variable, e.g. a parameter captured by
+			// a closure
+			stores := 0
+			for _, ref := range *addr.Referrers() {
+				if st, isStore := ref.(*ssa.Store); isStore && st.Addr == addr {
+					stores++
+					resolve(st.Val)
+				}
+			}
+			if stores == 0 {
+				ok = false
+			}
+		case *ssa.FreeVar:
+			// a captured variable
+			b, found := freeVarBinding(addr)
+			if !found {
+				ok = false
+				return
+			}
+			resolveLoad(b)
+		default:
+			ok = false
+		}
+	}
+	resolve = func(v ssa.Value) {
+		if seen[v] {
+			return
+		}
+		seen[v] = true
+		switch v := v.(type) {
+		case *ssa.Alloc, *ssa.Global, *ssa.MakeChan, *ssa.MakeMap:
+			keys = append(keys, valueKey{v: v})
+		case *ssa.FieldAddr:
+			keys = append(keys, valueKey{field: fieldName(v)})
+		case *ssa.UnOp:
+			// a value stored in a variable or field
+			if v.Op != token.MUL {
+				ok = false
+				return
+			}
+			resolveLoad(v.X)
+		case *ssa.Phi:
+			for _, e := range v.Edges {
+				resolve(e)
+			}
+		case *ssa.FreeVar:
+			b, found := freeVarBinding(v)
+			if !found {
+				ok = false
+				return
+			}
+			resolve(b)
+		case *ssa.Parameter:
+			args := c.parameterArgs(v)
+			if len(args) == 0 {
+				ok = false
+				return
+			}
+			for _, arg := range args {
+				resolve(arg)
+			}
+		default:
+			ok = false
+		}
+	}
+	resolve(v)
+	return keys, ok
+}
+
+// freeVarBinding returns the value bound to fv by the MakeClosure in
+// the enclosing function.
+func freeVarBinding(fv *ssa.FreeVar) (ssa.Value, bool) {
+	fn := fv.Parent()
+	idx := -1
+	for i, other := range fn.FreeVars {
+		if other == fv {
+			idx = i
+		}
+	}
+	if idx == -1 || fn.Parent() == nil {
+		return nil, false
+	}
+	for _, b := range fn.Parent().Blocks {
+		for _, ins := range b.Instrs {
+			mc, ok := ins.(*ssa.MakeClosure)
+			if ok && mc.Fn == fn {
+				return mc.Bindings[idx], true
+			}
+		}
+	}
+	return nil, false
+}
+
+// parameterArgs returns the arguments passed for p at all call sites
+// of its function known to the call graph. It returns nil if there
+// are none, or if some of them are dynamic.
+func (c *Checker) parameterArgs(p *ssa.Parameter) []ssa.Value {
+	fn := p.Parent()
+	idx := -1
+	for i, other := range fn.Params {
+		if other == p {
+			idx = i
+		}
+	}
+	node, ok := c.funcDescs.CallGraph.Nodes[fn]
+	if idx == -1 || !ok {
+		return nil
+	}
+	var args []ssa.Value
+	for _, edge := range node.In {
+		common := edge.Site.Common()
+		if common.IsInvoke() || common.StaticCallee() != fn || idx >= len(common.Args) {
+			return nil
+		}
+		args = append(args, common.Args[idx])
+	}
+	return args
+}
@@ -0,0 +1,73 @@
+package pkg
+
+import (
+	"context"
+	"errors"
+)
+
+func compute() int { return 0 }
+
+func fn1(ctx context.Context) (int, error) {
+	result := make(chan int)
+	go func() { // MATCH /the goroutine blocks on a channel at .*:13:10 without observing the cancellation of ctx/
+		result <- compute() // MATCH /sending on unbuffered channel result blocks the goroutine started at .*:12:2 forever if fn1 returns at .*:17:3 without receiving from it/
+	}()
+	select {
+	case <-ctx.Done():
+		return 0, ctx.Err()
+	case v := <-result:
+		return v, nil
+	}
+}
+
+func fn2(fail bool) error {
+	done := make(chan struct{})
+	go func() {
+		compute()
+		done <- struct{}{} // MATCH /sending on unbuffered channel done blocks the goroutine started at .*:25:2 forever if fn2 returns/
+	}()
+	if fail {
+		return errors.New("failed")
+	}
+	<-done
+	return nil
+}
+
+func fn3(fail bool) error {
+	done := make(chan struct{})
+	go func() {
+		compute()
+		done <- struct{}{}
+	}()
+	<-done
+	if fail {
+		return errors.New("failed")
+	}
+	return nil
+}
+
+func fn4(ctx context.Context) (int, error) {
+	result := make(chan int, 1)
+	go func() {
+		result <- compute()
+	}()
+	select {
+	case <-ctx.Done():
+		return 0, ctx.Err()
+	case v := <-result:
+		return v, nil
+	}
+}
+
+func fn5(fail bool) error {
+	done := make(chan struct{})
+	go func() {
+		compute()
+		close(done)
+	}()
+	if fail {
+		return errors.New("failed")
+	}
+	<-done
+	return nil
+}
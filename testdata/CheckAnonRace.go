@@ -0,0 +1,70 @@
+package pkg
+
+import "sync"
+
+func fn1() int {
+	x := 0
+	go func() {
+		x = 1 // MATCH /variable x is shared with the goroutine started at .*CheckAnonRace.go:7:2: the write here races with the read at .*CheckAnonRace.go:10:9/
+	}()
+	return x
+}
+
+func fn2() {
+	n := 0
+	go func() {
+		println(n) // MATCH /variable n is shared with the goroutine started at .*: the read here races with the write at/
+	}()
+	n = 2
+}
+
+func fn3() int {
+	var wg sync.WaitGroup
+	x := 0
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		println(x)
+	}()
+	wg.Wait()
+	return x
+}
+
+func fn4() int {
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	sum := 0
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		mu.Lock()
+		sum = 1
+		mu.Unlock()
+	}()
+	wg.Wait()
+	return sum
+}
+
+func fn5() int {
+	done := make(chan struct{})
+	total := 0
+	go func() {
+		total = 1
+		close(done)
+	}()
+	<-done
+	return total
+}
+
+func fn6() int {
+	var mu sync.Mutex
+	count := 0
+	go func() {
+		mu.Lock()
+		count++
+		mu.Unlock()
+	}()
+	mu.Lock()
+	defer mu.Unlock()
+	return count
+}
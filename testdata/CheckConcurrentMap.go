@@ -0,0 +1,68 @@
+package pkg
+
+import (
+	"fmt"
+	"sync"
+)
+
+func fn1() {
+	m := map[string]int{}
+	go func() {
+		m["a"] = 1 // MATCH /map m is written by the goroutine started at .*:10:2 and read at .*:13:15 without holding a lock; concurrent map access races and may panic/
+	}()
+	fmt.Println(m["a"])
+}
+
+func fn2(keys []string) {
+	m := map[string]int{}
+	var wg sync.WaitGroup
+	for _, k := range keys {
+		wg.Add(1)
+		go func(k string) {
+			defer wg.Done()
+			m[k]++ // MATCH /map m is written without holding a lock by every goroutine started at .*:21:3; concurrent map writes panic/
+		}(k)
+	}
+	wg.Wait()
+}
+
+func fn3(keys []string) {
+	m := map[string]int{}
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for _, k := range keys {
+		wg.Add(1)
+		go func(k string) {
+			defer wg.Done()
+			mu.Lock()
+			m[k]++
+			mu.Unlock()
+		}(k)
+	}
+	wg.Wait()
+	fmt.Println(len(m))
+}
+
+func fn4() {
+	m := map[string]int{}
+	m["init"] = 0
+	done := make(chan bool)
+	go func() {
+		m["a"] = 1
+		done <- true
+	}()
+	<-done
+	fmt.Println(m["a"])
+}
+
+func fn5() {
+	m := map[string]int{}
+	go func() {
+		delete(m, "a") // MATCH /map m is written by the goroutine started at .*:60:2 and read at .*:64:3/
+	}()
+	go func() {
+		for k := range m {
+			m[k] = 0 // MATCH /map m is written by the goroutine started at .*:63:2 and written at .*:61:9/
+		}
+	}()
+}
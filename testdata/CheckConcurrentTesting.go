@@ -0,0 +1,47 @@
+package pkg
+
+import "testing"
+
+func TestFn1(t *testing.T) {
+	go func() { // MATCH /the goroutine calls T.Fatal, which must be called in the same goroutine as the test/
+		t.Fatal("failed")
+	}()
+}
+
+func check(t *testing.T, err error) {
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func mustRun(t *testing.T) {
+	check(t, nil)
+}
+
+func TestFn2(t *testing.T) {
+	go func() { // MATCH /the goroutine calls T.Fatalf via check, which must be called in the same goroutine as the test/
+		check(t, nil)
+	}()
+}
+
+func TestFn3(t *testing.T) {
+	go mustRun(t) // MATCH /the goroutine calls T.Fatalf via mustRun -> check, which must be called in the same goroutine as the test/
+}
+
+func TestFn4(t *testing.T) {
+	go func() {
+		t.Error("failed")
+	}()
+	check(t, nil)
+}
+
+func retry(t *testing.T, n int) {
+	if n == 0 {
+		t.FailNow()
+	}
+	retry(t, n-1)
+}
+
+func TestFn5(t *testing.T) {
+	go retry(t, 3) // MATCH /the goroutine calls T.FailNow via retry, which/
+}
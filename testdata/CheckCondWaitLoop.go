@@ -0,0 +1,46 @@
+package pkg
+
+import "sync"
+
+type Queue struct {
+	mu    sync.Mutex
+	cond  *sync.Cond
+	items []int
+}
+
+func (q *Queue) Pop() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	for len(q.items) == 0 {
+		q.cond.Wait()
+	}
+	v := q.items[0]
+	q.items = q.items[1:]
+	return v
+}
+
+func (q *Queue) PopOnce() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if len(q.items) == 0 {
+		q.cond.Wait() // MATCH /Cond.Wait should be called in a loop that rechecks the condition/
+	}
+	v := q.items[0]
+	q.items = q.items[1:]
+	return v
+}
+
+func (q *Queue) wait() {
+	q.cond.Wait()
+}
+
+func (q *Queue) PopWrapped() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	for len(q.items) == 0 {
+		q.wait()
+	}
+	v := q.items[0]
+	q.items = q.items[1:]
+	return v
+}
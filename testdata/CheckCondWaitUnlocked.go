@@ -0,0 +1,64 @@
+package pkg
+
+import "sync"
+
+type Queue struct {
+	mu    sync.Mutex
+	cond  *sync.Cond
+	items []int
+}
+
+func NewQueue() *Queue {
+	q := &Queue{}
+	q.cond = sync.NewCond(&q.mu)
+	return q
+}
+
+func (q *Queue) Pop() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	for len(q.items) == 0 {
+		q.cond.Wait()
+	}
+	v := q.items[0]
+	q.items = q.items[1:]
+	return v
+}
+
+func (q *Queue) WaitEmpty() {
+	for len(q.items) != 0 {
+		q.cond.Wait() // MATCH /Cond.Wait is called without holding .*Queue.mu, the Cond's lock; Wait unlocks it before waiting/
+	}
+}
+
+func (q *Queue) WaitViaL() {
+	q.cond.L.Lock()
+	for len(q.items) != 0 {
+		q.cond.Wait()
+	}
+	q.cond.L.Unlock()
+}
+
+func fn1(ready func() bool) {
+	var mu sync.Mutex
+	cond := sync.NewCond(&mu)
+	for !ready() {
+		cond.Wait() // MATCH /Cond.Wait is called without holding mu, the Cond's lock/
+	}
+}
+
+func fn2(ready func() bool) {
+	var mu sync.Mutex
+	cond := sync.NewCond(&mu)
+	mu.Lock()
+	for !ready() {
+		cond.Wait()
+	}
+	mu.Unlock()
+}
+
+func fn3(cond *sync.Cond, ready func() bool) {
+	for !ready() {
+		cond.Wait()
+	}
+}
@@ -0,0 +1,61 @@
+package pkg
+
+import "sync"
+
+type Registry struct {
+	mu    sync.RWMutex
+	items map[string]int
+}
+
+func (r *Registry) fn1(k string, write bool) {
+	if write {
+		r.mu.Lock()
+	}
+	r.items[k]++
+	r.mu.Unlock() // MATCH /Unlock of field mu, which is only locked at .*:12:12 on some of the paths reaching it/
+}
+
+func (r *Registry) fn2(k string, write bool) {
+	if write {
+		r.mu.Lock()
+	}
+	defer r.mu.Unlock() // MATCH /Unlock of field mu, which is only locked at .*:20:12 on some of the paths reaching it/
+	r.items[k]++
+}
+
+func (r *Registry) fn3(k string, write bool) {
+	locked := false
+	if write {
+		r.mu.Lock() // MATCH /the lock acquired by Lock is not released on the path reaching the end of the function/
+		locked = true
+	}
+	r.items[k]++
+	if locked {
+		r.mu.Unlock()
+	}
+}
+
+func (r *Registry) fn4(k string) {
+	r.mu.Lock()
+	r.items[k]++
+	r.mu.Unlock()
+}
+
+func (r *Registry) fn5(keys []string) {
+	for i, k := range keys {
+		if i > 0 {
+			r.mu.Unlock()
+		}
+		r.mu.Lock()
+		r.items[k]++
+	}
+	r.mu.Unlock()
+}
+
+func use(r *Registry) {
+	r.fn1("a", true)
+	r.fn2("a", true)
+	r.fn3("a", true)
+	r.fn4("a")
+	r.fn5(nil)
+}
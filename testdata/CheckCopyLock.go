@@ -0,0 +1,72 @@
+package pkg
+
+import (
+	"fmt"
+	"sync"
+)
+
+type Counter struct {
+	mu sync.Mutex
+	n  int
+}
+
+type Stats struct {
+	c    Counter
+	name string
+}
+
+func (c Counter) Value() int { // MATCH /Value has a value receiver, so every call copies the sync.Mutex in Counter; use a pointer receiver/
+	return c.n
+}
+
+func (c *Counter) Inc() {
+	c.mu.Lock()
+	c.n++
+	c.mu.Unlock()
+}
+
+func use(c Counter) {}
+
+func usePtr(c *Counter) {}
+
+func fn1(c *Counter) {
+	use(*c) // MATCH /call of use copies lock value: Counter contains sync.Mutex/
+	usePtr(c)
+	use(Counter{})
+}
+
+func fn2(s *Stats) {
+	c := s.c // MATCH /assignment copies lock value to c: Counter contains sync.Mutex/
+	c.Inc()
+	var d = s.c // MATCH /variable declaration copies lock value to d: Counter contains sync.Mutex/
+	d.Inc()
+	p := &s.c
+	p.Inc()
+}
+
+func fn3(s *Stats) Counter {
+	return s.c // MATCH /return copies lock value: Counter contains sync.Mutex/
+}
+
+func fn4(s *Stats) Stats {
+	return Stats{name: s.name}
+}
+
+func fn5(cs []Counter) {
+	for _, c := range cs { // MATCH /range variable c copies lock value: each element contains sync.Mutex/
+		fmt.Println(c.n)
+	}
+	for i := range cs {
+		cs[i].Inc()
+	}
+}
+
+func fn6(wg *sync.WaitGroup) {
+	fmt.Println(*wg) // MATCH /call of fmt.Println copies lock value: sync.WaitGroup/
+}
+
+func fn7(ps []*Counter) {
+	for _, p := range ps {
+		p.Inc()
+	}
+}
@@ -0,0 +1,80 @@
+package pkg
+
+import (
+	"os"
+	"sync"
+)
+
+func fn1(names []string) {
+	for _, name := range names {
+		f, err := os.Open(name)
+		if err != nil {
+			continue
+		}
+		defer f.Close() // MATCH /deferred Close runs when the function returns, not at the end of the loop iteration/
+		f.Stat()
+	}
+}
+
+func fn2(names []string) {
+	for _, name := range names {
+		defer println(name) // MATCH /defer in a loop runs when the function returns/
+	}
+}
+
+func fn3(names []string) {
+	for _, name := range names {
+		f, err := os.Open(name)
+		if err != nil {
+			continue
+		}
+		defer func() { // MATCH /defer in a loop runs when the function returns/
+			f.Close()
+		}()
+	}
+}
+
+func fn4(names []string) {
+	for _, name := range names {
+		func() {
+			f, err := os.Open(name)
+			if err != nil {
+				return
+			}
+			defer f.Close()
+			f.Stat()
+		}()
+	}
+}
+
+func fn5(name string) {
+	f, err := os.Open(name)
+	if err != nil {
+		return
+	}
+	defer f.Close()
+	for i := 0; i < 10; i++ {
+		f.Stat()
+	}
+}
+
+func fn6(names []string) *os.File {
+	for _, name := range names {
+		f, err := os.Open(name)
+		if err != nil {
+			continue
+		}
+		defer println(name)
+		return f
+	}
+	return nil
+}
+
+var mu sync.Mutex
+
+func fn7(n int) {
+	for i := 0; i < n; i++ {
+		mu.Lock()
+		defer mu.Unlock() // MATCH /deferred Unlock runs when the function returns/
+	}
+}
@@ -1,9 +1,6 @@
 package main
 
-import (
-	"fmt"
-	"sync"
-)
+import "sync"
 
 var r sync.Mutex
 var rw sync.RWMutex
@@ -16,7 +13,7 @@ func fn1() {
 
 func fn2() {
 	r.Lock()
-	defer r.Unlock()
+	defer r.Unlock() // MATCH /empty critical section/
 }
 
 func fn3() {
@@ -31,10 +28,25 @@ func fn4() {
 
 func fn5() {
 	rw.RLock()
-	defer rw.Lock()
+	defer rw.Lock() // MATCH /deferring Lock right after acquiring the lock with RLock; did you mean to defer RUnlock/
 }
 
 func fn6() {
 	r.Lock()
 	defer rw.Lock()
 }
+
+func fn7() {
+	rw.Lock()
+	defer rw.RLock() // MATCH /deferring RLock right after acquiring the lock with Lock; did you mean to defer Unlock/
+}
+
+func fn8() {
+	rw.Lock()
+	defer rw.Lock() // MATCH /deferring Lock right after having locked already; did you mean to defer Unlock/
+}
+
+func fn9(l sync.Locker) {
+	l.Lock()
+	defer l.Lock() // MATCH /deferring Lock right after having locked already; did you mean to defer Unlock/
+}
@@ -0,0 +1,56 @@
+package pkg
+
+import "sync"
+
+type Store struct {
+	mu    sync.RWMutex
+	items map[string]int
+}
+
+func (s *Store) AddAll(keys []string) {
+	for _, k := range keys {
+		s.mu.Lock()
+		defer s.mu.Unlock() // MATCH /deferred Unlock runs when the function returns, not at the end of the loop iteration, so the lock acquired at .*:12:12 is acquired again while held/
+		s.items[k]++
+	}
+}
+
+func (s *Store) Sum(keys []string) int {
+	n := 0
+	for _, k := range keys {
+		s.mu.RLock()
+		defer s.mu.RUnlock() // MATCH /deferred RUnlock runs when the function returns/
+		n += s.items[k]
+	}
+	return n
+}
+
+func (s *Store) Reset(keys []string) {
+	s.mu.Lock()
+	for _, k := range keys {
+		if k == "" {
+			defer s.mu.Unlock()
+			return
+		}
+		delete(s.items, k)
+	}
+	s.mu.Unlock()
+}
+
+func (s *Store) Update(keys []string) {
+	for _, k := range keys {
+		func() {
+			s.mu.Lock()
+			defer s.mu.Unlock()
+			s.items[k] = 0
+		}()
+	}
+}
+
+func (s *Store) Count(keys []string) {
+	for _, k := range keys {
+		s.mu.Lock()
+		s.items[k]++
+		s.mu.Unlock()
+	}
+}
@@ -0,0 +1,40 @@
+package pkg
+
+import "sync"
+
+type Registry struct {
+	mu    sync.RWMutex
+	items map[string]int
+}
+
+func (r *Registry) Get(k string) int {
+	r.mu.RLock()
+	defer r.mu.Unlock() // MATCH /deferring Unlock, but the lock was acquired with RLock at .*:11:12; did you mean to defer RUnlock\?/
+	return r.items[k]
+}
+
+func (r *Registry) Set(k string, v int) {
+	r.mu.Lock()
+	defer r.mu.RUnlock() // MATCH /deferring RUnlock, but the lock was acquired with Lock at .*:17:11; did you mean to defer Unlock\?/
+	r.items[k] = v
+}
+
+func (r *Registry) Len() int {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return len(r.items)
+}
+
+func (r *Registry) Delete(k string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.items, k)
+}
+
+func (r *Registry) Swap(other *Registry) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	other.mu.RLock()
+	defer other.mu.RUnlock()
+	r.items, other.items = other.items, r.items
+}
@@ -0,0 +1,56 @@
+package pkg
+
+import "sync"
+
+type Store struct {
+	mu    sync.Mutex
+	items map[string]int
+	dirty bool
+}
+
+func (s *Store) flush() {}
+
+func (s *Store) Set(k string, v int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	defer func() {
+		s.mu.Lock() // MATCH /field mu is acquired by the function deferred at .*CheckDeferredRelock.go:16:2 while it is still held: defers run in reverse order, so the unlock deferred at .*CheckDeferredRelock.go:15:2 runs after it/
+		s.flush()
+		s.mu.Unlock()
+	}()
+	s.items[k] = v
+}
+
+func (s *Store) Delete(k string) {
+	s.mu.Lock()
+	defer func() {
+		s.mu.Lock()
+		s.dirty = true
+		s.mu.Unlock()
+	}()
+	defer s.mu.Unlock()
+	delete(s.items, k)
+}
+
+func (s *Store) Clear() {
+	s.mu.Lock()
+	defer func() {
+		s.mu.Lock()
+		s.flush()
+		s.mu.Unlock()
+	}()
+	s.items = nil
+	s.mu.Unlock()
+}
+
+var mu sync.Mutex
+
+func fn1() {
+	mu.Lock()
+	defer func() {
+		mu.Lock() // MATCH /mu is acquired by the function deferred at .*CheckDeferredRelock.go:50:2 while it is still held when fn1 returns/
+		println()
+		mu.Unlock()
+	}()
+	println()
+}
@@ -0,0 +1,62 @@
+package pkg
+
+import "sync"
+
+func fn1(b bool) {
+	ch := make(chan int)
+	if b {
+		close(ch)
+	}
+	close(ch) // MATCH /channel ch may already have been closed at .*CheckDoubleClose.go:8:8; closing a closed channel panics/
+}
+
+func fn2(b bool) {
+	ch := make(chan int)
+	if b {
+		close(ch)
+	} else {
+		close(ch)
+	}
+}
+
+func fn3() {
+	ch := make(chan int)
+	defer close(ch) // MATCH /channel ch is closed at .* and closed again by this deferred close when the function returns/
+	close(ch)
+}
+
+func fn4(n int) {
+	for i := 0; i < n; i++ {
+		ch := make(chan int)
+		close(ch)
+	}
+}
+
+func fn5(n int) {
+	ch := make(chan int)
+	for i := 0; i < n; i++ {
+		close(ch) // MATCH /channel ch is closed in a loop and may be closed again in a later iteration/
+	}
+}
+
+type Server struct {
+	once sync.Once
+	done chan struct{}
+}
+
+func (s *Server) Close() {
+	s.once.Do(func() {
+		close(s.done)
+	})
+}
+
+func (s *Server) Stop() {
+	s.Close()
+	s.Close()
+}
+
+func (s *Server) Reset() {
+	close(s.done)
+	s.done = make(chan struct{})
+	close(s.done)
+}
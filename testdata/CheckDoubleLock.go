@@ -16,21 +16,21 @@ func fn7() {
 	r.Lock()
 	i := 1
 	fmt.Println(i)
-	r.Lock()
+	r.Lock() // MATCH /mutex re-acquired here \(previously acquired at .*CheckDoubleLock.go:16:8\)/
 }
 
 func fn8() {
 	rw.Lock()
 	i := 1
 	fmt.Println(i)
-	rw.Lock()
+	rw.Lock() // MATCH /mutex re-acquired here \(previously acquired at .*CheckDoubleLock.go:23:9\)/
 }
 
 func fn9() {
 	r.Lock()
 	i := 1
 	fmt.Println(i)
-	rw.Lock()
+	rw.Lock() // MATCH /rw is acquired while holding r, but r is acquired while holding rw/
 }
 
 func fn10() {
@@ -38,9 +38,9 @@ func fn10() {
 	rw.RLock()
 	i := 1
 	fmt.Println(i)
-	r.Unlock()
-	r.Lock()
-	rw.RLock()
+	r.Unlock() // MATCH:42 /r is acquired while holding rw, but rw is acquired while holding r elsewhere/
+	r.Lock() // MATCH /the lock acquired by Lock is not released on the path reaching the end of the function/
+	rw.RLock() // MATCH /read lock re-acquired here \(previously acquired at .*CheckDoubleLock.go:38:10\)/
 }
 
 
@@ -48,7 +48,7 @@ func fn11() {
 	r.Lock()
 	i := 0
 	fmt.Println(i)
-	r.Lock()
+	r.Lock() // MATCH /mutex re-acquired here \(previously acquired at .*CheckDoubleLock.go:48:8\)/
 }
 
 func fn12() {
@@ -60,7 +60,7 @@ func fn12() {
 
 func fn133_(i int) int {
 	if i > 100 {
-		r.Lock()
+		r.Lock() // MATCH /mutex re-acquired here \(previously acquired at .*CheckDoubleLock.go:83:8\)/
 		defer r.Unlock()
 		i = i + 1
 	}
@@ -90,7 +90,7 @@ func fn14() {
 	defer rw.RUnlock()
 	i := 0
 	fmt.Println(i)
-	rw.Lock()
+	rw.Lock() // MATCH /Lock is called while the read lock acquired at .* is still held/
 	i = 0
 	fmt.Println(i)
 	rw.Unlock()
@@ -103,11 +103,11 @@ func fn15() {
 	fmt.Println(i)
 	rw.RUnlock()
 	rw.Lock()
-	rw.Unlock()
+	rw.Unlock() // MATCH /empty critical section/
 }
 
 func fn15_() {
-	r.Lock()
+	r.Lock() // MATCH /mutex re-acquired here \(previously acquired at .*CheckDoubleLock.go:197:8\)/
 	i := 0
 	fmt.Println(i)
 	r.Unlock()
@@ -118,7 +118,7 @@ func fn16(a int) {
 	r.Lock()
 	i = a
 	if i >= 0 {
-		r.Lock()
+		r.Lock() // MATCH /mutex re-acquired here \(previously acquired at .*CheckDoubleLock.go:118:8\)/
 	}
 	r.Unlock()
 }
@@ -127,7 +127,7 @@ func fn17(a int) {
 	i := 0
 	r.Lock()
 	i = a
-	r.Unlock()
+	r.Unlock() // MATCH /Unlock right after Lock; did you mean to defer Unlock\?/
 	if i >= 0 {
 		r.Lock()
 		i += 10
@@ -227,7 +227,7 @@ func fn19(a int) {
 			fmt.Println("operate channel error")
 		}
 
-		r.Lock()
+		r.Lock() // MATCH /mutex re-acquired here \(previously acquired at .*CheckDoubleLock.go:197:8\)/
 		a = 10
 		fmt.Println(a)
 		r.Unlock()
@@ -266,3 +266,99 @@ func fn20() {
 
 	}
 }
+
+// goto skips the Unlock and jumps straight to the second Lock
+func fn22(a int) {
+	r.Lock()
+	if a > 0 {
+		goto relock
+	}
+	r.Unlock()
+relock:
+	r.Lock() // MATCH /mutex re-acquired here \(previously acquired at .*CheckDoubleLock.go:272:8\)/
+	a++
+	fmt.Println(a)
+	r.Unlock()
+}
+
+// goto jumps past the Lock into the region that expects it to be held
+func fn23(a int) {
+	if a > 0 {
+		goto locked
+	}
+	r.Lock()
+locked:
+	a++
+	fmt.Println(a)
+	r.Unlock() // MATCH /Unlock of r, which is only locked at .*CheckDoubleLock.go:289:8 on some of the paths reaching it/
+}
+
+func lockR() {
+	r.Lock()
+	fmt.Println("locked")
+	r.Unlock()
+}
+
+// the two acquisitions are in different branches of the same if
+func fn24(a int) {
+	if a > 0 {
+		r.Lock()
+		a++
+	} else {
+		lockR()
+	}
+	fmt.Println(a)
+}
+
+func fn25(a int) {
+	if a > 0 {
+		r.Lock()
+		fmt.Println(a)
+	} else {
+		r.Lock()
+		a++
+	}
+	r.Unlock()
+}
+
+func work(a int) {
+	fmt.Println(a)
+}
+
+func fn26(a int) {
+	if a > 0 {
+		r.Lock()
+		work(a)
+		r.Unlock()
+	} else {
+		work(a)
+		r.Lock()
+		a++
+		r.Unlock()
+	}
+}
+
+// both locks may follow each other, but the pair is reported once
+func fn27(n int) {
+	for i := 0; i < n; i++ {
+		r.Lock()
+		fmt.Println(i)
+		r.Lock() // MATCH /mutex re-acquired here \(previously acquired at .*CheckDoubleLock.go:344:9\)/
+	}
+}
+
+type Account struct {
+	mu      sync.Mutex
+	balance int
+}
+
+// the locks of different accounts are different locks, although they
+// are in the same field
+func (a *Account) Transfer(other *Account, n int) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	other.mu.Lock()
+	defer other.mu.Unlock()
+	a.balance -= n
+	other.balance += n
+}
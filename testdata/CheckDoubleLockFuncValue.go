@@ -0,0 +1,60 @@
+package funcvalue
+
+import "sync"
+
+type Session struct {
+	mu       sync.Mutex
+	n        int
+	unlockFn func()
+	notify   func()
+}
+
+func NewSession() *Session {
+	s := &Session{}
+	s.unlockFn = func() { s.mu.Unlock() }
+	s.notify = func() {}
+	return s
+}
+
+func (s *Session) Step() {
+	s.mu.Lock()
+	s.n++
+	s.unlockFn()
+	s.mu.Lock()
+	s.n++
+	s.mu.Unlock()
+}
+
+func (s *Session) StepNotify() {
+	s.mu.Lock()
+	s.n++
+	s.notify()
+	s.mu.Lock() // MATCH /mutex re-acquired here/
+	s.n++
+	s.mu.Unlock()
+}
+
+func (s *Session) StepCallback(release func()) {
+	s.mu.Lock()
+	s.n++
+	release()
+	s.mu.Lock() // MATCH /mutex possibly re-acquired here \(previously acquired at .*\); it may have been released by a call through a function value that couldn't be resolved/
+	s.n++
+	s.mu.Unlock()
+}
+
+var (
+	mu      sync.Mutex
+	counter int
+)
+
+var unlockGlobal = func() { mu.Unlock() }
+
+func Global() {
+	mu.Lock()
+	counter++
+	unlockGlobal()
+	mu.Lock()
+	counter++
+	mu.Unlock()
+}
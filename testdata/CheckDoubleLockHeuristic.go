@@ -0,0 +1,35 @@
+package pkg
+
+// types with Lock and Unlock methods are treated as locks unless
+// NoLockHeuristic is set; other acquire and release pairs only with
+// LockTypes
+
+type SpinLock struct{ state int32 }
+
+func (l *SpinLock) Lock()   {}
+func (l *SpinLock) Unlock() {}
+
+type Sem struct{ n int32 }
+
+func (s *Sem) Acquire() {}
+func (s *Sem) Release() {}
+
+var (
+	l SpinLock
+	s Sem
+	n int
+)
+
+func fn1() {
+	l.Lock()
+	l.Lock() // MATCH /mutex re-acquired here/
+	n++
+	l.Unlock()
+}
+
+func fn2() {
+	s.Acquire()
+	s.Acquire()
+	n++
+	s.Release()
+}
@@ -0,0 +1,64 @@
+package pkg
+
+import "sync"
+
+type Cache struct {
+	mu    sync.Locker
+	items map[string]int
+}
+
+func (c *Cache) Get(k string) int {
+	c.mu.Lock() // MATCH /mutex re-acquired here \(previously acquired at .*CheckDoubleLockLocker.go:17:11\)/
+	defer c.mu.Unlock()
+	return c.items[k]
+}
+
+func (c *Cache) Sum(keys []string) int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	n := 0
+	for _, k := range keys {
+		n += c.Get(k)
+	}
+	return n
+}
+
+func (c *Cache) Reset() {
+	l := c.mu
+	l.Lock()
+	c.mu.Lock() // MATCH /mutex re-acquired here \(previously acquired at .*CheckDoubleLockLocker.go:28:8\)/
+	c.items = nil
+	c.mu.Unlock()
+	l.Unlock()
+}
+
+type Outer struct {
+	inner struct {
+		lk sync.Locker
+	}
+	n int
+}
+
+func (o *Outer) Do() {
+	o.inner.lk.Lock()
+	o.inner.lk.Lock() // MATCH /mutex re-acquired here/
+	o.n++
+	o.inner.lk.Unlock()
+}
+
+func fn1(a, b sync.Locker, n *int) {
+	a.Lock()
+	b.Lock()
+	*n++
+	b.Unlock()
+	a.Unlock()
+}
+
+func fn2(mu *sync.Mutex, n *int) {
+	var l sync.Locker = mu
+	l.Lock()
+	mu.Lock() // MATCH /mutex re-acquired here/
+	*n++
+	mu.Unlock()
+	l.Unlock()
+}
@@ -0,0 +1,23 @@
+package pkg
+
+import (
+	"sync"
+	"testing"
+)
+
+var (
+	mu sync.Mutex
+	n  int
+)
+
+// the functions in test files are only checked with IncludeTests,
+// but SA2002 applies to tests regardless
+func TestA(t *testing.T) {
+	mu.Lock()
+	mu.Lock()
+	n++
+	mu.Unlock()
+	go func() { // MATCH /the goroutine calls T.Fatal/
+		t.Fatal("in a goroutine")
+	}()
+}
@@ -0,0 +1,61 @@
+package earlycancel
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+func work(ctx context.Context) {
+	<-ctx.Done()
+}
+
+func fn1() {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel() // MATCH /deferred cancel cancels the context from context.WithCancel as soon as fn1 returns, but the goroutine started at .*, which uses it, isn't waited for/
+	go work(ctx)
+}
+
+func fn2() {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		work(ctx)
+	}()
+	wg.Wait()
+}
+
+func fn3() {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel() // MATCH /deferred cancel cancels the context from context.WithCancel as soon as fn3 returns/
+	go func() {
+		work(ctx)
+	}()
+}
+
+func fn4() error {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	errc := make(chan error, 1)
+	go func() {
+		work(ctx)
+		errc <- nil
+	}()
+	return <-errc
+}
+
+func fn5() {
+	ctx, cancel := context.WithCancel(context.Background()) // MATCH /the cancel function returned by context.WithCancel is discarded/
+	go work(ctx)
+	_ = cancel
+}
+
+func fn6() {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel() // MATCH /deferred cancel cancels the context from context.WithCancel as soon as fn6 returns/
+	ctx = context.WithValue(ctx, "k", 1)
+	go work(ctx)
+}
@@ -0,0 +1,67 @@
+package pkg
+
+import "sync"
+
+type Counter struct {
+	mu sync.RWMutex
+	n  int
+}
+
+func fn1(c *Counter) {
+	c.mu.Lock()
+	c.mu.Unlock() // MATCH /empty critical section/
+}
+
+func fn2(c *Counter) {
+	c.mu.RLock()
+	c.mu.RUnlock() // MATCH /empty critical section/
+}
+
+func fn3(c *Counter) {
+	c.mu.Lock()
+	defer c.mu.Unlock() // MATCH /empty critical section/
+}
+
+func fn4(c *Counter) {
+	c.mu.RLock()
+	defer c.mu.RUnlock() // MATCH /empty critical section/
+	return
+}
+
+func fn5(c *Counter) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.n++
+}
+
+func fn6(c *Counter) int {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.n
+}
+
+func fn7(c *Counter, b bool) {
+	if b {
+		c.mu.Lock()
+		defer c.mu.Unlock()
+	}
+	c.n++
+}
+
+func fn8(c *Counter) {
+	c.mu.Lock()
+	defer c.mu.RUnlock() // MATCH /deferring RUnlock, but the lock was acquired with Lock/
+}
+
+func fn9(c1, c2 *Counter) {
+	c1.mu.Lock()
+	defer c2.mu.Unlock()
+}
+
+func fn10(c *Counter) {
+	f := func() {
+		c.mu.Lock()
+		defer c.mu.Unlock() // MATCH /empty critical section/
+	}
+	f()
+}
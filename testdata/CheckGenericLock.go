@@ -0,0 +1,67 @@
+package genericlock
+
+import "sync"
+
+type Container[T any] struct {
+	mu    sync.Mutex
+	items []T
+}
+
+func (c *Container[T]) Add(v T) {
+	c.mu.Lock()
+	c.items = append(c.items, v)
+	c.mu.Lock() // MATCH /mutex re-acquired here/
+}
+
+func (c *Container[T]) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.items)
+}
+
+func WithLock[L sync.Locker](l L, f func()) {
+	l.Lock()
+	f()
+	l.Lock() // MATCH /mutex possibly re-acquired here/
+}
+
+func WithUnlock[L sync.Locker](l L, f func()) {
+	l.Lock()
+	f()
+	l.Unlock()
+}
+
+type Guarded[L sync.Locker, T any] struct {
+	mu L
+	v  T
+}
+
+func (g *Guarded[L, T]) Load() T {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.v
+}
+
+func (g *Guarded[L, T]) Store(v T) {
+	g.mu.Lock()
+	g.store(v)
+	g.mu.Unlock()
+}
+
+func (g *Guarded[L, T]) Swap(v T) {
+	g.store(v)
+}
+
+func (g *Guarded[L, T]) store(v T) {
+	g.v = v // MATCH /field .*Guarded\[L, T\].v is accessed without holding .*Guarded\[L, T\].mu, .*; Swap calls store/
+}
+
+func Use() {
+	c := &Container[int]{}
+	c.Add(1)
+	var mu sync.Mutex
+	WithLock(&mu, func() {})
+	WithUnlock(&mu, func() {})
+	g := &Guarded[*sync.Mutex, int]{mu: &sync.Mutex{}}
+	g.Store(1)
+}
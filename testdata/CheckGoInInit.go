@@ -0,0 +1,41 @@
+package pkg
+
+import "sync"
+
+var (
+	cache map[string]int
+	ready = make(chan struct{})
+)
+
+func load() {
+	cache = map[string]int{}
+	close(ready)
+}
+
+func init() {
+	go load() // MATCH /goroutine started in init may still be running when main starts/
+}
+
+func init() {
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		println("warming up")
+	}()
+	wg.Wait()
+}
+
+func init() {
+	var wg, other sync.WaitGroup
+	wg.Add(1)   // MATCH /Wait is never called on it/
+	go func() { // MATCH /consider starting it from an explicit initialization function/
+		defer wg.Done()
+		println("warming up")
+	}()
+	other.Wait()
+}
+
+func Start() {
+	go load()
+}
@@ -0,0 +1,44 @@
+package pkg
+
+import "sync"
+
+type Worker struct {
+	mu   sync.Mutex
+	jobs []int
+}
+
+func (w Worker) Run() { // MATCH /Run has a value receiver, so every call copies the sync.Mutex in Worker/
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.jobs = nil
+}
+
+func (w *Worker) Stop() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.jobs = nil
+}
+
+type Pool struct {
+	wg sync.WaitGroup
+}
+
+func (p Pool) Wait() { // MATCH /Wait has a value receiver/
+	p.wg.Wait()
+}
+
+type Plain struct {
+	n int
+}
+
+func (p Plain) Print() {
+	println(p.n)
+}
+
+func fn1(w Worker, wp *Worker, p Pool, pl Plain) {
+	go w.Run()  // MATCH /Run has a value receiver, so the goroutine runs on a copy of the sync.Mutex in Worker and doesn't synchronize with its caller/
+	go wp.Run() // MATCH /goroutine runs on a copy of the sync.Mutex in Worker/
+	go wp.Stop()
+	go p.Wait() // MATCH /Wait has a value receiver, so the goroutine runs on a copy of the sync.WaitGroup in Pool/
+	go pl.Print()
+}
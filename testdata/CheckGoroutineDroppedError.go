@@ -0,0 +1,75 @@
+package droppederr
+
+import (
+	"errors"
+	"fmt"
+	"log"
+)
+
+func do() error { return errors.New("x") }
+
+func fetch() (int, error) { return 0, nil }
+
+func fn1() {
+	go func() {
+		err := do() // MATCH /error returned by .*do in a goroutine is dropped/
+		_ = err
+	}()
+}
+
+func fn2() {
+	go func() {
+		do() // MATCH /error returned by .*do in a goroutine is dropped/
+	}()
+}
+
+func fn3() {
+	go func() {
+		if err := do(); err != nil { // MATCH /error returned by .*do in a goroutine is dropped/
+			return
+		}
+	}()
+}
+
+func fn4(errc chan error) {
+	go func() {
+		errc <- do()
+	}()
+}
+
+func fn5() {
+	go func() {
+		if err := do(); err != nil {
+			log.Println(err)
+		}
+	}()
+}
+
+func fn6() error {
+	var err error
+	done := make(chan struct{})
+	go func() {
+		err = do()
+		close(done)
+	}()
+	<-done
+	return err
+}
+
+func fn7() {
+	go func() {
+		n, _ := fetch() // MATCH /error returned by .*fetch in a goroutine is dropped/
+		fmt.Println(n)
+	}()
+}
+
+func fn8() {
+	go do() // MATCH /error returned by .*do is dropped by the go statement/
+}
+
+func fn9() {
+	go func() {
+		//lint:ignore SA2058 fire and forget
+		do()
+	}()
+}
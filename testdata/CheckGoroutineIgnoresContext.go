@@ -0,0 +1,62 @@
+package pkg
+
+import (
+	"context"
+	"fmt"
+)
+
+func fn1(ctx context.Context, in <-chan int) {
+	go func() { // MATCH /the goroutine blocks on a channel at .*:10:3 without observing the cancellation of ctx; select on ctx.Done\(\) as well so that it doesn't leak/
+		for v := range in {
+			fmt.Println(v)
+		}
+	}()
+}
+
+func fn2(ctx context.Context, in <-chan int) {
+	go func() {
+		for {
+			select {
+			case v := <-in:
+				fmt.Println(v)
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+}
+
+func fn3(ctx context.Context, in <-chan int, out chan<- int) {
+	go func() { // MATCH /the goroutine blocks on a select at .*:32:4 without observing the cancellation of ctx/
+		for {
+			select {
+			case v := <-in:
+				out <- v
+			case out <- 0:
+			}
+		}
+	}()
+}
+
+func fn4(ctx context.Context) {
+	go func() {
+		<-ctx.Done()
+		fmt.Println("cancelled")
+	}()
+}
+
+func fn5(ctx context.Context) {
+	result := make(chan int, 1)
+	go func() {
+		result <- 1
+	}()
+	fmt.Println(<-result)
+}
+
+func fn6(in <-chan int) {
+	go func() {
+		for v := range in {
+			fmt.Println(v)
+		}
+	}()
+}
@@ -0,0 +1,104 @@
+package pkg
+
+import "sync"
+
+var counter int
+
+func increment() {
+	counter++ // MATCH /variable counter is written by the goroutine started at .*CheckGoroutineRace.go:12:2 and read at .*CheckGoroutineRace.go:13:9 without holding a lock/
+}
+
+func fn1() int {
+	go increment()
+	return counter
+}
+
+func setTo(p *int, v int) {
+	*p = v // MATCH /variable x is written by the goroutine started at .*CheckGoroutineRace.go:22:2 and written at .*CheckGoroutineRace.go:23:2/
+}
+
+func fn2() int {
+	x := 0
+	go setTo(&x, 1)
+	x = 2
+	return x
+}
+
+func report(p *int) {
+	println(*p)
+}
+
+func fn3() {
+	y := 0
+	go report(&y)
+	y = 3 // MATCH /variable y is written here without holding a lock while the goroutine started at .*CheckGoroutineRace.go:33:2 reads it at .*CheckGoroutineRace.go:28:10/
+}
+
+var total int
+
+func add(n int) {
+	total += n // MATCH /variable total is written without holding a lock by every goroutine started at .*CheckGoroutineRace.go:45:3/
+}
+
+func fn4() {
+	for i := 0; i < 10; i++ {
+		go add(i)
+	}
+}
+
+var hits int
+
+func hit() {
+	hits++ // MATCH /variable hits is written by the goroutine started at .*CheckGoroutineRace.go:60:2 and read by the one started at .*CheckGoroutineRace.go:61:2, at .*CheckGoroutineRace.go:56:10/
+}
+
+func show() {
+	println(hits)
+}
+
+func fn5() {
+	go hit()
+	go show()
+}
+
+var mu sync.Mutex
+var guarded int
+
+func incrementLocked() {
+	mu.Lock()
+	guarded++
+	mu.Unlock()
+}
+
+func fn6() int {
+	go incrementLocked()
+	mu.Lock()
+	defer mu.Unlock()
+	return guarded
+}
+
+func fn7() int {
+	var wg sync.WaitGroup
+	z := 0
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		setTo(&z, 1)
+	}()
+	wg.Wait()
+	return z
+}
+
+func fn8() int {
+	x := 0
+	setTo(&x, 1)
+	go report(&x)
+	return x
+}
+
+func fn9() {
+	for i := 0; i < 10; i++ {
+		v := i
+		go setTo(&v, 0)
+	}
+}
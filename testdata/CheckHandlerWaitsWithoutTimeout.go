@@ -0,0 +1,71 @@
+package pkg
+
+import (
+	"net/http"
+	"time"
+)
+
+func compute() int { return 42 }
+
+func fn1(w http.ResponseWriter, r *http.Request) {
+	ch := make(chan int)
+	go func() {
+		ch <- compute()
+	}()
+	v := <-ch // MATCH /the handler waits for the goroutine started at .+ without a timeout or a case for the request's cancellation/
+	println(v)
+}
+
+func fn2(w http.ResponseWriter, r *http.Request) {
+	ch := make(chan int)
+	errs := make(chan error)
+	go func() {
+		ch <- compute() // MATCH /sending on unbuffered channel ch blocks the goroutine/
+	}()
+	select { // MATCH /make the channel buffered so that the goroutine can still send and exit/
+	case v := <-ch:
+		println(v)
+	case err := <-errs:
+		println(err)
+	}
+}
+
+func fn3(w http.ResponseWriter, r *http.Request) {
+	ch := make(chan int, 1)
+	go func() {
+		ch <- compute()
+	}()
+	select {
+	case v := <-ch:
+		println(v)
+	case <-r.Context().Done():
+	}
+}
+
+func fn4(w http.ResponseWriter, r *http.Request) {
+	ch := make(chan int)
+	go func() {
+		ch <- compute() // MATCH /sending on unbuffered channel ch blocks the goroutine/
+	}()
+	select {
+	case v := <-ch:
+		println(v)
+	case <-time.After(time.Second):
+	}
+}
+
+func fn5() {
+	ch := make(chan int)
+	go func() {
+		ch <- compute()
+	}()
+	println(<-ch)
+}
+
+func fn6(w http.ResponseWriter, r *http.Request) {
+	ch := make(chan int, 1)
+	go func() {
+		ch <- compute()
+	}()
+	println(<-ch)
+}
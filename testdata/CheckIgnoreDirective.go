@@ -0,0 +1,44 @@
+package pkg
+
+func fn1(b bool) {
+	ch := make(chan int)
+	if b {
+		close(ch)
+	}
+	close(ch) //gcbd:ignore SA2015
+}
+
+func fn2(b bool) {
+	ch := make(chan int)
+	if b {
+		close(ch)
+	}
+	//gcbd:ignore
+	close(ch)
+}
+
+func fn3(b bool) {
+	ch := make(chan int)
+	if b {
+		close(ch)
+	}
+	//gcbd:ignore SA2005,SA2015
+	close(ch)
+}
+
+func fn4(b bool) {
+	ch := make(chan int)
+	if b {
+		close(ch)
+	} // MATCH:34 /this linter directive didn't match anything/
+	//gcbd:ignore SA2005
+	close(ch) // MATCH /channel ch may already have been closed/
+}
+
+func fn5(b bool) {
+	ch := make(chan int)
+	if b {
+		close(ch) //gcbd:ignore
+	}
+	close(ch) // MATCH /channel ch may already have been closed/
+}
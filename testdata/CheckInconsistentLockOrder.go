@@ -0,0 +1,60 @@
+package pkg
+
+import "sync"
+
+var (
+	muX   sync.Mutex
+	muY   sync.Mutex
+	muP   sync.Mutex
+	muQ   sync.Mutex
+	count int
+)
+
+func fn1() {
+	muX.Lock()
+	muY.Lock() // MATCH /muY is acquired while holding muX, but muX is acquired while holding muY at .*; the two orders can deadlock/
+	count++
+	muY.Unlock()
+	muX.Unlock()
+}
+
+func fn2() {
+	muX.Lock()
+	defer muX.Unlock()
+	muY.Lock()
+	defer muY.Unlock()
+	count++
+}
+
+func fn3() {
+	muY.Lock()
+	muX.Lock() // MATCH /muX is acquired while holding muY, but muY is acquired while holding muX elsewhere, 2 times, first at .*:15:\d+; acquire the two locks in one order/
+	count++
+	muX.Unlock()
+	muY.Unlock()
+}
+
+func fn4() {
+	muP.Lock()
+	muQ.Lock() // MATCH /muQ is acquired while holding muP, but muP is acquired while holding muQ at .*; the two orders can deadlock/
+	count++
+	muQ.Unlock()
+	muP.Unlock()
+}
+
+func fn5() {
+	muQ.Lock()
+	muP.Lock() // MATCH /muP is acquired while holding muQ, but muQ is acquired while holding muP elsewhere, at .*:39:\d+; acquire/
+	count++
+	muP.Unlock()
+	muQ.Unlock()
+}
+
+func fn6() {
+	muX.Lock()
+	count++
+	muX.Unlock()
+	muY.Lock()
+	count++
+	muY.Unlock()
+}
@@ -0,0 +1,37 @@
+package pkg
+
+import (
+	"fmt"
+	"sync"
+)
+
+type Conn struct{ addr string }
+
+func dial(addr string) *Conn { return &Conn{addr} }
+
+func (c *Conn) Close() {}
+
+var conns sync.Map
+
+func fn1(addr string) *Conn {
+	c, _ := conns.LoadOrStore(addr, dial(addr)) // MATCH /the loaded result of LoadOrStore is never used/
+	return c.(*Conn)
+}
+
+func fn2(addr string) {
+	conns.LoadOrStore(addr, dial(addr)) // MATCH /the loaded result of LoadOrStore is never used/
+}
+
+func fn3(addr string) *Conn {
+	c := dial(addr)
+	actual, loaded := conns.LoadOrStore(addr, c)
+	if loaded {
+		c.Close()
+	}
+	return actual.(*Conn)
+}
+
+func fn4(addr string) {
+	_, loaded := conns.LoadOrStore(addr, dial(addr))
+	fmt.Println(loaded)
+}
@@ -0,0 +1,99 @@
+package pkg
+
+import "sync"
+
+type Pool struct {
+	mu      sync.Mutex
+	results []int
+}
+
+func fn1(p *Pool) {
+	var wg sync.WaitGroup
+	p.mu.Lock()
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		p.mu.Lock()
+		p.results = append(p.results, 1)
+		p.mu.Unlock()
+	}()
+	wg.Wait() // MATCH /waiting while holding the lock acquired at .+, which the goroutine started at .+ acquires at .+; this deadlocks/
+	p.mu.Unlock()
+}
+
+func fn2(p *Pool) {
+	var mu sync.Mutex
+	done := make(chan struct{})
+	mu.Lock()
+	go func() {
+		mu.Lock()
+		p.results = nil
+		mu.Unlock()
+		close(done)
+	}()
+	<-done // MATCH /this deadlocks/
+	// MATCH:34 /channel receive while holding mu/
+	mu.Unlock()
+}
+
+func fn3(p *Pool) {
+	var wg sync.WaitGroup
+	p.mu.Lock()
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		p.mu.Lock()
+		p.results = append(p.results, 1)
+		p.mu.Unlock()
+	}()
+	p.mu.Unlock()
+	wg.Wait()
+}
+
+func fn4(p, q *Pool) {
+	var wg sync.WaitGroup
+	p.mu.Lock()
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		var mu sync.Mutex
+		mu.Lock()
+		q.results = nil
+		mu.Unlock()
+	}()
+	wg.Wait()
+	p.results = nil
+	p.mu.Unlock()
+}
+
+func fn5() {
+	var mu sync.RWMutex
+	n := 0
+	var wg sync.WaitGroup
+	mu.RLock()
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		mu.RLock()
+		println(n)
+		mu.RUnlock()
+	}()
+	wg.Wait()
+	mu.RUnlock()
+}
+
+func fn6() {
+	var mu sync.RWMutex
+	n := 0
+	var wg sync.WaitGroup
+	mu.RLock()
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		mu.Lock()
+		n++
+		mu.Unlock()
+	}()
+	wg.Wait() // MATCH /this deadlocks/
+	mu.RUnlock()
+}
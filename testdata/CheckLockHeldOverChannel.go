@@ -0,0 +1,70 @@
+package pkg
+
+import "sync"
+
+type Queue struct {
+	mu      sync.Mutex
+	pending []int
+	out     chan int
+	done    chan struct{}
+}
+
+func (q *Queue) Flush() {
+	q.mu.Lock()
+	for _, v := range q.pending {
+		q.out <- v // MATCH /channel send while holding field mu, locked at .+; the goroutine that would unblock it may need the lock, so release it first/
+	}
+	q.pending = nil
+	q.mu.Unlock()
+}
+
+func (q *Queue) Wait() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	<-q.done // MATCH /channel receive while holding field mu/
+}
+
+func (q *Queue) Next() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	select { // MATCH /channel select while holding field mu/
+	case v := <-q.out:
+		return v
+	case <-q.done:
+		return -1
+	}
+}
+
+func (q *Queue) TryNext() (int, bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	select {
+	case v := <-q.out:
+		return v, true
+	default:
+		return 0, false
+	}
+}
+
+func (q *Queue) FlushUnlocked() {
+	q.mu.Lock()
+	pending := q.pending
+	q.pending = nil
+	q.mu.Unlock()
+	for _, v := range pending {
+		q.out <- v
+	}
+}
+
+func fn1() {
+	var mu sync.Mutex
+	ch := make(chan int, 1)
+	mu.Lock()
+	ch <- 1
+	mu.Unlock()
+	println(<-ch)
+}
+
+func NewQueue() *Queue {
+	return &Queue{out: make(chan int), done: make(chan struct{})}
+}
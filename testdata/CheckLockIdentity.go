@@ -0,0 +1,32 @@
+package pkg
+
+import "sync"
+
+var n int
+
+func helper() {
+	var mu sync.Mutex
+	mu.Lock()
+	n++
+	mu.Unlock()
+}
+
+// Both functions lock a local named mu, but they are different locks.
+func outer() {
+	var mu sync.Mutex
+	mu.Lock()
+	helper()
+	mu.Unlock()
+}
+
+// The closure locks the same mu through a captured variable.
+func captured() {
+	var mu sync.Mutex
+	mu.Lock()
+	func() {
+		mu.Lock() // MATCH /mutex re-acquired here/
+		n++
+		mu.Unlock()
+	}()
+	mu.Unlock()
+}
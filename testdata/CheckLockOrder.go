@@ -0,0 +1,75 @@
+package pkg
+
+import "sync"
+
+type Account struct {
+	mu      sync.Mutex
+	balance int
+}
+
+type Ledger struct {
+	mu      sync.Mutex
+	entries []int
+}
+
+type Bank struct {
+	acct   Account
+	ledger Ledger
+}
+
+func (b *Bank) Deposit(n int) {
+	b.acct.mu.Lock()
+	defer b.acct.mu.Unlock()
+	b.ledger.mu.Lock() // MATCH /.*Ledger.mu is acquired while holding .*Account.mu, but .*Account.mu is acquired while holding .*Ledger.mu at .*CheckLockOrder.go:32:16; the two orders can deadlock/
+	defer b.ledger.mu.Unlock()
+	b.acct.balance += n
+	b.ledger.entries = append(b.ledger.entries, n)
+}
+
+func (b *Bank) Audit() int {
+	b.ledger.mu.Lock()
+	defer b.ledger.mu.Unlock()
+	b.acct.mu.Lock() // MATCH /Account.mu is acquired while holding .*Ledger.mu, but .*Ledger.mu is acquired while holding .*Account.mu elsewhere, at .*CheckLockOrder.go:23:18; acquire the two locks in one order/
+	defer b.acct.mu.Unlock()
+	return b.acct.balance
+}
+
+var (
+	muA sync.Mutex
+	muB sync.Mutex
+	muC sync.Mutex
+	n   int
+)
+
+func fn1() {
+	muA.Lock()
+	muB.Lock() // MATCH /muB is acquired while holding muA, closing the lock order cycle muA -> muB -> muC -> muA; muC is acquired while holding muB at/
+	n++
+	muB.Unlock()
+	muA.Unlock()
+}
+
+func fn2() {
+	muB.Lock()
+	muC.Lock()
+	n++
+	muC.Unlock()
+	muB.Unlock()
+}
+
+func fn3() {
+	muC.Lock()
+	muA.Lock()
+	n++
+	muA.Unlock()
+	muC.Unlock()
+}
+
+func fn4() {
+	muB.Lock()
+	n++
+	muB.Unlock()
+	muA.Lock()
+	n++
+	muA.Unlock()
+}
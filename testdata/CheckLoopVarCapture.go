@@ -0,0 +1,55 @@
+package pkg
+
+func fn1(n int) {
+	for i := 0; i < n; i++ {
+		go func() { // MATCH /goroutine captures the loop variable i, which is shared by all iterations/
+			println(i) // MATCH /variable i is shared with the goroutine/
+		}()
+	}
+}
+
+func fn2(xs []string) {
+	for _, x := range xs {
+		go func() { // MATCH /goroutine captures the loop variable x/
+			println(x)
+		}()
+	}
+}
+
+func fn3(xs []string) {
+	for _, x := range xs {
+		go func(x string) {
+			println(x)
+		}(x)
+	}
+}
+
+func fn4(xs []string) {
+	for _, x := range xs {
+		x := x
+		go func() {
+			println(x)
+		}()
+	}
+}
+
+func fn5(n int) {
+	total := 0
+	for i := 0; i < n; i++ {
+		total += i
+	}
+	go func() {
+		println(total)
+	}()
+}
+
+func fn6(xs []string) {
+	done := make(chan struct{})
+	for _, x := range xs {
+		go func() {
+			println(x)
+			done <- struct{}{}
+		}()
+		<-done
+	}
+}
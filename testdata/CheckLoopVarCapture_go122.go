@@ -0,0 +1,36 @@
+package pkg
+
+func go122fn2(xs []string) {
+	for _, x := range xs {
+		go func() {
+			println(x)
+		}()
+	}
+}
+
+func go122fn3(xs []string) {
+	for _, x := range xs {
+		go func(x string) {
+			println(x)
+		}(x)
+	}
+}
+
+func go122fn4(xs []string) {
+	for _, x := range xs {
+		x := x
+		go func() {
+			println(x)
+		}()
+	}
+}
+
+func go122fn5(n int) {
+	total := 0
+	for i := 0; i < n; i++ {
+		total += i
+	}
+	go func() {
+		println(total)
+	}()
+}
@@ -0,0 +1,50 @@
+package pkg
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+func use(ctx context.Context) error { return ctx.Err() }
+
+func fn1() error {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	return use(ctx)
+}
+
+func fn2(b bool) error {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second) // MATCH /the cancel function returned by context.WithTimeout is not called on the path returning at .*CheckLostCancel.go:20:3/
+	if b {
+		return errors.New("b")
+	}
+	err := use(ctx)
+	cancel()
+	return err
+}
+
+func fn3() error {
+	ctx, _ := context.WithCancel(context.Background()) // MATCH /the cancel function returned by context.WithCancel is discarded/
+	return use(ctx)
+}
+
+func fn4() (context.Context, context.CancelFunc) {
+	return context.WithDeadline(context.Background(), time.Now())
+}
+
+type Worker struct {
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+func NewWorker() *Worker {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Worker{ctx: ctx, cancel: cancel}
+}
+
+func fn5() error {
+	ctx, cancel := context.WithCancel(context.Background()) // MATCH /the cancel function returned by context.WithCancel is discarded/
+	_ = cancel
+	return use(ctx)
+}
@@ -0,0 +1,50 @@
+package pkg
+
+import "sync/atomic"
+
+type Stats struct {
+	closed bool
+	hits   int64
+}
+
+func (s *Stats) Hit() {
+	atomic.AddInt64(&s.hits, 1) // MATCH /atomic.AddInt64 on field hits, which is at offset 4 of Stats on 32-bit platforms and so isn't 64-bit aligned there, panics on those platforms; move the field to the start of the struct or use atomic.Int64/
+}
+
+type Aligned struct {
+	hits   int64
+	closed bool
+}
+
+func (a *Aligned) Hit() int64 {
+	atomic.AddInt64(&a.hits, 1)
+	return atomic.LoadInt64(&a.hits)
+}
+
+type Padded struct {
+	a, b  int32
+	bytes uint64
+}
+
+func (p *Padded) Add(n uint64) {
+	atomic.AddUint64(&p.bytes, n)
+}
+
+type Outer struct {
+	id    int32
+	inner struct {
+		n uint64
+	}
+}
+
+func (o *Outer) Load() uint64 {
+	return atomic.LoadUint64(&o.inner.n) // MATCH /atomic.LoadUint64 on field n, which is at offset 4 of Outer on 32-bit platforms/
+}
+
+var total struct {
+	n int64
+}
+
+func add() {
+	atomic.AddInt64(&total.n, 1)
+}
@@ -0,0 +1,123 @@
+package pkg
+
+import (
+	"errors"
+	"sync"
+)
+
+type Store struct {
+	mu   sync.Mutex
+	rw   sync.RWMutex
+	data map[string]int
+}
+
+func (s *Store) Get(k string) (int, error) {
+	s.mu.Lock() // MATCH /the lock acquired by Lock is not released on the path returning at .*CheckMissingUnlock.go:18:3/
+	v, ok := s.data[k]
+	if !ok {
+		return 0, errors.New("not found")
+	}
+	s.mu.Unlock()
+	return v, nil
+}
+
+func (s *Store) Set(k string, v int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if v < 0 {
+		return
+	}
+	s.data[k] = v
+}
+
+func (s *Store) Delete(k string) {
+	s.mu.Lock()
+	defer func() {
+		s.mu.Unlock()
+	}()
+	if _, ok := s.data[k]; !ok {
+		return
+	}
+	delete(s.data, k)
+}
+
+func (s *Store) Len() int {
+	s.rw.RLock() // MATCH /the lock acquired by RLock is not released on the path returning at/
+	if s.data == nil {
+		return 0
+	}
+	n := len(s.data)
+	s.rw.RUnlock()
+	return n
+}
+
+func (s *Store) Has(k string) bool {
+	s.mu.Lock()
+	if _, ok := s.data[k]; ok {
+		s.mu.Unlock()
+		return true
+	}
+	s.mu.Unlock()
+	return false
+}
+
+// lock returns with the lock held on purpose.
+func (s *Store) lock() {
+	s.mu.Lock()
+}
+
+type Cache struct {
+	mu    sync.Mutex
+	items []int
+}
+
+func (c *Cache) Clear() {
+	defer c.mu.Unlock()
+	c.mu.Lock()
+	if c.items == nil {
+		return
+	}
+	c.items = nil
+}
+
+func (c *Cache) Reset(all bool) {
+	c.mu.Lock() // MATCH /the lock acquired by Lock is not released on the path reaching the end of the function/
+	if all {
+		defer c.mu.Unlock()
+		c.items = nil
+		return
+	}
+	if c.items != nil {
+		c.mu.Unlock()
+		return
+	}
+}
+
+func fn1(s *Store, c *Cache) {
+	s.lock()
+	c.Clear()
+	c.Reset(true)
+}
+
+// the lock is only acquired, and only released, if shared is set
+func (c *Cache) Append(v int, shared bool) {
+	if shared {
+		c.mu.Lock()
+	}
+	c.items = append(c.items, v)
+	if shared {
+		c.mu.Unlock()
+	}
+}
+
+// the lock is acquired if shared is set, but only released if it isn't
+func (c *Cache) Prepend(v int, shared bool) {
+	if shared {
+		c.mu.Lock() // MATCH /the lock acquired by Lock is not released on the path returning at .*CheckMissingUnlock.go:120:3/
+	}
+	c.items = append([]int{v}, c.items...)
+	if shared {
+		return
+	}
+	c.mu.Unlock()
+}
@@ -0,0 +1,22 @@
+package pkg
+
+import "sync"
+
+var (
+	mu   sync.Mutex
+	data map[string]int
+)
+
+// release is implemented elsewhere. It isn't known to unlock mu
+// unless it is listed in UnlockHelpers.
+func release(mu *sync.Mutex, v int) int
+
+func fn1(k string) int {
+	mu.Lock() // MATCH /the lock acquired by Lock is not released on the path returning at .*CheckMissingUnlockHelper.go:18:3/
+	v, ok := data[k]
+	if !ok {
+		return release(&mu, -1)
+	}
+	mu.Unlock()
+	return v
+}
@@ -0,0 +1,58 @@
+package pkg
+
+import (
+	"fmt"
+	"sync/atomic"
+)
+
+type Stats struct {
+	hits   int64
+	misses int64
+}
+
+func NewStats() *Stats {
+	s := &Stats{}
+	s.hits = 0
+	return s
+}
+
+func (s *Stats) Hit() {
+	atomic.AddInt64(&s.hits, 1)
+}
+
+func (s *Stats) Hits() int64 {
+	return s.hits // MATCH /field hits is accessed atomically at .*:20:17, but this read isn't atomic; mixing atomic and plain accesses is a data race/
+}
+
+func (s *Stats) Miss() {
+	s.misses++
+}
+
+func (s *Stats) Misses() int64 {
+	return s.misses
+}
+
+var requests int64
+
+func handle() {
+	atomic.AddInt64(&requests, 1)
+}
+
+func reset() {
+	requests = 0 // MATCH /requests is accessed atomically at .*, but this write isn't atomic/
+}
+
+func report() {
+	fmt.Println(atomic.LoadInt64(&requests))
+}
+
+func fn1() {
+	var n int32
+	done := make(chan bool)
+	go func() {
+		atomic.AddInt32(&n, 1)
+		done <- true
+	}()
+	fmt.Println(n) // MATCH /n is accessed atomically at .*, but this read isn't atomic/
+	<-done
+}
@@ -0,0 +1,80 @@
+package pkg
+
+import "sync"
+
+type Crawler struct {
+	wg sync.WaitGroup
+}
+
+func fetch() bool { return true }
+
+func fn1() {
+	var wg sync.WaitGroup
+	wg.Add(1) // MATCH /the counter can't reach zero/
+	go func() {
+		defer wg.Done() // MATCH /wg.Done is called more often than Add incremented the counter \(by 1\) on some path/
+		if !fetch() {
+			wg.Done()
+			return
+		}
+	}()
+	wg.Wait()
+}
+
+func fn2() {
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		fetch()
+	}()
+	go func() {
+		defer wg.Done()
+		fetch()
+	}()
+	wg.Wait()
+}
+
+func (c *Crawler) fn3() {
+	c.wg.Add(1) // MATCH /the counter can't reach zero/
+	go func() {
+		fetch()
+		c.wg.Done()
+		c.wg.Done() // MATCH /Done is called more often than Add incremented the counter \(by 1\) on some path/
+	}()
+	c.wg.Wait()
+}
+
+func fn4(urls []string) {
+	var wg sync.WaitGroup
+	for range urls {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			fetch()
+		}()
+	}
+	wg.Wait()
+}
+
+func fn5(n int) {
+	var wg sync.WaitGroup
+	wg.Add(n)
+	go func() {
+		wg.Done()
+		wg.Done()
+	}()
+	wg.Wait()
+}
+
+func fn6() {
+	var wg sync.WaitGroup
+	wg.Add(1)
+	wg.Done()
+	wg.Done() // MATCH /wg.Done is called more often than Add incremented the counter \(by 1\) on some path/
+	wg.Wait()
+}
+
+func use(c *Crawler) {
+	c.fn3()
+}
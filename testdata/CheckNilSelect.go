@@ -0,0 +1,73 @@
+package pkg
+
+type Worker struct {
+	jobs chan int
+	quit chan struct{}
+}
+
+type Server struct {
+	reqs chan int
+}
+
+var (
+	events chan string
+	errs   chan error
+)
+
+func NewServer() *Server {
+	return &Server{reqs: make(chan int)}
+}
+
+func fn1() {
+	var ch chan int
+	select { // MATCH /select blocks forever: all of its channels are nil \(nil\)/
+	case <-ch:
+	case ch <- 1:
+	}
+}
+
+func fn2(w *Worker) {
+	for {
+		select { // MATCH /all of its channels are nil \(field .*Worker.jobs is never assigned, field .*Worker.quit is never assigned\)/
+		case j := <-w.jobs:
+			println(j)
+		case <-w.quit:
+			return
+		}
+	}
+}
+
+func fn3(s *Server, w *Worker) {
+	select {
+	case r := <-s.reqs:
+		println(r)
+	case <-w.quit:
+	}
+}
+
+func fn4() {
+	select { // MATCH /\(variable events is never assigned, variable errs is never assigned\)/
+	case e := <-events:
+		println(e)
+	case err := <-errs:
+		println(err)
+	}
+}
+
+func fn5(w *Worker) {
+	select {
+	case <-w.jobs:
+	default:
+	}
+}
+
+func fn6(b bool) {
+	var ch chan int
+	if b {
+		ch = make(chan int, 1)
+	}
+	select {
+	case ch <- 1:
+	case <-ch:
+	}
+}
@@ -0,0 +1,82 @@
+package pkg
+
+import (
+	"context"
+	"time"
+)
+
+var counter int
+
+func poll() {
+	counter++
+}
+
+func fn1() {
+	go func() { // MATCH /the goroutine started here may never terminate/
+		for {
+			poll()
+			time.Sleep(time.Second)
+		}
+	}()
+}
+
+func spin() {
+	for {
+		counter++
+	}
+}
+
+func fn2() {
+	go spin() // MATCH /the goroutine started here may never terminate/
+}
+
+func fn3(ctx context.Context) {
+	go func() {
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			default:
+			}
+			poll()
+		}
+	}()
+}
+
+func fn4(stop chan struct{}) {
+	go func() {
+		for {
+			poll()
+			if _, ok := <-stop; !ok {
+				return
+			}
+		}
+	}()
+}
+
+func fn5(n int) {
+	go func() {
+		for i := 0; i < n; i++ {
+			poll()
+		}
+	}()
+}
+
+func fn6(jobs chan int) {
+	go func() {
+		for {
+			counter += <-jobs
+		}
+	}()
+}
+
+func fn7() {
+	go func() {
+		for {
+			if counter > 10 {
+				break
+			}
+			poll()
+		}
+	}()
+}
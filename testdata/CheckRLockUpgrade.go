@@ -0,0 +1,36 @@
+package pkg
+
+import "sync"
+
+type Cache struct {
+	mu sync.RWMutex
+	m  map[string]int
+}
+
+func (c *Cache) GetOrSet(k string, v int) int {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	if old, ok := c.m[k]; ok {
+		return old
+	}
+	c.mu.Lock() // MATCH /Lock is called while the read lock acquired at .*CheckRLockUpgrade.go:11:12 is still held; RWMutex can't upgrade a read lock and this deadlocks/
+	c.m[k] = v
+	c.mu.Unlock()
+	return v
+}
+
+func (c *Cache) GetOrSetFixed(k string, v int) int {
+	c.mu.RLock()
+	old, ok := c.m[k]
+	c.mu.RUnlock()
+	if ok {
+		return old
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if old, ok := c.m[k]; ok {
+		return old
+	}
+	c.m[k] = v
+	return v
+}
@@ -0,0 +1,58 @@
+package pkg
+
+import "sync"
+
+type Registry struct {
+	mu    sync.Mutex
+	names map[string]int
+}
+
+func (r *Registry) Lookup(name string) (int, bool) {
+	r.mu.Lock() // MATCH /field mu is a sync.Mutex, but 3 of the 4 critical sections locking it only read; an RWMutex would let them run concurrently/
+	defer r.mu.Unlock()
+	id, ok := r.names[name]
+	return id, ok
+}
+
+func (r *Registry) Len() int {
+	r.mu.Lock()
+	n := len(r.names)
+	r.mu.Unlock()
+	return n
+}
+
+func (r *Registry) Has(name string) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	_, ok := r.names[name]
+	return ok
+}
+
+func (r *Registry) Register(name string, id int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.names[name] = id
+}
+
+type Counter struct {
+	mu sync.Mutex
+	n  int
+}
+
+func (c *Counter) Get() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.n
+}
+
+func (c *Counter) Inc() {
+	c.mu.Lock()
+	c.n++
+	c.mu.Unlock()
+}
+
+func (c *Counter) Add(n int) {
+	c.mu.Lock()
+	c.n += n
+	c.mu.Unlock()
+}
@@ -0,0 +1,48 @@
+package pkg
+
+func consume(ch chan int) {
+	for v := range ch { // MATCH /receiving from channel ch, which was closed at .*CheckReceiveFromClosed.go:13:7 before the goroutine started at .*CheckReceiveFromClosed.go:14:2; the receive yields the zero value immediately/
+		println(v)
+	}
+}
+
+func fn1() {
+	ch := make(chan int, 3)
+	ch <- 1
+	ch <- 2
+	close(ch)
+	go consume(ch)
+}
+
+func fn2() {
+	results := make(chan string)
+	close(results)
+	go func() {
+		println(<-results) // MATCH /receiving from channel results, which was closed at .*CheckReceiveFromClosed.go:19:7/
+	}()
+}
+
+func fn3() {
+	ch := make(chan int)
+	go consume(ch)
+	ch <- 1
+	close(ch)
+}
+
+func fn4() {
+	done := make(chan struct{})
+	close(done)
+	go func() {
+		<-done
+	}()
+}
+
+func fn5(b bool) {
+	ch := make(chan int)
+	if b {
+		close(ch)
+	}
+	go func() {
+		println(<-ch)
+	}()
+}
@@ -0,0 +1,65 @@
+package pkg
+
+import "sync"
+
+var treeMu sync.Mutex
+
+type node struct {
+	left, right *node
+	children    []*node
+	mu          sync.Mutex
+	n           int
+}
+
+func walk(n *node) {
+	treeMu.Lock()
+	defer treeMu.Unlock()
+	if n == nil {
+		return
+	}
+	walk(n.left) // MATCH /recursive call of walk while holding the lock acquired at .*:15:13; the call acquires the lock again there and deadlocks/
+}
+
+func (n *node) incr(k int) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.n++
+	if k > 0 {
+		n.incr(k - 1) // MATCH /recursive call of incr while holding the lock/
+	}
+}
+
+func (n *node) visit() {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	for _, ch := range n.children {
+		ch.visit()
+	}
+}
+
+func (n *node) step(k int) {
+	n.mu.Lock()
+	n.n++
+	n.mu.Unlock()
+	if k > 0 {
+		n.step(k - 1)
+	}
+}
+
+func ping(k int) {
+	treeMu.Lock()
+	pong(k) // MATCH /call of pong leads back to ping via pong -> ping while holding the lock acquired at .*:50:13; the lock is acquired again there and deadlocks/
+	treeMu.Unlock()
+}
+
+func pong(k int) {
+	if k > 0 {
+		ping(k - 1)
+	}
+}
+
+func fn1(n *node) {
+	n.incr(1)
+	n.visit()
+	n.step(1)
+}
@@ -0,0 +1,62 @@
+package pkg
+
+import "sync"
+
+type Cache struct {
+	mu    sync.RWMutex
+	items map[string]int
+}
+
+func (c *Cache) Get(k string) int {
+	c.mu.RLock() // want `read lock re-acquired here \(previously acquired at .*CheckRecursiveRLock.go:17:12\); if another goroutine calls Lock in between` `RLock called while holding the write lock acquired at .*CheckRecursiveRLock.go:27:11; an RWMutex can't be read locked by the holder of its write lock, so this always deadlocks`
+	defer c.mu.RUnlock()
+	return c.items[k]
+}
+
+func (c *Cache) Sum(keys []string) int {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	n := 0
+	for _, k := range keys {
+		n += c.Get(k)
+	}
+	return n
+}
+
+func (c *Cache) Set(k string, v int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.Get(k) == v {
+		return
+	}
+	c.items[k] = v
+}
+
+func (c *Cache) Reset() {
+	c.mu.Lock()
+	c.items = map[string]int{}
+	c.mu.RLock() // want `RLock called while holding the write lock acquired at .*CheckRecursiveRLock.go:36:11; an RWMutex can't be read locked by the holder of its write lock, so this always deadlocks`
+	n := len(c.items)
+	c.mu.RUnlock()
+	c.mu.Unlock()
+	println(n)
+}
+
+func (c *Cache) Len() int {
+	c.mu.Lock()
+	c.items["len"] = len(c.items)
+	c.mu.Unlock()
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return len(c.items)
+}
+
+func (c *Cache) Merge(other *Cache) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	other.mu.RLock()
+	defer other.mu.RUnlock()
+	for k, v := range other.items {
+		c.items[k] = v
+	}
+}
@@ -0,0 +1,63 @@
+package pkg
+
+import "time"
+
+func fn1(ch chan int) int {
+	for {
+		select { // MATCH /select with a default case in a loop that never blocks is a busy wait/
+		case v := <-ch:
+			return v
+		default:
+		}
+	}
+}
+
+func fn2(ch chan int) int {
+	for {
+		select {
+		case v := <-ch:
+			return v
+		default:
+			time.Sleep(time.Millisecond)
+		}
+	}
+}
+
+func fn3(ch chan int, tick <-chan time.Time) int {
+	for {
+		select {
+		case v := <-ch:
+			return v
+		case <-tick:
+		}
+	}
+}
+
+func fn4(ch chan int, work func()) {
+	for {
+		select {
+		case <-ch:
+			return
+		default:
+			work()
+		}
+	}
+}
+
+func fn5(ch chan int) {
+	select {
+	case <-ch:
+	default:
+	}
+}
+
+func fn6(ch chan int, n int) int {
+	for i := 0; i < n; i++ {
+		select {
+		case v := <-ch:
+			return v
+		default:
+		}
+	}
+	return 0
+}
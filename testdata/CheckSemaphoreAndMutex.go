@@ -0,0 +1,43 @@
+package semmutex
+
+import "sync"
+
+type Store struct {
+	sem  chan struct{}
+	mu   sync.Mutex
+	data map[string]int
+}
+
+func (s *Store) Put(k string, v int) {
+	s.sem <- struct{}{}
+	s.mu.Lock()   // MATCH /mutex .*Store.mu is acquired while holding semaphore .*Store.sem, but the opposite order is used at .*; this can deadlock/
+	s.data[k] = v // MATCH /field .*Store.data is guarded by both semaphore .*Store.sem and mutex .*Store.mu; one of them is redundant/
+	s.mu.Unlock()
+	<-s.sem
+}
+
+func (s *Store) Delete(k string) {
+	s.mu.Lock()
+	s.sem <- struct{}{} // MATCH /semaphore .*Store.sem is acquired while holding mutex .*Store.mu, but the opposite order is used at .*; this can deadlock/
+	delete(s.data, k)
+	<-s.sem
+	s.mu.Unlock()
+}
+
+type Limiter struct {
+	sem   chan struct{}
+	mu    sync.Mutex
+	count int
+}
+
+func (l *Limiter) Run(f func()) {
+	l.sem <- struct{}{}
+	defer func() { <-l.sem }()
+	f()
+}
+
+func (l *Limiter) Inc() {
+	l.mu.Lock()
+	l.count++
+	l.mu.Unlock()
+}
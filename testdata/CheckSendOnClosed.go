@@ -0,0 +1,44 @@
+package pkg
+
+func fn1(b bool) {
+	ch := make(chan int, 1)
+	if b {
+		close(ch)
+	}
+	ch <- 1 // MATCH /sending on channel ch, which may have been closed at .*CheckSendOnClosed.go:6:8; sending on a closed channel panics/
+}
+
+func fn2() {
+	ch := make(chan int, 1)
+	ch <- 1
+	close(ch)
+}
+
+func fn3(n int) {
+	ch := make(chan int, 1)
+	close(ch)
+	for i := 0; i < n; i++ {
+		select { // MATCH /sending on channel ch, which may have been closed at/
+		case ch <- i:
+		default:
+		}
+	}
+}
+
+func fn4(n int) {
+	for i := 0; i < n; i++ {
+		ch := make(chan int, 1)
+		ch <- i
+		close(ch)
+	}
+}
+
+func fn5() {
+	ch := make(chan int)
+	go func() {
+		close(ch)
+	}()
+	go func() {
+		ch <- 1 // MATCH /sending on unbuffered channel ch, which is never received from/
+	}()
+}
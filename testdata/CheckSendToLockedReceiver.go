@@ -0,0 +1,79 @@
+package pkg
+
+import "sync"
+
+type Pipe struct {
+	mu    sync.Mutex
+	items chan int
+	n     int
+}
+
+func (p *Pipe) Put(v int) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.n++
+	p.items <- v // want `channel send while holding field mu, locked at .*CheckSendToLockedReceiver.go:12:11, but the channel is only received from while holding it too, e.g. at .*CheckSendToLockedReceiver.go:21:7` `the goroutine that would unblock it may need the lock`
+}
+
+func (p *Pipe) Get() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	v := <-p.items // want `channel receive while holding field mu`
+	p.n--
+	return v
+}
+
+type Stream struct {
+	mu   sync.Mutex
+	data chan int
+}
+
+func (s *Stream) Put(v int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.data <- v // want `channel send while holding field mu, locked at .*; the goroutine that would unblock it may need the lock`
+}
+
+func (s *Stream) Get() int {
+	return <-s.data
+}
+
+func (s *Stream) GetLocked() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return <-s.data // want `channel receive while holding field mu`
+}
+
+type Buffer struct {
+	mu   sync.Mutex
+	data chan int
+}
+
+func (b *Buffer) Put(v int) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.data <- v // want `channel send while holding field mu, locked at .*; the goroutine that would unblock it may need the lock`
+}
+
+func (b *Buffer) Get() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	select {
+	case v := <-b.data:
+		return v
+	default:
+		return 0
+	}
+}
+
+func use() {
+	p := &Pipe{items: make(chan int)}
+	go p.Put(1)
+	println(p.Get())
+	s := &Stream{data: make(chan int)}
+	go s.Put(1)
+	println(s.Get(), s.GetLocked())
+	b := &Buffer{data: make(chan int)}
+	go b.Put(1)
+	println(b.Get())
+}
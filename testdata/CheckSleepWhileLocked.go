@@ -0,0 +1,43 @@
+package pkg
+
+import (
+	"sync"
+	"time"
+)
+
+type Client struct {
+	mu      sync.Mutex
+	retries int
+}
+
+var global sync.RWMutex
+
+func (c *Client) Retry() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for i := 0; i < c.retries; i++ {
+		time.Sleep(time.Second) // MATCH /time.Sleep while holding field mu, locked at .+; everything waiting for the lock is blocked for the duration of the sleep/
+	}
+}
+
+func (c *Client) Backoff() {
+	c.mu.Lock()
+	n := c.retries
+	c.mu.Unlock()
+	time.Sleep(time.Duration(n) * time.Second)
+}
+
+func (c *Client) Maybe(b bool) {
+	if b {
+		c.mu.Lock()
+		c.retries++
+		c.mu.Unlock()
+	}
+	time.Sleep(time.Millisecond)
+}
+
+func fn1() {
+	global.RLock()
+	time.Sleep(time.Millisecond) // MATCH /time.Sleep while holding global/
+	global.RUnlock()
+}
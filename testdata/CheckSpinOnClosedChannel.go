@@ -0,0 +1,63 @@
+package spinclosed
+
+func process(int) {}
+
+func fn1() {
+	ch := make(chan int)
+	go func() {
+		ch <- 1
+		close(ch)
+	}()
+	for {
+		v := <-ch // MATCH /receiving from a channel that is closed at .* in a loop that never exits spins forever once it is closed; use v, ok := <-ch/
+		process(v)
+	}
+}
+
+func fn2() {
+	ch := make(chan int)
+	go func() {
+		ch <- 1
+		close(ch)
+	}()
+	for {
+		v, ok := <-ch
+		if !ok {
+			return
+		}
+		process(v)
+	}
+}
+
+func fn3() {
+	ch := make(chan int)
+	go func() {
+		ch <- 1
+	}()
+	for {
+		v := <-ch
+		process(v)
+	}
+}
+
+type Worker struct {
+	jobs chan int
+}
+
+func (w *Worker) Stop() {
+	defer close(w.jobs)
+}
+
+func (w *Worker) Run() {
+	for {
+		process(<-w.jobs) // MATCH /receiving from a channel that is closed at .* in a loop that never exits/
+	}
+}
+
+func fn4() {
+	ch := make(chan int)
+	go close(ch)
+	for v := range ch {
+		process(v)
+	}
+}
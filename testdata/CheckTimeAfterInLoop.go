@@ -0,0 +1,36 @@
+package pkg
+
+import "time"
+
+func fn1(ch chan int) {
+	for {
+		select {
+		case <-ch:
+		case <-time.After(time.Second): // MATCH /calling time.After in a loop leaks a timer until it fires; consider time.NewTimer and Reset/
+			return
+		}
+	}
+}
+
+func fn2(ch chan int) {
+	select {
+	case <-ch:
+	case <-time.After(time.Second):
+	}
+}
+
+func fn3(ch chan int) {
+	t := time.NewTimer(time.Second)
+	defer t.Stop()
+	for {
+		select {
+		case <-ch:
+			if !t.Stop() {
+				<-t.C
+			}
+			t.Reset(time.Second)
+		case <-t.C:
+			return
+		}
+	}
+}
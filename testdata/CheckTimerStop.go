@@ -0,0 +1,102 @@
+package timerstop
+
+import "time"
+
+type Poller struct {
+	ticker *time.Ticker
+}
+
+func work() bool { return false }
+
+func fn1() {
+	t := time.NewTicker(time.Second) // MATCH /ticker t is never stopped on the path returning at/
+	for range t.C {
+		if work() {
+			return
+		}
+	}
+}
+
+func fn2() {
+	t := time.NewTicker(time.Second)
+	defer t.Stop()
+	for range t.C {
+		if work() {
+			return
+		}
+	}
+}
+
+func fn3(ch chan int) {
+	for {
+		t := time.NewTimer(time.Second) // MATCH /timer t is never stopped before it is created again in the next loop iteration/
+		select {
+		case <-ch:
+		case <-t.C:
+		}
+	}
+}
+
+func fn4(ch chan int) {
+	for {
+		t := time.NewTimer(time.Second)
+		select {
+		case <-ch:
+			t.Stop()
+		case <-t.C:
+		}
+	}
+}
+
+func fn5() {
+	t := time.NewTimer(time.Second)
+	<-t.C
+}
+
+func fn6() *time.Timer {
+	t := time.NewTimer(time.Second)
+	return t
+}
+
+func (p *Poller) Start() {
+	p.ticker = time.NewTicker(time.Second)
+}
+
+func fn7(d time.Duration) {
+	t := time.NewTimer(d) // MATCH /timer t is never stopped on the path returning at/
+	if d < 0 {
+		return
+	}
+	t.Stop()
+}
+
+func fn8(done chan struct{}) {
+	go func() {
+		t := time.NewTicker(time.Second) // MATCH /ticker t is never stopped on the path returning at/
+		for {
+			select {
+			case <-t.C:
+				work()
+			case <-done:
+				return
+			}
+		}
+	}()
+}
+
+func fn9() {
+	t := time.NewTicker(time.Second)
+	defer func() {
+		t.Stop()
+	}()
+	for range t.C {
+		if work() {
+			return
+		}
+	}
+}
+
+func fn10(ch chan *time.Ticker) {
+	t := time.NewTicker(time.Second)
+	ch <- t
+}
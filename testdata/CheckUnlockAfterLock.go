@@ -0,0 +1,56 @@
+package pkg
+
+import "sync"
+
+var (
+	mu sync.Mutex
+	rw sync.RWMutex
+	n  int
+)
+
+func fn1() {
+	mu.Lock()
+	mu.Unlock() // MATCH /empty critical section/
+}
+
+func fn2() int {
+	rw.RLock()
+	rw.RUnlock() // MATCH /empty critical section/
+	return n
+}
+
+func fn3() {
+	mu.Lock()
+	n++
+	mu.Unlock()
+}
+
+func fn4() {
+	mu.Lock()
+	defer mu.Unlock()
+	n++
+}
+
+func fn5() int {
+	rw.RLock()
+	v := n
+	rw.RUnlock()
+	return v
+}
+
+// the assignments to locals don't need the lock
+func fn6(a int) int {
+	i := 0
+	mu.Lock()
+	i = a
+	mu.Unlock() // MATCH /Unlock right after Lock; did you mean to defer Unlock\?/
+	return i
+}
+
+func fn7(a int) int {
+	v := 0
+	rw.RLock()
+	v = a
+	rw.RUnlock() // MATCH /RUnlock right after RLock; did you mean to defer RUnlock\?/
+	return v
+}
@@ -0,0 +1,50 @@
+package pkg
+
+import "sync"
+
+func fn1() {
+	var mu sync.Mutex
+	mu.Unlock() // MATCH /Unlock of mu, which is never locked before it in this function/
+}
+
+func fn2(b bool) {
+	var mu sync.Mutex
+	if b {
+		mu.Lock()
+	}
+	mu.Unlock() // MATCH /Unlock of mu, which is only locked at .*:13:10 on some of the paths reaching it/
+}
+
+func fn3() {
+	var mu sync.RWMutex
+	n := 0
+	mu.RLock()
+	n++
+	mu.RUnlock()
+	defer mu.Unlock() // MATCH /Unlock of mu, which is never locked before it in this function/
+	_ = n
+}
+
+func acquire(mu *sync.Mutex) {
+	mu.Lock()
+}
+
+func fn4() {
+	var mu sync.Mutex
+	acquire(&mu)
+	mu.Unlock()
+}
+
+func fn5(mu *sync.Mutex) {
+	mu.Unlock()
+}
+
+func fn6() {
+	mu := &sync.Mutex{}
+	for i := 0; i < 2; i++ {
+		if i > 0 {
+			mu.Unlock()
+		}
+		mu.Lock()
+	}
+}
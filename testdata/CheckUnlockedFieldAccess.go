@@ -0,0 +1,52 @@
+package unlockedfield
+
+import "sync"
+
+type Counter struct {
+	mu sync.Mutex
+	n  int
+}
+
+func (c *Counter) Inc() {
+	c.mu.Lock()
+	c.n++
+	c.incLocked()
+	c.mu.Unlock()
+}
+
+func (c *Counter) Get() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.n
+}
+
+// incLocked expects c.mu to be held, but Reset doesn't acquire it.
+func (c *Counter) incLocked() {
+	c.n++ // MATCH /field .*Counter.n is accessed without holding .*Counter.mu, .*; Reset calls incLocked/
+}
+
+func (c *Counter) Reset() {
+	c.incLocked()
+}
+
+type Gauge struct {
+	mu sync.Mutex
+	v  int
+}
+
+func (g *Gauge) Set(v int) {
+	g.mu.Lock()
+	g.setLocked(v)
+	g.mu.Unlock()
+}
+
+func (g *Gauge) Value() int {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.v
+}
+
+// setLocked is only ever called with g.mu held.
+func (g *Gauge) setLocked(v int) {
+	g.v = v
+}
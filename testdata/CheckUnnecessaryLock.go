@@ -0,0 +1,76 @@
+package main
+
+import (
+	"fmt"
+	"sync"
+)
+
+type Config struct {
+	mu     sync.Mutex
+	values map[string]string
+}
+
+func (c *Config) Set(k, v string) {
+	c.mu.Lock() // MATCH /.*Config.mu is only ever locked on the main goroutine, so the lock appears to be unnecessary/
+	c.values[k] = v
+	c.mu.Unlock()
+}
+
+type Counter struct {
+	mu sync.Mutex
+	n  int
+}
+
+func (c *Counter) Inc() {
+	c.mu.Lock()
+	c.n++
+	c.mu.Unlock()
+}
+
+type Stats struct {
+	mu   sync.Mutex
+	hits int
+}
+
+func (s *Stats) Hit() {
+	s.mu.Lock()
+	s.hits++
+	s.mu.Unlock()
+}
+
+type Hitter interface {
+	Hit()
+}
+
+var (
+	globalMu sync.Mutex
+	total    int
+)
+
+func add(n int) {
+	globalMu.Lock() // MATCH /globalMu is only ever locked on the main goroutine/
+	total += n
+	globalMu.Unlock()
+}
+
+func main() {
+	cfg := &Config{values: map[string]string{}}
+	cfg.Set("a", "b")
+
+	c := &Counter{}
+	var wg sync.WaitGroup
+	for i := 0; i < 2; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			c.Inc()
+		}()
+	}
+	wg.Wait()
+
+	var h Hitter = &Stats{}
+	h.Hit()
+
+	add(1)
+	fmt.Println(total)
+}
@@ -0,0 +1,76 @@
+package pkg
+
+import "fmt"
+
+func compute() int { return 42 }
+
+func fn1() {
+	results := make(chan int)
+	go func() {
+		results <- compute() // MATCH /sending on unbuffered channel results, which is never received from, blocks the goroutine forever/
+	}()
+}
+
+func produce(out chan int) {
+	out <- compute() // MATCH /sending on unbuffered channel out, which is never received from/
+}
+
+func fn2() {
+	out := make(chan int)
+	go produce(out)
+}
+
+func fn3() {
+	results := make(chan int)
+	go func() {
+		results <- compute()
+	}()
+	fmt.Println(<-results)
+}
+
+func fn4() {
+	results := make(chan int, 1)
+	go func() {
+		results <- compute()
+	}()
+}
+
+func fn5() {
+	results := make(chan int)
+	go func() {
+		results <- compute()
+	}()
+	for r := range results {
+		fmt.Println(r)
+	}
+}
+
+func consume(in chan int) {
+	fmt.Println(<-in)
+}
+
+func fn6() {
+	ch := make(chan int)
+	go func() {
+		ch <- compute()
+	}()
+	consume(ch)
+}
+
+func fn7() chan int {
+	ch := make(chan int)
+	go func() {
+		ch <- compute()
+	}()
+	return ch
+}
+
+func fn8(done chan struct{}) {
+	ch := make(chan int)
+	go func() {
+		select {
+		case ch <- compute():
+		case <-done:
+		}
+	}()
+}
@@ -0,0 +1,50 @@
+package wgaccounting
+
+import "sync"
+
+func spawn(wg *sync.WaitGroup, f func()) {
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		f()
+	}()
+}
+
+func spawnTwice(wg *sync.WaitGroup, f func()) {
+	wg.Add(2) // MATCH /WaitGroup counter is incremented by 2 but the goroutines started here decrement it by 1; the counter can't reach zero/
+	go func() {
+		defer wg.Done()
+		f()
+	}()
+}
+
+func spawnPair(wg *sync.WaitGroup, f func()) {
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		f()
+	}()
+	go func() {
+		defer wg.Done()
+		f()
+	}()
+}
+
+func spawnMaybe(wg *sync.WaitGroup, f func() bool) {
+	wg.Add(1)
+	go func() { // MATCH /goroutine may return at .* without calling Done; Wait will block forever/
+		if f() {
+			return
+		}
+		wg.Done()
+	}()
+}
+
+func Run() {
+	var wg sync.WaitGroup
+	spawn(&wg, func() {})
+	spawnTwice(&wg, func() {})
+	spawnPair(&wg, func() {})
+	spawnMaybe(&wg, func() bool { return false })
+	wg.Wait()
+}
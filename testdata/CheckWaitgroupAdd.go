@@ -0,0 +1,46 @@
+package pkg
+
+import (
+	"fmt"
+	"sync"
+)
+
+func fn1() {
+	var wg sync.WaitGroup
+	go func() {
+		wg.Add(1) // MATCH /should call wg.Add\(1\) before starting the goroutine to avoid a race/
+		defer wg.Done()
+	}()
+	wg.Wait()
+}
+
+func fn2() {
+	var wg sync.WaitGroup
+	go func() {
+		fmt.Println("starting")
+		wg.Add(1) // MATCH /should call wg.Add\(1\) before starting the goroutine to avoid a race/
+		defer wg.Done()
+	}()
+	wg.Wait()
+}
+
+func fn3(b bool) {
+	var wg sync.WaitGroup
+	go func() {
+		if b {
+			wg.Add(1) // MATCH /should call wg.Add\(1\) before starting the goroutine to avoid a race/
+			defer wg.Done()
+		}
+	}()
+	wg.Wait()
+}
+
+func fn4() {
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		fmt.Println("working")
+	}()
+	wg.Wait()
+}
@@ -5,7 +5,6 @@ import (
 	"sync"
 )
 
-/* test for SA2006 */
 func fn21(array []int) {
 
 	var wg sync.WaitGroup
@@ -17,6 +16,55 @@ func fn21(array []int) {
 			fmt.Println(a)
 			wg.Done()
 		}()
+		wg.Wait() // MATCH /wg.Wait waits for the goroutines started at .*:14:3, but is called in the loop that starts them and blocks before the goroutines of later iterations exist; call Wait after the loop/
+	}
+}
+
+func fn22(array []int) {
+	var wg sync.WaitGroup
+	wg.Add(len(array))
+	for _, a := range array {
+		wg.Wait() // MATCH /wg.Wait waits for the goroutines started at/
+		go func(a int) {
+			defer wg.Done()
+			fmt.Println(a)
+		}(a)
+	}
+}
+
+func fn23(array []int) {
+	var wg sync.WaitGroup
+	for _, a := range array {
+		wg.Add(1)
+		go func(a int) {
+			defer wg.Done()
+			fmt.Println(a)
+		}(a)
 		wg.Wait()
 	}
 }
+
+func fn24(array []int) {
+	var wg sync.WaitGroup
+	wg.Add(len(array))
+	for _, a := range array {
+		go func(a int) {
+			defer wg.Done()
+			fmt.Println(a)
+		}(a)
+	}
+	wg.Wait()
+}
+
+func fn25(array []int) {
+	var wg, other sync.WaitGroup
+	wg.Add(len(array))
+	for _, a := range array {
+		go func(a int) {
+			defer wg.Done()
+			fmt.Println(a)
+		}(a)
+		other.Wait()
+	}
+	wg.Wait()
+}
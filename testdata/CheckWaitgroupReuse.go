@@ -0,0 +1,71 @@
+package pkg
+
+import "sync"
+
+func work(int) {}
+
+func fn1(batches [][]int) {
+	var wg sync.WaitGroup
+	for _, batch := range batches {
+		wg.Add(len(batch)) // MATCH /wg.Add for the next round may run before the Wait at .*CheckWaitgroupReuse.go:18:11, called by the goroutine started at .*CheckWaitgroupReuse.go:17:3 in the previous iteration, has returned/
+		for _, v := range batch {
+			go func(v int) {
+				defer wg.Done()
+				work(v)
+			}(v)
+		}
+		go func() {
+			wg.Wait()
+			println("batch done")
+		}()
+	}
+}
+
+func fn2(batches [][]int) {
+	var wg sync.WaitGroup
+	for _, batch := range batches {
+		wg.Add(len(batch))
+		for _, v := range batch {
+			go func(v int) {
+				defer wg.Done()
+				work(v)
+			}(v)
+		}
+		done := make(chan struct{})
+		go func() {
+			wg.Wait()
+			close(done)
+		}()
+		<-done
+	}
+}
+
+func fn3(batches [][]int) {
+	var wg sync.WaitGroup
+	for _, batch := range batches {
+		wg.Add(len(batch))
+		for _, v := range batch {
+			go func(v int) {
+				defer wg.Done()
+				work(v)
+			}(v)
+		}
+		wg.Wait()
+	}
+}
+
+func wait(wg *sync.WaitGroup) {
+	wg.Wait()
+}
+
+func fn4(n int) {
+	wg := &sync.WaitGroup{}
+	for i := 0; i < n; i++ {
+		wg.Add(1) // MATCH /wg.Add for the next round may run before the Wait/
+		go func(i int) {
+			defer wg.Done()
+			work(i)
+		}(i)
+		go wait(wg)
+	}
+}
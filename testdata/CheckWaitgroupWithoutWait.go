@@ -0,0 +1,90 @@
+package wgwait
+
+import "sync"
+
+type Pool struct {
+	wg sync.WaitGroup
+}
+
+func (p *Pool) Go(f func()) {
+	p.wg.Add(1)
+	go func() {
+		defer p.wg.Done()
+		f()
+	}()
+}
+
+func (p *Pool) Close() {
+	p.wg.Wait()
+}
+
+func fn1() {
+	var wg sync.WaitGroup
+	for i := 0; i < 3; i++ {
+		wg.Add(1) // MATCH /WaitGroup wg \(declared at .*\) is used with Add and Done but Wait is never called on it/
+		go func() {
+			defer wg.Done()
+		}()
+	}
+}
+
+func fn2() {
+	var wg sync.WaitGroup
+	for i := 0; i < 3; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+		}()
+	}
+	wg.Wait()
+}
+
+func spawn(wg *sync.WaitGroup, f func()) {
+	wg.Add(1) // MATCH /WaitGroup wg \(declared at .*:57:.*\) is used with Add and Done but Wait is never called on it/
+	go func() {
+		defer wg.Done()
+		f()
+	}()
+}
+
+func fn3() {
+	wg := &sync.WaitGroup{}
+	spawn(wg, func() {})
+	wg.Wait()
+}
+
+func fn4() {
+	wg := &sync.WaitGroup{}
+	spawn(wg, func() {})
+}
+
+var global sync.WaitGroup
+
+func fn5() {
+	global.Add(1) // MATCH /WaitGroup global .*Wait is never called on it/
+	go global.Done()
+}
+
+func fn6() {
+	var wg sync.WaitGroup
+	wg.Add(1) // MATCH /WaitGroup wg .* is used with Add and Done but Wait is only called in fn6\$2, which is never reached/
+	go func() {
+		defer wg.Done()
+	}()
+	wait := func() {
+		wg.Wait()
+	}
+	_ = wait
+}
+
+func fn7() {
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+	}()
+	wait := func() {
+		wg.Wait()
+	}
+	wait()
+}
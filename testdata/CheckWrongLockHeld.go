@@ -0,0 +1,78 @@
+package pkg
+
+import "sync"
+
+type Cache struct {
+	itemsMu sync.RWMutex
+	items   map[string]int
+
+	statsMu sync.Mutex
+	hits    int
+}
+
+func (c *Cache) Get(k string) int {
+	c.itemsMu.RLock()
+	v := c.items[k]
+	c.itemsMu.RUnlock()
+
+	c.statsMu.Lock()
+	c.hits++
+	c.statsMu.Unlock()
+	return v
+}
+
+func (c *Cache) Set(k string, v int) {
+	c.itemsMu.Lock()
+	c.items[k] = v
+	c.itemsMu.Unlock()
+}
+
+func (c *Cache) Len() int {
+	c.itemsMu.RLock()
+	defer c.itemsMu.RUnlock()
+	return len(c.items)
+}
+
+func (c *Cache) Stats() (int, int) {
+	c.statsMu.Lock()
+	defer c.statsMu.Unlock()
+	return c.hits, len(c.items) // MATCH /field .*Cache.items is accessed holding .*Cache.statsMu, but it is guarded by .*Cache.itemsMu, which is held at 4 of 5 locked accesses/
+}
+
+func (c *Cache) Reset() {
+	c.itemsMu.Lock()
+	c.items = map[string]int{}
+	c.itemsMu.Unlock()
+	c.statsMu.Lock()
+	c.hits = 0
+	c.statsMu.Unlock()
+}
+
+func (c *Cache) resetHits() {
+	c.hits = 0
+}
+
+func (c *Cache) Clear() {
+	c.statsMu.Lock()
+	c.resetHits()
+	c.statsMu.Unlock()
+}
+
+// Ambiguous is accessed under either lock equally often, so no guard
+// is inferred.
+type Ambiguous struct {
+	a, b sync.Mutex
+	n    int
+}
+
+func (x *Ambiguous) IncA() {
+	x.a.Lock()
+	x.n++
+	x.a.Unlock()
+}
+
+func (x *Ambiguous) IncB() {
+	x.b.Lock()
+	x.n++
+	x.b.Unlock()
+}